@@ -1,14 +1,17 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"math/rand"
+	"fmt"
 	"net/http"
+	"path/filepath"
 
 	"github.com/apex/log"
 	"github.com/fatih/set"
 	"github.com/gorilla/mux"
+	"github.com/tympanix/supper/app/job"
 	"github.com/tympanix/supper/app/notify"
 	"github.com/tympanix/supper/types"
 	"golang.org/x/text/language"
@@ -19,6 +22,7 @@ type API struct {
 	types.App
 	*Hub
 	*mux.Router
+	jobs *job.Manager
 }
 
 // Error is an error occurring in an API endpoint
@@ -28,18 +32,28 @@ type Error interface {
 }
 
 // New creates a new API handler
-func New(app types.App) http.Handler {
+func New(app types.App) *API {
 	api := &API{
 		App:    app,
 		Hub:    newHub(),
 		Router: mux.NewRouter(),
+		jobs: job.NewManager(
+			app.Config().Jobs().Concurrency(),
+			app.Config().Jobs().Queue(),
+			app.Config().Jobs().History(),
+		),
 	}
 
+	api.jobs.Listen(api.sendToWebsocket)
+
 	api.Handle("/media", apiHandler(api.media))
 	api.Handle("/config", apiHandler(api.config))
+	api.Methods("GET").Path("/wanted").Handler(apiHandler(api.listWanted))
 	api.HandleFunc("/ws", api.serveWebsocket)
 	apiSubs := api.PathPrefix("/subtitles").Subrouter()
 	api.subtitleRouter(apiSubs)
+	apiJobs := api.PathPrefix("/jobs").Subrouter()
+	api.jobRouter(apiJobs)
 
 	go api.Hub.run()
 
@@ -118,19 +132,26 @@ func NewError(err error, status int) Error {
 	}
 }
 
-func (a *API) asyncSendToWebsocket() chan<- *notify.Entry {
-	c := make(chan *notify.Entry)
-	job := rand.Uint32()
-	go func() {
-		for v := range c {
-			v.Context = v.Context.WithField("job", job)
-			data, err := json.Marshal(v)
-			if err != nil {
-				log.WithError(err).Error("Websocket error")
-				continue
-			}
-			a.Broadcast(data)
+// Watch watches the directories and processes new media as jobs, such that
+// the results are kept in the job history and sent to the websocket
+func (a *API) Watch(ctx context.Context, dirs []string) error {
+	return a.WatchContext(ctx, dirs, func(path string) {
+		name := fmt.Sprintf("Process %v", filepath.Base(path))
+		_, err := a.jobs.Submit(name, path, func(ctx context.Context, c chan<- *notify.Entry) error {
+			return a.ProcessMediaContext(ctx, path, c)
+		})
+		if err != nil {
+			log.WithError(err).WithField("path", path).Error("Could not process new media")
 		}
-	}()
-	return c
+	})
+}
+
+func (a *API) sendToWebsocket(j *job.Job, e *notify.Entry) {
+	e.Context = e.Context.WithField("job", j.ID())
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.WithError(err).Error("Websocket error")
+		return
+	}
+	a.Broadcast(data)
 }
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/tympanix/supper/app/job"
+)
+
+type jsonAcceptedJob struct {
+	*job.Job
+	message string
+}
+
+func (j jsonAcceptedJob) Error() string {
+	return j.message
+}
+
+func (j jsonAcceptedJob) Status() int {
+	return http.StatusAccepted
+}
+
+func (j jsonAcceptedJob) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error string   `json:"error"`
+		Job   *job.Job `json:"job"`
+	}{
+		j.message,
+		j.Job,
+	})
+}
+
+// jobError returns the api error for errors from the job manager
+func jobError(err error) Error {
+	switch err {
+	case job.ErrBusy:
+		return NewError(err, http.StatusTooManyRequests)
+	case job.ErrQueueFull:
+		return NewError(err, http.StatusServiceUnavailable)
+	case job.ErrNotFound:
+		return NewError(err, http.StatusNotFound)
+	case job.ErrFinished:
+		return NewError(err, http.StatusConflict)
+	default:
+		return NewError(err, http.StatusInternalServerError)
+	}
+}
+
+func (a *API) jobRouter(mux *mux.Router) {
+	mux.Methods("GET").Path("").Handler(apiHandler(a.listJobs))
+	mux.Methods("GET").Path("/{id:[0-9]+}").Handler(apiHandler(a.getJob))
+	mux.Methods("DELETE").Path("/{id:[0-9]+}").Handler(apiHandler(a.cancelJob))
+}
+
+func (a *API) listJobs(w http.ResponseWriter, r *http.Request) interface{} {
+	return a.jobs.List()
+}
+
+func (a *API) findJob(r *http.Request) (*job.Job, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, NewError(err, http.StatusBadRequest)
+	}
+	j, err := a.jobs.Get(id)
+	if err != nil {
+		return nil, jobError(err)
+	}
+	return j, nil
+}
+
+func (a *API) getJob(w http.ResponseWriter, r *http.Request) interface{} {
+	j, err := a.findJob(r)
+	if err != nil {
+		return err
+	}
+	return j
+}
+
+func (a *API) cancelJob(w http.ResponseWriter, r *http.Request) interface{} {
+	j, err := a.findJob(r)
+	if err != nil {
+		return err
+	}
+	if err := a.jobs.Cancel(j.ID()); err != nil {
+		return jobError(err)
+	}
+	return j
+}
@@ -10,6 +10,7 @@ import (
 	"sort"
 
 	"github.com/tympanix/supper/media/parse"
+	"github.com/tympanix/supper/media/sidecar"
 	"github.com/tympanix/supper/types"
 )
 
@@ -123,6 +124,28 @@ func (a *API) detailsMedia(w http.ResponseWriter, r *http.Request) interface{} {
 	return files
 }
 
+// jsonLocalSubtitle is a subtitle on disk along with where it came from, if
+// it was downloaded by supper
+type jsonLocalSubtitle struct {
+	types.LocalSubtitle
+	source *sidecar.Record
+}
+
+func (s jsonLocalSubtitle) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(s.LocalSubtitle)
+	if err != nil || s.source == nil {
+		return data, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if fields["source"], err = json.Marshal(s.source); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
 func (a *API) fileList(folder jsonFolder) (interface{}, error) {
 	path, err := folder.getPath(a)
 	if err != nil {
@@ -134,11 +157,29 @@ func (a *API) fileList(folder jsonFolder) (interface{}, error) {
 	}
 	video := list.FilterVideo()
 	medialist := make([]interface{}, 0)
+	sidecars := make(map[string]map[string]sidecar.Record)
 	for _, m := range video.List() {
 		subs, err := m.ExistingSubtitles()
 		if err != nil {
 			return nil, NewError(err, http.StatusInternalServerError)
 		}
+		dir := filepath.Dir(m.Path())
+		records, ok := sidecars[dir]
+		if !ok {
+			// Subtitles are listed without their source if the sidecar is invalid
+			records, _ = sidecar.List(dir)
+			sidecars[dir] = records
+		}
+		jsonSubs := make([]jsonLocalSubtitle, 0, subs.Len())
+		for _, s := range subs.List() {
+			if l, ok := s.(types.LocalSubtitle); ok {
+				j := jsonLocalSubtitle{LocalSubtitle: l}
+				if r, ok := records[filepath.Base(l.Path())]; ok {
+					j.source = &r
+				}
+				jsonSubs = append(jsonSubs, j)
+			}
+		}
 		var mtype string
 		if _, ok := m.TypeEpisode(); ok {
 			mtype = typeShow
@@ -150,17 +191,17 @@ func (a *API) fileList(folder jsonFolder) (interface{}, error) {
 			return nil, errors.New("interval path error for media file")
 		}
 		medialist = append(medialist, struct {
-			Type  string             `json:"type"`
-			Name  string             `json:"filename"`
-			Path  string             `json:"filepath"`
-			Media types.Media        `json:"media"`
-			Subs  types.SubtitleList `json:"subtitles"`
+			Type  string              `json:"type"`
+			Name  string              `json:"filename"`
+			Path  string              `json:"filepath"`
+			Media types.Media         `json:"media"`
+			Subs  []jsonLocalSubtitle `json:"subtitles"`
 		}{
 			mtype,
 			m.Name(),
 			relpath,
 			m,
-			subs,
+			jsonSubs,
 		})
 	}
 	return medialist, nil
@@ -1,24 +1,24 @@
 package api
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
-	"sync"
 
 	"github.com/gorilla/mux"
 	"github.com/tympanix/supper/app/notify"
 	"github.com/tympanix/supper/media/list"
+	"github.com/tympanix/supper/media/subformat"
 	"github.com/tympanix/supper/types"
 	"golang.org/x/text/language"
 )
 
-var busyFolders = new(sync.Map)
-
 type jsonMedia struct {
 	jsonFolder
 	Filepath string `json:"filepath"`
@@ -53,14 +53,19 @@ func (m jsonMedia) MediaFile(a types.App) (types.LocalMedia, error) {
 
 type jsonSubtitle struct {
 	jsonMedia
-	URL  string `json:"link"`
-	Lang string `json:"language"`
+	URL    string `json:"link"`
+	Lang   string `json:"language"`
+	Source string `json:"provider"`
 }
 
 func (s jsonSubtitle) Link() string {
 	return s.URL
 }
 
+func (s jsonSubtitle) Provider() string {
+	return s.Source
+}
+
 type jsonRatedSubtitle struct {
 	types.RatedSubtitle
 }
@@ -74,7 +79,18 @@ func (r jsonRatedSubtitle) MarshalJSON() ([]byte, error) {
 		return nil, errors.New("Could not marshal subtitle which is not online")
 	}
 
+	var provider string
+	if p, ok := s.(types.ProvidedSubtitle); ok {
+		provider = p.Provider()
+	}
+
+	completed := true
+	if c, ok := s.(types.Completable); ok {
+		completed = c.Completed()
+	}
+
 	info := []string{
+		provider,
 		dl.Link(),
 		s.ForMedia().Meta().Codec().String(),
 		s.ForMedia().Meta().Group(),
@@ -88,18 +104,22 @@ func (r jsonRatedSubtitle) MarshalJSON() ([]byte, error) {
 	hex.Encode(infohash, hashval)
 
 	return json.Marshal(struct {
-		Hash  string       `json:"hash"`
-		Lang  language.Tag `json:"language"`
-		Link  string       `json:"link"`
-		Score float32      `json:"score"`
-		HI    bool         `json:"hi"`
-		Media types.Media  `json:"media"`
+		Hash      string       `json:"hash"`
+		Lang      language.Tag `json:"language"`
+		Link      string       `json:"link"`
+		Provider  string       `json:"provider"`
+		Score     float32      `json:"score"`
+		HI        bool         `json:"hi"`
+		Completed bool         `json:"completed"`
+		Media     types.Media  `json:"media"`
 	}{
 		string(infohash),
 		s.Language(),
 		dl.Link(),
+		provider,
 		r.Score(),
 		s.HearingImpaired(),
+		completed,
 		s.ForMedia(),
 	})
 }
@@ -121,6 +141,60 @@ func (a *API) subtitleRouter(mux *mux.Router) {
 		Handler(apiHandler(a.listSubtitles))
 	mux.Queries("action", "single").Methods("POST").
 		Handler(apiHandler(a.singleSubtitle))
+	mux.Queries("action", "reject").Methods("POST").
+		Handler(apiHandler(a.rejectSubtitle))
+	mux.Queries("action", "sync").Methods("POST").
+		Handler(apiHandler(a.syncSubtitle))
+}
+
+type jsonSubtitleFile struct {
+	jsonMedia
+	Filename string `json:"filename"`
+}
+
+func (s jsonSubtitleFile) getPath(a types.App) (string, error) {
+	path, err := s.jsonMedia.getPath(a)
+	if err != nil {
+		return "", err
+	}
+	if s.Filename == "" || s.Filename != filepath.Base(s.Filename) {
+		return "", errors.New("Illegal subtitle filename")
+	}
+	return filepath.Join(filepath.Dir(path), s.Filename), nil
+}
+
+func (a *API) rejectSubtitle(w http.ResponseWriter, r *http.Request) interface{} {
+	var sub jsonSubtitleFile
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&sub); err != nil {
+		return NewError(err, http.StatusBadRequest)
+	}
+	path, err := sub.getPath(a)
+	if err != nil {
+		return NewError(err, http.StatusBadRequest)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return NewError(errors.New("subtitle not found"), http.StatusNotFound)
+	}
+
+	j, err := a.jobs.Submit("Reject subtitle", path, func(ctx context.Context, c chan<- *notify.Entry) error {
+		subs, err := a.RejectSubtitleContext(ctx, path, c)
+		if err != nil {
+			if err != context.Canceled {
+				c <- notify.Error("%v", err)
+			}
+			return err
+		}
+		if len(subs) <= 0 {
+			c <- notify.Error("no subtitle(s) found")
+		}
+		return nil
+	})
+	if err != nil {
+		return jobError(err)
+	}
+
+	return jsonAcceptedJob{j, "Rejecting subtitle"}
 }
 
 func (a *API) singleSubtitle(w http.ResponseWriter, r *http.Request) interface{} {
@@ -197,10 +271,6 @@ func (a *API) downloadSubtitles(w http.ResponseWriter, r *http.Request) interfac
 	if err != nil {
 		return NewError(err, http.StatusBadRequest)
 	}
-	if _, busy := busyFolders.LoadOrStore(path, true); busy {
-		return NewError(errors.New("folder is busy"), http.StatusTooManyRequests)
-	}
-	defer busyFolders.Delete(path)
 	media, err := a.FindMedia(path)
 	if err != nil {
 		return NewError(err, http.StatusBadRequest)
@@ -216,18 +286,56 @@ func (a *API) downloadSubtitles(w http.ResponseWriter, r *http.Request) interfac
 		return NewError(errors.New("subtitle already satisfied"), http.StatusAccepted)
 	}
 
-	go func() {
-		c := a.asyncSendToWebsocket()
-		defer close(c)
-		subs, err := a.DownloadSubtitles(media, langs, c)
+	j, err := a.jobs.Submit("Download subtitles", path, func(ctx context.Context, c chan<- *notify.Entry) error {
+		subs, err := a.DownloadSubtitlesContext(ctx, media, langs, c)
 		if err != nil {
-			c <- notify.Error(err.Error())
-			return
+			if err != context.Canceled {
+				c <- notify.Error("%v", err)
+			}
+			return err
 		}
 		if len(subs) <= 0 {
 			c <- notify.Error("no subtitle(s) found")
 		}
-	}()
+		return nil
+	})
+	if err != nil {
+		return jobError(err)
+	}
+
+	return jsonAcceptedJob{j, "Downloading subtitles"}
+}
 
-	return NewError(errors.New("Downloading subtitles"), http.StatusAccepted)
+type jsonSyncSubtitle struct {
+	jsonSubtitleFile
+	Offset  string   `json:"offset"`
+	Anchors []string `json:"anchors"`
+	FPS     string   `json:"fps"`
+}
+
+func (a *API) syncSubtitle(w http.ResponseWriter, r *http.Request) interface{} {
+	var sub jsonSyncSubtitle
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&sub); err != nil {
+		return NewError(err, http.StatusBadRequest)
+	}
+	path, err := sub.getPath(a)
+	if err != nil {
+		return NewError(err, http.StatusBadRequest)
+	}
+	timing, err := subformat.ParseTiming(sub.Offset, sub.Anchors, sub.FPS)
+	if err != nil {
+		return NewError(err, http.StatusBadRequest)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return NewError(errors.New("subtitle not found"), http.StatusNotFound)
+	}
+	if err := a.SyncSubtitle(path, timing); err != nil {
+		return NewError(err, http.StatusInternalServerError)
+	}
+	return struct {
+		Message string `json:"message"`
+	}{
+		"ok",
+	}
 }
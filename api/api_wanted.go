@@ -0,0 +1,31 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/apex/log"
+	"github.com/tympanix/supper/app/job"
+	"github.com/tympanix/supper/app/notify"
+)
+
+// wantedKey is the job key used when searching for wanted subtitles, such
+// that only a single search runs at a time
+const wantedKey = "wanted"
+
+func (a *API) listWanted(w http.ResponseWriter, r *http.Request) interface{} {
+	return a.Wanted()
+}
+
+// Schedule searches for wanted subtitles as jobs whenever they are due, such
+// that the results are kept in the job history and sent to the websocket
+func (a *API) Schedule(ctx context.Context) error {
+	return a.ScheduleWantedContext(ctx, func() {
+		_, err := a.jobs.Submit("Retry wanted subtitles", wantedKey, func(ctx context.Context, c chan<- *notify.Entry) error {
+			return a.RetryWantedContext(ctx, c)
+		})
+		if err != nil && err != job.ErrBusy {
+			log.WithError(err).Error("Could not retry wanted subtitles")
+		}
+	})
+}
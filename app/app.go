@@ -1,7 +1,9 @@
 package app
 
 import (
+	"context"
 	"errors"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -16,9 +18,15 @@ import (
 	_ "github.com/tympanix/supper/statik"
 
 	"github.com/tympanix/supper/api"
+	"github.com/tympanix/supper/app/blacklist"
+	"github.com/tympanix/supper/app/cache"
 	"github.com/tympanix/supper/app/cfg"
+	"github.com/tympanix/supper/app/library"
+	"github.com/tympanix/supper/app/wanted"
 	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/cleanup"
 	"github.com/tympanix/supper/media/list"
+	"github.com/tympanix/supper/media/provider"
 	"github.com/tympanix/supper/types"
 )
 
@@ -30,29 +38,77 @@ var filetypes = []string{
 
 // Application is an configuration instance of the application
 type Application struct {
-	types.Provider
 	*http.ServeMux
-	cfg      types.Config
-	scrapers []types.Scraper
+	cfg       types.Config
+	providers []types.Provider
+	scrapers  []types.Scraper
+	limits    *limiter
+	delay     *pacer
+	wanted    *wanted.List
+	blacklist *blacklist.List
+	index     *library.Index
+	cleaner   *cleanup.Cleaner
+	api       *api.API
 }
 
 // New returns a new application from the cli context
 func New(cfg types.Config) *Application {
 	app := &Application{
-		Provider: cfg.Providers()[0],
-		cfg:      cfg,
-		ServeMux: http.NewServeMux(),
-		scrapers: cfg.Scrapers(),
+		cfg:       cfg,
+		ServeMux:  http.NewServeMux(),
+		providers: cfg.Providers(),
+		scrapers:  cfg.Scrapers(),
+		limits:    newLimiter(),
+		delay:     new(pacer),
+		wanted:    openWanted(cfg),
+		blacklist: openBlacklist(cfg),
+		index:     openIndex(cfg),
+		cleaner:   newCleaner(cfg),
 	}
 
-	api := api.New(app)
-	app.ServeMux.Handle("/api/", http.StripPrefix("/api", api))
+	provider.SetOffline(cfg.Offline())
+
+	if dir := cfg.CacheDir(); dir != "" {
+		store := cache.New(dir)
+		provider.UseCache(store)
+		app.scrapers = make([]types.Scraper, 0, len(cfg.Scrapers()))
+		for _, s := range cfg.Scrapers() {
+			app.scrapers = append(app.scrapers, cache.Scraper(store, s))
+		}
+	}
+
+	app.api = api.New(app)
+	app.ServeMux.Handle("/api/", http.StripPrefix("/api", app.api))
 
 	app.ServeMux.Handle("/", app.webAppHandler())
 
 	return app
 }
 
+// dataFile returns the path of the file with persistent state in the data
+// directory, or an empty path if there is no data directory. Files from older
+// versions, which were kept in the cache directory, are moved to the data
+// directory
+func dataFile(config types.Config, name string) string {
+	dir := config.DataDir()
+	if dir == "" {
+		return ""
+	}
+	path := filepath.Join(dir, name)
+
+	if cache := config.CacheDir(); cache != "" && cache != dir {
+		old := filepath.Join(cache, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if _, err := os.Stat(old); err == nil {
+				if err := os.MkdirAll(dir, os.ModePerm); err == nil {
+					os.Rename(old, path)
+				}
+			}
+		}
+	}
+	return path
+}
+
 // NewFromDefault construct an application using the default config
 func NewFromDefault() types.App {
 	return New(cfg.Default)
@@ -84,13 +140,55 @@ func (a *Application) webAppHandler() http.Handler {
 	})
 }
 
+// Close closes the library index and stops the external plugins of the
+// configuration. The application must not be used once it has been closed
+func (a *Application) Close() error {
+	var err error
+	if a.index != nil {
+		err = a.index.Close()
+		a.index = nil
+	}
+	if c, ok := a.cfg.(io.Closer); ok {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// WatchJobs watches the directories for new media, which is processed as jobs
+// of the web application
+func (a *Application) WatchJobs(ctx context.Context, dirs []string) error {
+	return a.api.Watch(ctx, dirs)
+}
+
+// ScheduleJobs searches for wanted subtitles as jobs of the web application,
+// whenever they are due to be searched for again
+func (a *Application) ScheduleJobs(ctx context.Context) error {
+	return a.api.Schedule(ctx)
+}
+
+// Providers returns the list of subtitle providers
+func (a *Application) Providers() []types.Provider {
+	return a.providers
+}
+
 // Scrapers returns the list of supported scrapers
 func (a *Application) Scrapers() []types.Scraper {
 	return a.scrapers
 }
 
-// FindMedia searches for media files
+// FindMedia searches for media files. Directories are looked up in the
+// library index if it is enabled
 func (a *Application) FindMedia(roots ...string) (types.LocalMediaList, error) {
+	if a.index != nil {
+		return a.findIndexedMedia(roots...)
+	}
+	return walkMedia(roots...)
+}
+
+// walkMedia searches the file system for media files
+func walkMedia(roots ...string) (types.LocalMediaList, error) {
 	medialist := make([]types.LocalMedia, 0)
 
 	for _, root := range roots {
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
@@ -57,7 +58,7 @@ func (a *Application) ExtractMedia(m types.MediaReadCloser) error {
 		}
 	}
 
-	dest, err := a.scrapeAndRenameMedia(m, m)
+	dest, err := a.scrapeAndRenameMedia(context.Background(), m, m)
 
 	if err != nil {
 		return err
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/fatih/set"
+	"github.com/tympanix/supper/app/blacklist"
+	"github.com/tympanix/supper/app/notify"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/list"
+	"github.com/tympanix/supper/media/parse"
+	"github.com/tympanix/supper/media/sidecar"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language/display"
+)
+
+// errBlacklisted is returned when a downloaded subtitle has been rejected
+// before
+var errBlacklisted = errors.New("subtitle has been rejected")
+
+// openBlacklist opens the list of rejected subtitles in the data directory.
+// The list is kept in memory only if it can not be stored
+func openBlacklist(config types.Config) *blacklist.List {
+	path := dataFile(config, "blacklist.json")
+
+	l, err := blacklist.Open(path)
+	if err != nil {
+		log.WithError(err).WithField("path", path).Warn("Could not read rejected subtitles")
+		l, _ = blacklist.Open("")
+	}
+	return l
+}
+
+// RejectSubtitleContext rejects the subtitle at the path, such that it is
+// never downloaded for the video again. The subtitle is removed and the best
+// subtitle which has not been rejected is downloaded instead
+func (a *Application) RejectSubtitleContext(ctx context.Context, path string, c chan<- *notify.Entry) ([]types.LocalSubtitle, error) {
+	sub, err := media.NewLocalSubtitle(path)
+	if err != nil {
+		return nil, err
+	}
+
+	video, err := videoForSubtitle(path)
+	if err != nil {
+		return nil, err
+	}
+
+	note := notify.WithField("media", video).
+		WithField("lang", display.English.Languages().Name(sub.Language()))
+
+	rec, _, err := sidecar.Get(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// The subtitle may have been edited since it was downloaded, so the
+	// hash of the downloaded content is rejected if it is known
+	hash := rec.Hash
+	if hash == "" {
+		if hash, err = sidecar.Hash(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.Config().Dry() {
+		c <- note.WithField("reason", "dry-run").Info("Skip reject")
+		return nil, nil
+	}
+
+	if err := a.blacklist.Add(video.Identity(), rec.Link, hash); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, err
+	}
+	if err := sidecar.Remove(path); err != nil {
+		c <- note.WithError(err).Debug("Could not remove subtitle record")
+	}
+
+	c <- note.WithField("provider", rec.Provider).Info("Subtitle rejected")
+
+	return a.DownloadSubtitlesContext(ctx, list.NewLocalMedia(video), set.New(sub.Language()), c)
+}
+
+// videoForSubtitle returns the video in the same directory as the subtitle
+// at the path, which the subtitle was named after
+func videoForSubtitle(path string) (types.Video, error) {
+	name := parse.Filename(path)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[:i]
+	}
+
+	for _, ext := range filetypes {
+		p := filepath.Join(filepath.Dir(path), name+ext)
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		m, err := media.NewLocalFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := m.(types.Video); ok {
+			return v, nil
+		}
+	}
+
+	return nil, errors.New("no video found for subtitle")
+}
+
+// downloadAllowed downloads the subtitle in the preferred format, unless the
+// content of the subtitle has been rejected for the media before, the
+// subtitle is in another language than expected or the subtitle fails the
+// sanity checks. The outcome of the sanity checks is returned as well
+func (a *Application) downloadAllowed(ctx context.Context, m types.Video, s types.OnlineSubtitle) (io.ReadCloser, *sidecar.Check, error) {
+	srt, err := a.downloadLimited(ctx, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer srt.Close()
+
+	data, err := ioutil.ReadAll(srt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := verifyLanguage(data, s.Language()); err != nil {
+		return nil, nil, err
+	}
+	check, err := a.checkSubtitle(data, s.Language(), m)
+	if err != nil {
+		return nil, nil, err
+	}
+	data = a.convertSubtitle(data, s.Language())
+
+	hash, err := sidecar.HashReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.blacklist.Hash(m.Identity(), hash) {
+		return nil, nil, errBlacklisted
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(data)), check, nil
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/app/blacklist"
+	"github.com/tympanix/supper/app/notify"
+	"github.com/tympanix/supper/media/sidecar"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+// rankedMedia is media which is rated with a fixed score
+type rankedMedia struct {
+	types.Media
+	score float32
+}
+
+type linkedSubtitle struct {
+	online
+	link string
+}
+
+func (s linkedSubtitle) Link() string {
+	return s.link
+}
+
+type fakeCandidate struct {
+	link  string
+	data  string
+	score float32
+}
+
+type fakeLinkedProvider []fakeCandidate
+
+func (p fakeLinkedProvider) Name() string {
+	return "fakelinkedprovider"
+}
+
+func (p fakeLinkedProvider) ResolveSubtitle(link types.Linker) (types.Downloadable, error) {
+	return nil, nil
+}
+
+func (p fakeLinkedProvider) SearchSubtitles(m types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	var subs []types.OnlineSubtitle
+	for _, c := range p {
+		s := subtitle{rankedMedia{m, c.score}, language.German, false}
+		subs = append(subs, linkedSubtitle{online{s, []byte(c.data)}, c.link})
+	}
+	return subs, nil
+}
+
+func TestRejectSubtitle(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	const path = "out/Inception.2010.720p.x264.mkv"
+	const sub = "out/Inception.2010.720p.x264.de.srt"
+
+	require.NoError(t, copyTestFiles("test", "out"))
+
+	config := defaultConfig
+	config.evaluator = fakeEvaluator(func(m types.Media, n types.Media) float32 {
+		if r, ok := n.(rankedMedia); ok {
+			return r.score
+		}
+		return 0
+	})
+
+	app := New(config)
+	app.providers = []types.Provider{fakeLinkedProvider{
+		{"https://example.com/1", "first", 0.9},
+		{"https://example.com/2", "second", 0.8},
+	}}
+
+	var err error
+	app.blacklist, err = blacklist.Open("")
+	require.NoError(t, err)
+
+	l, err := app.FindMedia(path)
+	require.NoError(t, err)
+
+	c := notify.AsyncDiscard()
+	defer close(c)
+
+	subs, err := app.DownloadSubtitles(l, set.New(language.German), c)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assertContent(t, "first", sub)
+
+	// The next best subtitle is downloaded instead
+	subs, err = app.RejectSubtitleContext(context.Background(), sub, c)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assertContent(t, "second", sub)
+	assert.Len(t, app.blacklist.Entries(), 1)
+
+	// The downloaded content is rejected, even if the subtitle was edited
+	require.NoError(t, replaceSubtitle(sub, []byte("second"), []byte("edited")))
+	rec, ok, err := sidecar.Get(sub)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, rec.Edited)
+
+	// Rejected subtitles are never downloaded again, even from another link
+	app.providers = []types.Provider{fakeLinkedProvider{
+		{"https://example.com/1", "first", 0.9},
+		{"https://example.com/3", "second", 0.8},
+	}}
+
+	subs, err = app.RejectSubtitleContext(context.Background(), sub, c)
+	require.NoError(t, err)
+	assert.Empty(t, subs)
+
+	_, err = os.Stat(sub)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRejectSubtitleNoVideo(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	require.NoError(t, copyTestFiles("test", "out"))
+	require.NoError(t, os.Remove("out/Inception.2010.720p.x264.mkv"))
+
+	app := New(defaultConfig)
+
+	c := notify.AsyncDiscard()
+	defer close(c)
+
+	_, err := app.RejectSubtitleContext(context.Background(), "out/Inception.2010.720p.x264.en.srt", c)
+	assert.Error(t, err)
+
+	_, err = os.Stat("out/Inception.2010.720p.x264.en.srt")
+	assert.NoError(t, err)
+}
+
+func assertContent(t *testing.T, expected string, path string) {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(data))
+}
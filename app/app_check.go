@@ -0,0 +1,118 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tympanix/supper/media/charset"
+	"github.com/tympanix/supper/media/container"
+	"github.com/tympanix/supper/media/sidecar"
+	"github.com/tympanix/supper/media/subformat"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+// validateDiscard discards downloaded subtitles which fail the sanity checks,
+// while other validations only flag the problems
+const validateDiscard = "discard"
+
+// maxCPS is the average reading speed, in characters per second, above which
+// a subtitle is considered garbage
+const maxCPS = 35
+
+// checkSlack is how long after the end of the video the last cue may end
+const checkSlack = 2 * time.Minute
+
+// checkError is returned when a downloaded subtitle fails the sanity checks
+type checkError struct {
+	problems []string
+}
+
+func (e checkError) Error() string {
+	return "subtitle failed checks: " + strings.Join(e.problems, ", ")
+}
+
+// checkSubtitle parses the cues of the subtitle and checks their timing and
+// reading speed, and that they fit the duration of the video as given by its
+// container. The stats and problems are returned to be recorded with the
+// subtitle. Subtitles which are unusable (e.g. empty, truncated or for another
+// cut of the video) are discarded with an error, unless they are only flagged
+func (a *Application) checkSubtitle(data []byte, lang language.Tag, m types.Video) (*sidecar.Check, error) {
+	validation := a.Config().Validation()
+	if validation == "" {
+		return nil, nil
+	}
+
+	check := &sidecar.Check{}
+	var fatal, flagged []string
+
+	text, _, err := charset.ToUTF8(data, lang)
+	var format subformat.Format
+	var cues []subformat.Cue
+	if err == nil {
+		if format, err = subformat.Detect(text); err == nil {
+			cues, err = format.Decode(bytes.NewReader(text))
+		}
+	}
+	if err != nil || len(cues) == 0 {
+		fatal = append(fatal, "no cues found")
+	}
+
+	s := subformat.Analyze(cues)
+	check.Stats = s
+
+	if s.Cues > 0 {
+		switch {
+		case s.Empty == s.Cues:
+			fatal = append(fatal, "no text found")
+		case s.Empty*10 > s.Cues:
+			flagged = append(flagged, fmt.Sprintf("%d empty cues", s.Empty))
+		}
+
+		switch {
+		case s.Invalid*20 > s.Cues:
+			fatal = append(fatal, fmt.Sprintf("%d cues with invalid timing", s.Invalid))
+		case s.Invalid > 0:
+			flagged = append(flagged, fmt.Sprintf("%d cues with invalid timing", s.Invalid))
+		}
+
+		// Cues of ASS/SSA subtitles overlap on purpose, e.g. for signs and
+		// dialogue shown at the same time
+		styled := format != nil && (format.Name() == "ass" || format.Name() == "ssa")
+
+		switch {
+		case s.Overlaps*4 > s.Cues && !styled:
+			fatal = append(fatal, fmt.Sprintf("%d overlapping cues", s.Overlaps))
+		case s.Overlaps*20 > s.Cues:
+			flagged = append(flagged, fmt.Sprintf("%d overlapping cues", s.Overlaps))
+		}
+
+		switch {
+		case s.CPS > maxCPS:
+			fatal = append(fatal, fmt.Sprintf("reading speed of %.1f characters per second", s.CPS))
+		case s.Fast*10 > s.Cues:
+			flagged = append(flagged, fmt.Sprintf("%d cues too fast to read", s.Fast))
+		}
+
+		if d, err := container.Duration(m.Path()); err == nil && d > 0 {
+			check.Duration = d
+			last, end := s.Last.Truncate(time.Second), d.Truncate(time.Second)
+			switch {
+			case s.Last > d+checkSlack:
+				fatal = append(fatal, fmt.Sprintf("ends at %v after the video ends at %v", last, end))
+			case s.Last < d/2:
+				fatal = append(fatal, fmt.Sprintf("ends at %v before half of the video of %v", last, end))
+			case s.Last < d*3/4:
+				flagged = append(flagged, fmt.Sprintf("ends at %v long before the video ends at %v", last, end))
+			}
+		}
+	}
+
+	check.Problems = append(fatal, flagged...)
+	if len(fatal) > 0 && validation == validateDiscard {
+		return check, checkError{fatal}
+	}
+	return check, nil
+}
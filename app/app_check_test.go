@@ -0,0 +1,228 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/sidecar"
+	"github.com/tympanix/supper/media/subformat"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+// checkVideo is a copy of a video of 1h30m which the subtitles are checked
+// against
+const checkVideo = "out/Inception.2010.720p.x264.mkv"
+
+var checkLines = []string{
+	"Der Zug fährt um sieben, also müssen wir früh aufstehen.",
+	"Hast du die Brote eingepackt?",
+	"Es regnet seit drei Tagen ohne Pause.",
+}
+
+// checkedCues returns a cue every ten seconds, until the given time
+func checkedCues(end time.Duration) []subformat.Cue {
+	var cues []subformat.Cue
+	for s := 7 * time.Second; s+3*time.Second <= end; s += 10 * time.Second {
+		line := checkLines[len(cues)%len(checkLines)]
+		cues = append(cues, subformat.Cue{Start: s, End: s + 3*time.Second, Lines: []string{line}})
+	}
+	return cues
+}
+
+// checkedSubtitle returns a subtitle with a cue every ten seconds, which ends
+// at the given time
+func checkedSubtitle(t *testing.T, end time.Duration) string {
+	var buf bytes.Buffer
+	require.NoError(t, subformat.Default.Encode(&buf, checkedCues(end)))
+	return buf.String()
+}
+
+func copyCheckVideo(t *testing.T) types.Video {
+	require.NoError(t, copyTestFiles("test", "out"))
+	data, err := ioutil.ReadFile("../media/container/test/sample.mkv")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(checkVideo, data, 0644))
+
+	m, err := media.NewLocalFile(checkVideo)
+	require.NoError(t, err)
+	video, ok := m.(types.Video)
+	require.True(t, ok)
+	return video
+}
+
+func TestCheckSubtitle(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	video := copyCheckVideo(t)
+
+	config := defaultConfig
+	config.validate = validateDiscard
+	app := New(config)
+
+	tests := []struct {
+		data    string
+		fails   bool
+		problem string
+	}{
+		{checkedSubtitle(t, 89*time.Minute), false, ""},
+		{checkedSubtitle(t, 60*time.Minute), false, "ends at 1h0m0s long before the video ends at 1h30m0s"},
+		{checkedSubtitle(t, 30*time.Minute), true, "ends at 30m0s before half of the video of 1h30m0s"},
+		{checkedSubtitle(t, 95*time.Minute), true, "ends at 1h35m0s after the video ends at 1h30m0s"},
+		{"not a subtitle", true, "no cues found"},
+		{"1\n00:00:01,000 --> 00:00:01,100\n" + strings.Repeat("garbage ", 20) + "\n", true, "reading speed of 1590.0 characters per second"},
+	}
+
+	for _, test := range tests {
+		check, err := app.checkSubtitle([]byte(test.data), language.German, video)
+		require.NotNil(t, check)
+		if check.Cues > 0 {
+			assert.Equal(t, 90*time.Minute+500*time.Millisecond, check.Duration)
+		}
+		if test.fails {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+		if test.problem == "" {
+			assert.Empty(t, check.Problems)
+		} else {
+			assert.Contains(t, check.Problems, test.problem)
+		}
+	}
+
+	// Subtitles are only flagged
+	config.validate = "flag"
+	app = New(config)
+
+	check, err := app.checkSubtitle([]byte(checkedSubtitle(t, 30*time.Minute)), language.German, video)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, check.Problems)
+
+	// Subtitles are not checked
+	config.validate = ""
+	app = New(config)
+
+	check, err = app.checkSubtitle([]byte("not a subtitle"), language.German, video)
+	assert.NoError(t, err)
+	assert.Nil(t, check)
+}
+
+func TestCheckStyledSubtitle(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	video := copyCheckVideo(t)
+
+	config := defaultConfig
+	config.validate = validateDiscard
+	app := New(config)
+
+	// Signs are shown along with the dialogue, and are listed after it
+	cues := checkedCues(89 * time.Minute)
+	var signs []subformat.Cue
+	for _, c := range cues {
+		signs = append(signs, subformat.Cue{Start: c.Start + time.Second, End: c.End - time.Second, Lines: []string{"Bahnhof"}})
+	}
+	cues = append(cues, signs...)
+	overlaps := fmt.Sprintf("%d overlapping cues", len(signs))
+
+	for _, name := range []string{"ass", "srt"} {
+		format, err := subformat.ByName(name)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, format.Encode(&buf, cues))
+
+		check, err := app.checkSubtitle(buf.Bytes(), language.German, video)
+		require.NotNil(t, check)
+		assert.Contains(t, check.Problems, overlaps, name)
+
+		// Overlapping cues only fail subtitles without styles
+		if name == "ass" {
+			assert.NoError(t, err, name)
+		} else {
+			assert.Error(t, err, name)
+		}
+	}
+}
+
+func TestDownloadFailedChecks(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	copyCheckVideo(t)
+	full := checkedSubtitle(t, 89*time.Minute)
+
+	config := defaultConfig
+	config.validate = validateDiscard
+	config.evaluator = fakeEvaluator(func(m types.Media, n types.Media) float32 {
+		if r, ok := n.(rankedMedia); ok {
+			return r.score
+		}
+		return 0
+	})
+
+	app := New(config)
+	app.providers = []types.Provider{fakeLinkedProvider{
+		{"https://example.com/1", checkedSubtitle(t, 30*time.Minute), 0.9},
+		{"https://example.com/2", full, 0.8},
+	}}
+
+	l, err := app.FindMedia(checkVideo)
+	require.NoError(t, err)
+
+	c, messages := collectEntries()
+	subs, err := app.DownloadSubtitles(l, set.New(language.German), c)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+
+	assert.Contains(t, messages(), "Subtitle failed checks")
+	assertContent(t, full, subs[0].Path())
+
+	rec, ok, err := sidecar.Get(subs[0].Path())
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NotNil(t, rec.Check)
+	assert.Equal(t, "https://example.com/2", rec.Link)
+	assert.Equal(t, 534, rec.Check.Cues)
+	assert.Empty(t, rec.Check.Problems)
+}
+
+func TestDownloadFlaggedSubtitle(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	copyCheckVideo(t)
+	truncated := checkedSubtitle(t, 30*time.Minute)
+
+	config := defaultConfig
+	config.validate = "flag"
+
+	app := New(config)
+	app.providers = []types.Provider{fakeLinkedProvider{
+		{"https://example.com/1", truncated, 0.9},
+	}}
+
+	l, err := app.FindMedia(checkVideo)
+	require.NoError(t, err)
+
+	c, messages := collectEntries()
+	subs, err := app.DownloadSubtitles(l, set.New(language.German), c)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+
+	assert.Contains(t, messages(), "Subtitle flagged")
+	assertContent(t, truncated, subs[0].Path())
+
+	rec, ok, err := sidecar.Get(subs[0].Path())
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NotNil(t, rec.Check)
+	assert.Equal(t, []string{"ends at 30m0s before half of the video of 1h30m0s"}, rec.Check.Problems)
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/apex/log"
+	"github.com/tympanix/supper/media/charset"
+	"github.com/tympanix/supper/media/cleanup"
+	"github.com/tympanix/supper/media/subformat"
+	"github.com/tympanix/supper/types"
+)
+
+// newCleaner returns the cleaner for the cleanup rules of the configuration,
+// or nil if subtitles should not be cleaned
+func newCleaner(config types.Config) *cleanup.Cleaner {
+	c := config.Cleanup()
+	if !c.Ads() && !c.HearingImpaired() && len(c.Patterns()) == 0 && len(c.Phrases()) == 0 {
+		return nil
+	}
+
+	cleaner, err := cleanup.New(cleanup.Rules{
+		Ads:             c.Ads(),
+		HearingImpaired: c.HearingImpaired(),
+		Patterns:        c.Patterns(),
+		Phrases:         c.Phrases(),
+	})
+	if err != nil {
+		log.WithError(err).Warn("Invalid cleanup rules")
+		return nil
+	}
+	return cleaner
+}
+
+// cleanText removes ads, credits and hearing impaired annotations from the
+// subtitle text in UTF-8. Only the cues which are cleaned are changed, such
+// that the format, styles and formatting of the rest of the subtitle are kept
+func (a *Application) cleanText(text []byte) ([]byte, cleanup.Result, error) {
+	var res cleanup.Result
+	cleaned, err := subformat.Edit(text, func(cue subformat.Cue) []string {
+		lines, r := a.cleaner.CleanCue(cue)
+		res.Ads += r.Ads
+		res.Annotations += r.Annotations
+		return lines
+	})
+	if err != nil {
+		return nil, cleanup.Result{}, err
+	}
+	return cleaned, res, nil
+}
+
+// CleanSubtitle removes ads, credits and hearing impaired annotations from
+// the subtitle at the path, using the cleanup rules of the configuration. The
+// subtitle keeps its format and character encoding, and the original subtitle
+// is kept as a backup
+func (a *Application) CleanSubtitle(path string) (cleanup.Result, error) {
+	if a.cleaner == nil {
+		return cleanup.Result{}, errors.New("no cleanup rules configured")
+	}
+
+	data, text, enc, err := readSubtitle(path)
+	if err != nil {
+		return cleanup.Result{}, err
+	}
+
+	cleaned, res, err := a.cleanText(text)
+	if err != nil || !res.Changed() || a.Config().Dry() {
+		return res, err
+	}
+
+	encoded, err := charset.FromUTF8(cleaned, enc)
+	if err != nil {
+		return res, err
+	}
+	return res, replaceSubtitle(path, data, encoded)
+}
+
+// filterImpaired returns the subtitles which match the hearing impaired
+// preference of the configuration. Hearing impaired subtitles are used when
+// no other subtitles exist, if their annotations are stripped anyway
+func (a *Application) filterImpaired(l types.SubtitleList) types.SubtitleList {
+	hi := a.Config().Impaired()
+	filtered := l.HearingImpaired(hi)
+	if filtered.Len() == 0 && !hi && a.Config().Cleanup().HearingImpaired() {
+		return l
+	}
+	return filtered
+}
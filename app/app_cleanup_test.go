@@ -0,0 +1,148 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/app/notify"
+	"github.com/tympanix/supper/media/cleanup"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+// uncleanSubtitle is a hearing impaired German subtitle in the Windows-1252
+// encoding, which ends with an ad
+const uncleanSubtitle = "1\n00:00:01,000 --> 00:00:02,500\n[Musik]\n\n" +
+	"2\n00:00:03,000 --> 00:00:04,500\nHANS: Gr\xfc\xdf dich.\n\n" +
+	"3\n00:00:05,000 --> 00:00:06,500\nUntertitel von XYZ\nwww.example.com\n"
+
+// fakeImpairedProvider finds hearing impaired subtitles only
+type fakeImpairedProvider []fakeCandidate
+
+func (p fakeImpairedProvider) Name() string {
+	return "fakeimpairedprovider"
+}
+
+func (p fakeImpairedProvider) ResolveSubtitle(link types.Linker) (types.Downloadable, error) {
+	return nil, nil
+}
+
+func (p fakeImpairedProvider) SearchSubtitles(m types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	var subs []types.OnlineSubtitle
+	for _, c := range p {
+		s := subtitle{rankedMedia{m, c.score}, language.German, true}
+		subs = append(subs, linkedSubtitle{online{s, []byte(c.data)}, c.link})
+	}
+	return subs, nil
+}
+
+func TestCleanSubtitle(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	const sub = "out/Inception.2010.720p.x264.de.srt"
+
+	require.NoError(t, os.MkdirAll("out", os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(sub, []byte(uncleanSubtitle), 0644))
+
+	config := defaultConfig
+	config.cleanup = fakeCleanup{ads: true, hi: true}
+	app := New(config)
+
+	res, err := app.CleanSubtitle(sub)
+	require.NoError(t, err)
+	assert.Equal(t, cleanup.Result{Ads: 1, Annotations: 2}, res)
+	assertContent(t, "1\n00:00:03,000 --> 00:00:04,500\nGr\xfc\xdf dich.\n\n", sub)
+	assertContent(t, uncleanSubtitle, sub+syncBackupExt)
+
+	res, err = app.CleanSubtitle(sub)
+	require.NoError(t, err)
+	assert.False(t, res.Changed())
+
+	_, err = New(defaultConfig).CleanSubtitle(sub)
+	assert.Error(t, err)
+}
+
+func TestCleanSubtitleStyled(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	const sub = "out/Inception.2010.720p.x264.de.ass"
+
+	require.NoError(t, os.MkdirAll("out", os.ModePerm))
+	events := "[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n"
+	styled := "Dialogue: 0,0:00:03.00,0:00:04.50,Sign,,0,0,0,,{\\pos(192,20)}Gr\xfc\xdf dich.\n"
+	original := "[Script Info]\nScriptType: v4.00+\n\n" + events +
+		"Dialogue: 0,0:00:01.00,0:00:02.50,Default,,0,0,0,,{\\i1}[Musik]{\\i0}\n" + styled +
+		"Dialogue: 0,0:00:05.00,0:00:06.50,Default,,0,0,0,,Untertitel von XYZ\\Nwww.example.com\n"
+	require.NoError(t, ioutil.WriteFile(sub, []byte(original), 0644))
+
+	config := defaultConfig
+	config.cleanup = fakeCleanup{ads: true, hi: true}
+
+	res, err := New(config).CleanSubtitle(sub)
+	require.NoError(t, err)
+	assert.Equal(t, cleanup.Result{Ads: 1, Annotations: 1}, res)
+	assertContent(t, "[Script Info]\nScriptType: v4.00+\n\n"+events+styled, sub)
+}
+
+func TestDownloadCleanSubtitle(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	require.NoError(t, copyTestFiles("test", "out"))
+
+	config := defaultConfig
+	config.cleanup = fakeCleanup{ads: true}
+
+	app := New(config)
+	app.providers = []types.Provider{fakeLinkedProvider{
+		{"https://example.com/1", uncleanSubtitle, 1.0},
+	}}
+
+	l, err := app.FindMedia("out/Inception.2010.720p.x264.mkv")
+	require.NoError(t, err)
+
+	subs, err := app.DownloadSubtitles(l, set.New(language.German), notify.AsyncDiscard())
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+
+	assertContent(t, "1\n00:00:01,000 --> 00:00:02,500\n[Musik]\n\n"+
+		"2\n00:00:03,000 --> 00:00:04,500\nHANS: Gr\xfc\xdf dich.\n\n", subs[0].Path())
+}
+
+func TestDownloadImpairedFallback(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	require.NoError(t, copyTestFiles("test", "out"))
+
+	config := defaultConfig
+	config.encoding = "utf-8"
+
+	app := New(config)
+	app.providers = []types.Provider{fakeImpairedProvider{
+		{"https://example.com/1", uncleanSubtitle, 1.0},
+	}}
+
+	l, err := app.FindMedia("out/Inception.2010.720p.x264.mkv")
+	require.NoError(t, err)
+
+	// Hearing impaired subtitles are not used, unless they are stripped
+	subs, err := app.DownloadSubtitles(l, set.New(language.German), notify.AsyncDiscard())
+	require.NoError(t, err)
+	assert.Empty(t, subs)
+
+	config.cleanup = fakeCleanup{hi: true}
+	app = New(config)
+	app.providers = []types.Provider{fakeImpairedProvider{
+		{"https://example.com/1", uncleanSubtitle, 1.0},
+	}}
+
+	subs, err = app.DownloadSubtitles(l, set.New(language.German), notify.AsyncDiscard())
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+
+	assertContent(t, "1\n00:00:03,000 --> 00:00:04,500\nGrüß dich.\n\n"+
+		"2\n00:00:05,000 --> 00:00:06,500\nUntertitel von XYZ\nwww.example.com\n", subs[0].Path())
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/charset"
+	"github.com/tympanix/supper/media/subformat"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/language"
+)
+
+// convertSubtitle cleans the subtitle in the language and converts it into
+// the preferred format and character encoding of the configuration. Subtitles
+// in an unknown format, or which can not be parsed, are kept as they are
+func (a *Application) convertSubtitle(data []byte, lang language.Tag) []byte {
+	name := a.Config().Format()
+	if name == "" && a.Config().Encoding() == "" && a.cleaner == nil {
+		return data
+	}
+
+	text, original, err := charset.ToUTF8(data, lang)
+	if err != nil {
+		return data
+	}
+
+	if a.cleaner != nil {
+		if cleaned, _, err := a.cleanText(text); err == nil {
+			text = cleaned
+		}
+	}
+
+	if format, err := subformat.ByName(name); err == nil {
+		if converted, err := subformat.Convert(text, format); err == nil {
+			text = converted
+		}
+	}
+
+	enc := original
+	if name := a.Config().Encoding(); name != "" {
+		if enc, err = charset.Lookup(name); err != nil {
+			return data
+		}
+	}
+
+	encoded, err := charset.FromUTF8(text, enc)
+	if err != nil {
+		return data
+	}
+	return encoded
+}
+
+// readSubtitle reads the subtitle at the path and decodes it into UTF-8, using
+// the language of the subtitle to detect its character encoding. An error is
+// returned if the decoded text is not in any supported subtitle format, such
+// as for binary VobSub subtitles
+func readSubtitle(path string) ([]byte, []byte, encoding.Encoding, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	lang := language.Und
+	if sub, err := media.NewLocalSubtitle(path); err == nil {
+		lang = sub.Language()
+	}
+
+	text, enc, err := charset.ToUTF8(data, lang)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if _, err := subformat.Detect(text); err != nil {
+		return nil, nil, nil, err
+	}
+	return data, text, enc, nil
+}
+
+// NormalizeSubtitle converts the subtitle at the path into the preferred
+// character encoding of the configuration. The language of the subtitle is
+// used to detect its current encoding, which is returned along with whether
+// the subtitle changed. The original subtitle is kept as a backup
+func (a *Application) NormalizeSubtitle(path string) (string, bool, error) {
+	name := a.Config().Encoding()
+	if name == "" {
+		return "", false, errors.New("no character encoding configured")
+	}
+
+	enc, err := charset.Lookup(name)
+	if err != nil {
+		return "", false, err
+	}
+
+	data, text, original, err := readSubtitle(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	encoded, err := charset.FromUTF8(text, enc)
+	if err != nil {
+		return "", false, err
+	}
+
+	from := charset.Name(original)
+	if bytes.Equal(encoded, data) {
+		return from, false, nil
+	}
+	if a.Config().Dry() {
+		return from, true, nil
+	}
+
+	return from, true, replaceSubtitle(path, data, encoded)
+}
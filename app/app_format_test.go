@@ -0,0 +1,127 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/app/notify"
+	"github.com/tympanix/supper/media/subformat"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+func TestSubtitleFormat(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	require.NoError(t, copyTestFiles("test", "out"))
+
+	config := defaultConfig
+	config.format = "vtt"
+	config.evaluator = fakeEvaluator(func(types.Media, types.Media) float32 {
+		return 1.0
+	})
+
+	app := New(config)
+	app.providers = []types.Provider{fakeLinkedProvider{
+		{"https://example.com/1", "1\n00:00:01,000 --> 00:00:02,500\nHello\n", 1.0},
+	}}
+
+	l, err := app.FindMedia("out/Inception.2010.720p.x264.mkv")
+	require.NoError(t, err)
+
+	c := notify.AsyncDiscard()
+	defer close(c)
+
+	subs, err := app.DownloadSubtitles(l, set.New(language.German), c)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+
+	assert.Equal(t, "out/Inception.2010.720p.x264.de.vtt", subs[0].Path())
+	assertContent(t, "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\nHello\n\n", subs[0].Path())
+}
+
+func TestSubtitleEncoding(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	require.NoError(t, copyTestFiles("test", "out"))
+
+	config := defaultConfig
+	config.encoding = "utf-8"
+	config.evaluator = fakeEvaluator(func(types.Media, types.Media) float32 {
+		return 1.0
+	})
+
+	app := New(config)
+	app.providers = []types.Provider{fakeLinkedProvider{
+		{"https://example.com/1", "1\n00:00:01,000 --> 00:00:02,500\nGr\xfc\xdfe\n", 1.0},
+	}}
+
+	l, err := app.FindMedia("out/Inception.2010.720p.x264.mkv")
+	require.NoError(t, err)
+
+	c := notify.AsyncDiscard()
+	defer close(c)
+
+	subs, err := app.DownloadSubtitles(l, set.New(language.German), c)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+
+	assert.Equal(t, "out/Inception.2010.720p.x264.de.srt", subs[0].Path())
+	assertContent(t, "1\n00:00:01,000 --> 00:00:02,500\nGrüße\n", subs[0].Path())
+}
+
+// windows1251 is a Russian subtitle in the Windows-1251 encoding
+const windows1251 = "1\n00:00:01,000 --> 00:00:02,500\n\xcf\xf0\xe8\xe2\xe5\xf2\n"
+
+func TestNormalizeSubtitle(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	const sub = "out/Inception.2010.720p.x264.ru.srt"
+
+	require.NoError(t, os.MkdirAll("out", os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(sub, []byte(windows1251), 0644))
+
+	config := defaultConfig
+	config.encoding = "utf-8"
+	app := New(config)
+
+	from, changed, err := app.NormalizeSubtitle(sub)
+	require.NoError(t, err)
+	assert.Equal(t, "windows-1251", from)
+	assert.True(t, changed)
+	assertContent(t, "1\n00:00:01,000 --> 00:00:02,500\nПривет\n", sub)
+	assertContent(t, windows1251, sub+syncBackupExt)
+
+	from, changed, err = app.NormalizeSubtitle(sub)
+	require.NoError(t, err)
+	assert.Equal(t, "utf-8", from)
+	assert.False(t, changed)
+
+	_, _, err = New(defaultConfig).NormalizeSubtitle(sub)
+	assert.Error(t, err)
+}
+
+func TestNormalizeSubtitleBinary(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	// VobSub subtitles share the extension of text subtitles, but are images
+	const sub = "out/Inception.2010.720p.x264.en.sub"
+	const vobsub = "\x00\x00\x01\xba\x44\x00\x04\x00\x04\x01\x01\x89\xc3\xf8\x00\x00\x01\xbd"
+
+	require.NoError(t, os.MkdirAll("out", os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(sub, []byte(vobsub), 0644))
+
+	config := defaultConfig
+	config.encoding = "utf-8"
+
+	_, _, err := New(config).NormalizeSubtitle(sub)
+	assert.Equal(t, subformat.ErrUnknownFormat, err)
+	assertContent(t, vobsub, sub)
+
+	_, err = os.Stat(sub + syncBackupExt)
+	assert.True(t, os.IsNotExist(err))
+}
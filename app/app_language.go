@@ -0,0 +1,50 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tympanix/supper/media/charset"
+	"github.com/tympanix/supper/media/langid"
+	"github.com/tympanix/supper/media/subformat"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// languageError is returned when a downloaded subtitle turns out to be in
+// another language than the provider claimed
+type languageError struct {
+	expected language.Tag
+	found    language.Tag
+}
+
+func (e languageError) Error() string {
+	return fmt.Sprintf("subtitle is in %v, not %v",
+		display.English.Languages().Name(e.found),
+		display.English.Languages().Name(e.expected))
+}
+
+// verifyLanguage identifies the language of the dialogue of the subtitle, and
+// returns an error if it is not the expected language. Subtitles which can
+// not be parsed are not verified
+func verifyLanguage(data []byte, lang language.Tag) error {
+	text, _, err := charset.ToUTF8(data, lang)
+	if err != nil {
+		return nil
+	}
+
+	cues, err := subformat.Parse(text)
+	if err != nil {
+		return nil
+	}
+
+	var lines []string
+	for _, c := range cues {
+		lines = append(lines, c.Lines...)
+	}
+
+	if found, ok := langid.Matches(strings.Join(lines, "\n"), lang); !ok {
+		return languageError{lang, found}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+const germanSubtitle = `1
+00:00:01,000 --> 00:00:04,000
+Der Zug fährt um sieben, also müssen wir früh aufstehen.
+
+2
+00:00:05,000 --> 00:00:08,000
+Hast du die Brote eingepackt? Ich dachte, das wolltest du machen.
+
+3
+00:00:09,000 --> 00:00:12,000
+Es regnet seit drei Tagen ohne Pause.
+`
+
+const indonesianSubtitle = `1
+00:00:01,000 --> 00:00:04,000
+Keretanya berangkat jam tujuh, jadi kita harus bangun pagi.
+
+2
+00:00:05,000 --> 00:00:08,000
+Apakah kau sudah membawa rotinya? Kupikir kau yang akan melakukannya.
+
+3
+00:00:09,000 --> 00:00:12,000
+Sudah hujan selama tiga hari tanpa berhenti.
+`
+
+func TestVerifyLanguage(t *testing.T) {
+	assert.NoError(t, verifyLanguage([]byte(germanSubtitle), language.German))
+	assert.NoError(t, verifyLanguage([]byte("not a subtitle"), language.German))
+
+	err := verifyLanguage([]byte(indonesianSubtitle), language.German)
+	require.Error(t, err)
+	assert.Equal(t, "subtitle is in Indonesian, not German", err.Error())
+}
+
+func TestDownloadWrongLanguage(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	require.NoError(t, copyTestFiles("test", "out"))
+
+	config := defaultConfig
+	config.evaluator = fakeEvaluator(func(m types.Media, n types.Media) float32 {
+		if r, ok := n.(rankedMedia); ok {
+			return r.score
+		}
+		return 0
+	})
+
+	app := New(config)
+	app.providers = []types.Provider{fakeLinkedProvider{
+		{"https://example.com/1", indonesianSubtitle, 0.9},
+		{"https://example.com/2", germanSubtitle, 0.8},
+	}}
+
+	l, err := app.FindMedia("out/Inception.2010.720p.x264.mkv")
+	require.NoError(t, err)
+
+	c, messages := collectEntries()
+	subs, err := app.DownloadSubtitles(l, set.New(language.German), c)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+
+	assert.Contains(t, messages(), "Wrong subtitle language")
+	assertContent(t, germanSubtitle, subs[0].Path())
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"os"
+
+	"github.com/apex/log"
+	"github.com/tympanix/supper/app/library"
+	"github.com/tympanix/supper/media/list"
+	"github.com/tympanix/supper/types"
+)
+
+// openIndex opens the library index, which is kept open while the application
+// is running. Nil is returned if the index is disabled or can not be opened,
+// e.g. if it is in use by another process, in which case media is found by
+// searching the file system
+func openIndex(config types.Config) *library.Index {
+	path := config.Index()
+	if path == "" {
+		return nil
+	}
+	index, err := library.Open(path)
+	if err != nil {
+		log.WithError(err).WithField("index", path).Warn("Library index unavailable")
+		return nil
+	}
+	return index
+}
+
+// findIndexedMedia searches for media files using the library index. The
+// file system is searched instead if the index can not be used
+func (a *Application) findIndexedMedia(roots ...string) (types.LocalMediaList, error) {
+	for _, root := range roots {
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	var result types.LocalMediaList
+	err := a.withIndex(func(index *library.Index) error {
+		medialist := make([]types.LocalMedia, 0)
+		for _, root := range roots {
+			var found types.LocalMediaList
+			if info, err := os.Stat(root); err == nil && info.IsDir() {
+				if _, err := index.Refresh(root); err != nil {
+					return err
+				}
+				if found, err = index.Find(root); err != nil {
+					return err
+				}
+			} else if found, err = walkMedia(root); err != nil {
+				return err
+			}
+			medialist = append(medialist, found.List()...)
+		}
+		result = list.NewLocalMedia(medialist...)
+		return nil
+	})
+
+	if err != nil {
+		log.WithError(err).WithField("index", a.Config().Index()).
+			Warn("Library index unavailable")
+		return walkMedia(roots...)
+	}
+
+	return result, nil
+}
+
+// withIndex calls the function with the library index, unless the index is
+// disabled or unavailable
+func (a *Application) withIndex(fn func(*library.Index) error) error {
+	if a.index == nil {
+		return nil
+	}
+	return fn(a.index)
+}
+
+// indexScraped records the identity of the scraped media in the library
+// index, if the media is a local file
+func (a *Application) indexScraped(m types.Media, s types.Scraper, scraped types.Media) {
+	local, ok := m.(types.Local)
+	if !ok || a.Config().Dry() {
+		return
+	}
+	err := a.withIndex(func(index *library.Index) error {
+		return index.SetScraped(local.Path(), s.Name(), scraped.Identity())
+	})
+	if err != nil {
+		log.WithError(err).WithField("media", m).Debug("Could not index scraped media")
+	}
+}
+
+// scrapedBefore returns true if the scraper has found the media at the path
+// to be the media itself, as recorded in the library index. Media renamed
+// after being scraped is not scraped again, since its name already is the
+// name found by the scraper
+func (a *Application) scrapedBefore(m types.Media, s types.Scraper) bool {
+	local, ok := m.(types.Local)
+	if !ok {
+		return false
+	}
+	var scraped bool
+	err := a.withIndex(func(index *library.Index) error {
+		entry, err := index.Entry(local.Path())
+		if err != nil || entry == nil {
+			return err
+		}
+		id, ok := entry.Scraped[s.Name()]
+		scraped = ok && id == m.Identity()
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).WithField("media", m).Debug("Could not read scraped media from index")
+	}
+	return scraped
+}
+
+// indexRenamed records the identities of the renamed media in the library
+// index for the destination of the media
+func (a *Application) indexRenamed(m types.LocalMedia, dest string) {
+	err := a.withIndex(func(index *library.Index) error {
+		return index.CopyScraped(m.Path(), dest)
+	})
+	if err != nil {
+		log.WithError(err).WithField("media", m).Debug("Could not index renamed media")
+	}
+}
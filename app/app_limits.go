@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter bounds the number of concurrent operations for each key
+type limiter struct {
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}
+
+func newLimiter() *limiter {
+	return &limiter{
+		sems: make(map[string]chan struct{}),
+	}
+}
+
+// acquire waits for one of the n slots of the key to be available. The
+// returned function releases the slot again. Operations are unlimited if n is
+// zero
+func (l *limiter) acquire(ctx context.Context, key string, n int) (func(), error) {
+	if n <= 0 {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	sem, ok := l.sems[key]
+	if !ok {
+		sem = make(chan struct{}, n)
+		l.sems[key] = sem
+	}
+	l.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// pacer spaces out operations such that they are at least a duration apart,
+// even when performed concurrently
+type pacer struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait blocks for the duration after the previous operation has been let
+// through
+func (p *pacer) wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	at := time.Now()
+	if p.next.After(at) {
+		at = p.next
+	}
+	at = at.Add(d)
+	p.next = at
+	p.mu.Unlock()
+
+	select {
+	case <-time.After(time.Until(at)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
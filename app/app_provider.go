@@ -0,0 +1,232 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/apex/log"
+	"github.com/fatih/set"
+	"github.com/tympanix/supper/media/list"
+	"github.com/tympanix/supper/media/provider"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+// providedSubtitle tags an online subtitle with the provider it was found by
+type providedSubtitle struct {
+	types.OnlineSubtitle
+	provider string
+}
+
+// Provider returns the name of the provider which found the subtitle
+func (s providedSubtitle) Provider() string {
+	return s.provider
+}
+
+// Completed returns false if the provider found the subtitle while it was
+// not completely translated yet
+func (s providedSubtitle) Completed() bool {
+	if c, ok := s.OnlineSubtitle.(types.Completable); ok {
+		return c.Completed()
+	}
+	return true
+}
+
+// providerError is an error returned by a single provider during a search
+type providerError struct {
+	provider string
+	err      error
+}
+
+func (e providerError) Error() string {
+	return fmt.Sprintf("%s: %v", e.provider, e.err)
+}
+
+// SearchSubtitles searches every configured provider concurrently for
+// subtitles matching the media. The results are merged, de-duplicated and
+// tagged with the provider they came from. An error is only returned if every
+// provider failed to search for the media
+func (a *Application) SearchSubtitles(m types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	return a.SearchSubtitlesContext(context.Background(), m)
+}
+
+// SearchSubtitlesContext searches every configured provider concurrently for
+// subtitles matching the media, until the context is cancelled
+func (a *Application) SearchSubtitlesContext(ctx context.Context, m types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	subs, errs := a.searchProviders(ctx, m, a.Providers())
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(subs) == 0 && len(errs) > 0 {
+		return nil, joinProviderErrors(errs)
+	}
+	for _, e := range errs {
+		log.WithError(e.err).WithField("provider", e.provider).
+			Warn("Provider failed")
+	}
+	return subs, nil
+}
+
+// searchOfflineFirst searches the offline providers for subtitles first. The
+// online providers are only searched if the offline subtitles does not
+// satisfy every language in the set
+func (a *Application) searchOfflineFirst(ctx context.Context, m types.LocalMedia, lang set.Interface) ([]types.OnlineSubtitle, []providerError) {
+	var offline, online []types.Provider
+	for _, p := range a.Providers() {
+		if o, ok := p.(types.OfflineProvider); ok && o.Offline() {
+			offline = append(offline, p)
+		} else {
+			online = append(online, p)
+		}
+	}
+
+	if len(offline) == 0 {
+		return a.searchProviders(ctx, m, online)
+	}
+
+	subs, errs := a.searchProviders(ctx, m, offline)
+
+	if len(online) == 0 || a.satisfiesLanguages(m, subs, lang) {
+		return subs, errs
+	}
+
+	more, moreErrs := a.searchProviders(ctx, m, online)
+	return append(subs, more...), append(errs, moreErrs...)
+}
+
+// satisfiesLanguages returns true if the subtitles contains a subtitle with a
+// sufficient score for every language in the set
+func (a *Application) satisfiesLanguages(m types.LocalMedia, subs []types.OnlineSubtitle, lang set.Interface) bool {
+	l, err := list.NewSubtitlesFromInterface(subs)
+	if err != nil {
+		return false
+	}
+	for _, v := range lang.List() {
+		tag, ok := v.(language.Tag)
+		if !ok {
+			return false
+		}
+		best := a.filterImpaired(l.FilterLanguage(tag).Completed()).RateByMedia(m, a.Config().Evaluator()).Best()
+		if best == nil || best.Score() < (float32(a.Config().Score())/100.0) {
+			return false
+		}
+	}
+	return true
+}
+
+// searchProviders performs the search for subtitles on the providers and
+// returns the merged results along with the errors of the failing providers.
+// Providers which does not support the media are silently ignored, as are
+// subtitles which have been rejected for the media
+func (a *Application) searchProviders(ctx context.Context, m types.LocalMedia, providers []types.Provider) ([]types.OnlineSubtitle, []providerError) {
+	if len(providers) == 0 {
+		return nil, []providerError{
+			{"supper", errors.New("no subtitle providers configured")},
+		}
+	}
+
+	results := make([][]types.OnlineSubtitle, len(providers))
+	errs := make([]error, len(providers))
+
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p types.Provider) {
+			defer wg.Done()
+			results[i], errs[i] = a.searchLimited(ctx, p, m)
+		}(i, p)
+	}
+	wg.Wait()
+
+	var failed []providerError
+	var subs []types.OnlineSubtitle
+	seen := make(map[string]bool)
+
+	for i, p := range providers {
+		if errs[i] != nil {
+			if !provider.IsErrMediaNotSupported(errs[i]) {
+				failed = append(failed, providerError{p.Name(), errs[i]})
+			}
+			continue
+		}
+		for _, s := range results[i] {
+			if link := s.Link(); link != "" {
+				key := p.Name() + ":" + link
+				if seen[key] || a.blacklist.Link(m.Identity(), link) {
+					continue
+				}
+				seen[key] = true
+			}
+			subs = append(subs, providedSubtitle{s, p.Name()})
+		}
+	}
+
+	return subs, failed
+}
+
+// searchLimited searches the provider for subtitles, respecting the maximum
+// number of concurrent searches of the provider
+func (a *Application) searchLimited(ctx context.Context, p types.Provider, m types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	release, err := a.limits.acquire(ctx, "search:"+p.Name(), provider.PolicyFor(p.Name()).Searches)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return searchProvider(ctx, p, m)
+}
+
+// searchProvider searches the provider for subtitles. Providers which can not
+// be cancelled are not searched if the context is already done
+func searchProvider(ctx context.Context, p types.Provider, m types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	if c, ok := p.(types.ContextProvider); ok {
+		return c.SearchSubtitlesContext(ctx, m)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return p.SearchSubtitles(m)
+}
+
+// scrape scrapes the media using the scraper. Scrapers which can not be
+// cancelled are not used if the context is already done
+func scrape(ctx context.Context, s types.Scraper, m types.Media) (types.Media, error) {
+	if c, ok := s.(types.ContextScraper); ok {
+		return c.ScrapeContext(ctx, m)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Scrape(m)
+}
+
+func joinProviderErrors(errs []providerError) error {
+	if len(errs) == 1 {
+		return errs[0].err
+	}
+	msg := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msg = append(msg, e.Error())
+	}
+	return errors.New(strings.Join(msg, "; "))
+}
+
+// ResolveSubtitle resolves a link to a downloadable subtitle using the
+// provider the link is tagged with. Links which are not tagged with a provider
+// are not resolved, since several providers may accept the same link
+func (a *Application) ResolveSubtitle(l types.Linker) (types.Downloadable, error) {
+	p, ok := l.(interface {
+		Provider() string
+	})
+	if !ok || p.Provider() == "" {
+		return nil, errors.New("subtitle link is not tagged with a provider")
+	}
+	for _, v := range a.Providers() {
+		if v.Name() == p.Provider() {
+			return v.ResolveSubtitle(l)
+		}
+	}
+	return nil, fmt.Errorf("unknown subtitle provider %v", p.Provider())
+}
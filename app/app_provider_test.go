@@ -0,0 +1,544 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/app/notify"
+	"github.com/tympanix/supper/media/provider"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+type linkedOnline struct {
+	online
+	link string
+}
+
+func (o linkedOnline) Link() string {
+	return o.link
+}
+
+type fakeLinkProvider struct {
+	name  string
+	links []string
+}
+
+func (f fakeLinkProvider) Name() string {
+	return f.name
+}
+
+func (f fakeLinkProvider) ResolveSubtitle(l types.Linker) (types.Downloadable, error) {
+	for _, v := range f.links {
+		if v == l.Link() {
+			return online{}, nil
+		}
+	}
+	return nil, errors.New("test provider could not resolve link")
+}
+
+func (f fakeLinkProvider) SearchSubtitles(m types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	var subs []types.OnlineSubtitle
+	for _, l := range f.links {
+		subs = append(subs, linkedOnline{
+			online{subtitle{m, language.English, false}, []byte(m.Identity())},
+			l,
+		})
+	}
+	return subs, nil
+}
+
+type fakeProviderNotSupported struct{}
+
+func (fakeProviderNotSupported) Name() string {
+	return "notsupported"
+}
+
+func (fakeProviderNotSupported) ResolveSubtitle(types.Linker) (types.Downloadable, error) {
+	return nil, errors.New("not supported")
+}
+
+func (fakeProviderNotSupported) SearchSubtitles(types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	return nil, provider.ErrMediaNotSupported{}
+}
+
+type fakeLink struct {
+	link     string
+	provider string
+}
+
+func (l fakeLink) Link() string     { return l.link }
+func (l fakeLink) Provider() string { return l.provider }
+
+func findTestVideo(t *testing.T, app *Application) types.LocalMedia {
+	media, err := app.FindMedia("test")
+	require.NoError(t, err)
+	video := media.FilterMovies().List()
+	require.NotEmpty(t, video)
+	return video[0]
+}
+
+func TestSearchSubtitlesMerge(t *testing.T) {
+	config := defaultConfig
+	config.providers = []types.Provider{
+		fakeLinkProvider{"first", []string{"a", "b", "a"}},
+		fakeLinkProvider{"second", []string{"b", "c"}},
+	}
+
+	app := New(config)
+	subs, err := app.SearchSubtitles(findTestVideo(t, app))
+	require.NoError(t, err)
+	require.Len(t, subs, 4)
+
+	var links, providers []string
+	for _, s := range subs {
+		p, ok := s.(types.ProvidedSubtitle)
+		require.True(t, ok)
+		links = append(links, p.Link())
+		providers = append(providers, p.Provider())
+	}
+
+	// links are only unique within the provider which found them
+	assert.Equal(t, []string{"a", "b", "b", "c"}, links)
+	assert.Equal(t, []string{"first", "first", "second", "second"}, providers)
+}
+
+func TestSearchSubtitlesPartialFailure(t *testing.T) {
+	config := defaultConfig
+	config.providers = []types.Provider{
+		fakeProviderError{},
+		fakeProviderNotSupported{},
+		fakeLinkProvider{"working", []string{"a"}},
+	}
+
+	app := New(config)
+	subs, err := app.SearchSubtitles(findTestVideo(t, app))
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, "working", subs[0].(types.ProvidedSubtitle).Provider())
+}
+
+func TestSearchSubtitlesAllFailing(t *testing.T) {
+	config := defaultConfig
+	config.providers = []types.Provider{
+		fakeProviderError{},
+		fakeProviderError{},
+	}
+
+	app := New(config)
+	_, err := app.SearchSubtitles(findTestVideo(t, app))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fakeprovidererror: test provider error")
+}
+
+func TestSearchSubtitlesNotSupported(t *testing.T) {
+	config := defaultConfig
+	config.providers = []types.Provider{
+		fakeProviderNotSupported{},
+	}
+
+	app := New(config)
+	subs, err := app.SearchSubtitles(findTestVideo(t, app))
+	assert.NoError(t, err)
+	assert.Empty(t, subs)
+}
+
+func TestDownloadSubtitlesPartialFailure(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	config := defaultConfig
+	config.strict = true
+	config.languages = set.New(language.German)
+
+	config.providers = []types.Provider{
+		fakeProviderError{},
+		defaultConfig.providers[0],
+	}
+
+	err := performSubtitleTest(t, subtitleLangTester(language.German), config)
+	assert.NoError(t, err)
+}
+
+func TestResolveSubtitle(t *testing.T) {
+	config := defaultConfig
+	config.providers = []types.Provider{
+		fakeLinkProvider{"first", []string{"a"}},
+		fakeLinkProvider{"second", []string{"b"}},
+	}
+
+	app := New(config)
+
+	_, err := app.ResolveSubtitle(fakeLink{"b", ""})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not tagged with a provider")
+
+	_, err = app.ResolveSubtitle(fakeLink{"b", "second"})
+	assert.NoError(t, err)
+
+	_, err = app.ResolveSubtitle(fakeLink{"b", "first"})
+	assert.Error(t, err)
+
+	_, err = app.ResolveSubtitle(fakeLink{"b", "unknown"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown subtitle provider")
+
+	_, err = app.ResolveSubtitle(fakeLink{"c", "second"})
+	assert.Error(t, err)
+}
+
+type fakeOfflineProvider struct {
+	fakeLinkProvider
+}
+
+func (fakeOfflineProvider) Offline() bool {
+	return true
+}
+
+type fakeCountingProvider struct {
+	fakeLinkProvider
+	calls *int32
+}
+
+func (f fakeCountingProvider) SearchSubtitles(m types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	atomic.AddInt32(f.calls, 1)
+	return f.fakeLinkProvider.SearchSubtitles(m)
+}
+
+func TestSearchOfflineFirst(t *testing.T) {
+	var calls int32
+
+	config := defaultConfig
+	config.providers = []types.Provider{
+		fakeCountingProvider{fakeLinkProvider{"online", []string{"b"}}, &calls},
+		fakeOfflineProvider{fakeLinkProvider{"offline", []string{"a"}}},
+	}
+
+	app := New(config)
+	video := findTestVideo(t, app)
+
+	subs, errs := app.searchOfflineFirst(context.Background(), video, set.New(language.English))
+	assert.Empty(t, errs)
+	require.Len(t, subs, 1)
+	assert.Equal(t, "offline", subs[0].(types.ProvidedSubtitle).Provider())
+	assert.Equal(t, int32(0), calls)
+
+	subs, errs = app.searchOfflineFirst(context.Background(), video, set.New(language.English, language.German))
+	assert.Empty(t, errs)
+	require.Len(t, subs, 2)
+	assert.Equal(t, "online", subs[1].(types.ProvidedSubtitle).Provider())
+	assert.Equal(t, int32(1), calls)
+}
+
+// fakeCancelProvider cancels the context when searching for more than a
+// number of media
+type fakeCancelProvider struct {
+	fakeProvider
+	cancel   context.CancelFunc
+	after    int32
+	searches int32
+}
+
+func (p *fakeCancelProvider) SearchSubtitlesContext(ctx context.Context, m types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	if atomic.AddInt32(&p.searches, 1) > p.after {
+		p.cancel()
+		return nil, ctx.Err()
+	}
+	return p.fakeProvider.SearchSubtitles(m)
+}
+
+func TestDownloadSubtitlesCancel(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := &fakeCancelProvider{
+		fakeProvider: fakeProvider{[]language.Tag{language.German}},
+		cancel:       cancel,
+		after:        1,
+	}
+
+	config := defaultConfig
+	config.languages = set.New(language.German)
+	config.providers = []types.Provider{p}
+
+	app := New(config)
+
+	require.NoError(t, copyTestFiles("test", "out"))
+	media, err := app.FindMedia("out")
+	require.NoError(t, err)
+	require.True(t, media.FilterVideo().Len() > 1)
+
+	c := notify.AsyncDiscard()
+	defer close(c)
+
+	subs, err := app.DownloadSubtitlesContext(ctx, media, config.Languages(), c)
+	assert.Equal(t, context.Canceled, err)
+	assert.Len(t, subs, 1)
+	assert.Equal(t, int32(2), p.searches)
+}
+
+// cancelOnline cancels the context while the subtitle is downloaded, and fails
+// with the error the http client returns when its request is cancelled
+type cancelOnline struct {
+	online
+	cancel context.CancelFunc
+}
+
+func (o cancelOnline) Download() (io.ReadCloser, error) {
+	o.cancel()
+	return nil, &url.Error{Op: "Get", URL: "http://localhost/subtitle", Err: context.Canceled}
+}
+
+// fakeCancelDownloadProvider finds subtitles which cancel the context when
+// downloaded, for every media but the first
+type fakeCancelDownloadProvider struct {
+	fakeProvider
+	cancel   context.CancelFunc
+	searches int32
+}
+
+func (p *fakeCancelDownloadProvider) SearchSubtitles(m types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	subs, err := p.fakeProvider.SearchSubtitles(m)
+	if atomic.AddInt32(&p.searches, 1) > 1 {
+		for i, s := range subs {
+			subs[i] = cancelOnline{s.(online), p.cancel}
+		}
+	}
+	return subs, err
+}
+
+func TestDownloadSubtitlesCancelDownload(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := defaultConfig
+	config.strict = true
+	config.languages = set.New(language.German)
+	config.providers = []types.Provider{&fakeCancelDownloadProvider{
+		fakeProvider: fakeProvider{[]language.Tag{language.German}},
+		cancel:       cancel,
+	}}
+
+	app := New(config)
+
+	require.NoError(t, copyTestFiles("test", "out"))
+	media, err := app.FindMedia("out")
+	require.NoError(t, err)
+	require.True(t, media.FilterVideo().Len() > 1)
+
+	c := notify.AsyncDiscard()
+	defer close(c)
+
+	// the subtitle downloaded before the cancellation is kept
+	subs, err := app.DownloadSubtitlesContext(ctx, media, config.Languages(), c)
+	assert.Equal(t, context.Canceled, err)
+	assert.Len(t, subs, 1)
+}
+
+func TestRenameMediaCancel(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	app := New(defaultConfig)
+
+	media, err := app.FindMedia("test")
+	require.NoError(t, err)
+
+	err = app.RenameMediaContext(ctx, media)
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = os.Stat("out")
+	assert.True(t, os.IsNotExist(err))
+}
+
+// fakeSlowProvider records the maximum number of concurrent searches. The
+// search for the slow media takes longer than for other media
+type fakeSlowProvider struct {
+	fakeProvider
+	slow    string
+	active  int32
+	maximum int32
+}
+
+func (p *fakeSlowProvider) Name() string {
+	return "slowprovider"
+}
+
+func (p *fakeSlowProvider) SearchSubtitles(m types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	n := atomic.AddInt32(&p.active, 1)
+	defer atomic.AddInt32(&p.active, -1)
+	for {
+		max := atomic.LoadInt32(&p.maximum)
+		if n <= max || atomic.CompareAndSwapInt32(&p.maximum, max, n) {
+			break
+		}
+	}
+	if m.Identity() == p.slow {
+		time.Sleep(100 * time.Millisecond)
+	} else {
+		time.Sleep(20 * time.Millisecond)
+	}
+	return p.fakeProvider.SearchSubtitles(m)
+}
+
+func performWorkersTest(t *testing.T, workers int) (*fakeSlowProvider, []*notify.Entry) {
+	config := defaultConfig
+	config.workers = workers
+	config.languages = set.New(language.German)
+
+	app := New(config)
+
+	require.NoError(t, copyTestFiles("test", "out"))
+	media, err := app.FindMedia("out")
+	require.NoError(t, err)
+
+	video := media.FilterVideo().List()
+	require.True(t, len(video) > 1)
+
+	p := &fakeSlowProvider{
+		fakeProvider: fakeProvider{[]language.Tag{language.German}},
+		slow:         video[0].Identity(),
+	}
+	config.providers = []types.Provider{p}
+	app = New(config)
+
+	var entries []*notify.Entry
+	c := make(chan *notify.Entry)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for e := range c {
+			entries = append(entries, e)
+		}
+	}()
+
+	subs, err := app.DownloadSubtitles(media, config.Languages(), c)
+	close(c)
+	<-done
+
+	require.NoError(t, err)
+	assert.Len(t, subs, len(video))
+
+	return p, entries
+}
+
+func TestDownloadSubtitlesWorkers(t *testing.T) {
+	defer cleanRenameTest(t)
+	defer provider.SetPolicies(provider.DefaultPolicy, nil)
+
+	provider.SetPolicies(provider.Policy{}, nil)
+
+	p, entries := performWorkersTest(t, 2)
+	assert.Equal(t, int32(2), p.maximum)
+
+	// Notifications are in the order of the media, even though the first
+	// media finishes last
+	var items []string
+	for _, e := range entries {
+		items = append(items, e.Fields["item"].(string))
+	}
+	assert.Equal(t, []string{"1/2", "2/2"}, items)
+}
+
+func TestDownloadSubtitlesSearchLimit(t *testing.T) {
+	defer cleanRenameTest(t)
+	defer provider.SetPolicies(provider.DefaultPolicy, nil)
+
+	provider.SetPolicies(provider.Policy{}, map[string]provider.Policy{
+		"slowprovider": {Searches: 1},
+	})
+
+	p, _ := performWorkersTest(t, 2)
+	assert.Equal(t, int32(1), p.maximum)
+}
+
+// incompleteSubtitle is a subtitle which is not completely translated yet
+type incompleteSubtitle struct {
+	linkedSubtitle
+}
+
+func (incompleteSubtitle) Completed() bool {
+	return false
+}
+
+// fakeIncompleteProvider finds subtitles which are not completely translated
+// yet, except for the last one
+type fakeIncompleteProvider []fakeCandidate
+
+func (p fakeIncompleteProvider) Name() string {
+	return "fakeincompleteprovider"
+}
+
+func (p fakeIncompleteProvider) ResolveSubtitle(link types.Linker) (types.Downloadable, error) {
+	return nil, nil
+}
+
+func (p fakeIncompleteProvider) SearchSubtitles(m types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	var subs []types.OnlineSubtitle
+	for i, c := range p {
+		s := subtitle{rankedMedia{m, c.score}, language.German, false}
+		linked := linkedSubtitle{online{s, []byte(c.data)}, c.link}
+		if i < len(p)-1 {
+			subs = append(subs, incompleteSubtitle{linked})
+			continue
+		}
+		subs = append(subs, linked)
+	}
+	return subs, nil
+}
+
+func TestDownloadSubtitlesIncomplete(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	require.NoError(t, copyTestFiles("test", "out"))
+
+	config := defaultConfig
+	config.evaluator = fakeEvaluator(func(m types.Media, n types.Media) float32 {
+		if r, ok := n.(rankedMedia); ok {
+			return r.score
+		}
+		return 0
+	})
+
+	app := New(config)
+	app.providers = []types.Provider{fakeIncompleteProvider{
+		{"https://example.com/1", "incomplete", 0.9},
+		{"https://example.com/2", "complete", 0.8},
+	}}
+
+	l, err := app.FindMedia("out/Inception.2010.720p.x264.mkv")
+	require.NoError(t, err)
+
+	c := notify.AsyncDiscard()
+	defer close(c)
+
+	subs, err := app.DownloadSubtitles(l, set.New(language.German), c)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assertContent(t, "complete", "out/Inception.2010.720p.x264.de.srt")
+
+	app.providers = []types.Provider{fakeIncompleteProvider{
+		{"https://example.com/1", "incomplete", 0.9},
+		{"https://example.com/2", "incomplete", 0.8},
+		{"https://example.com/3", "complete", 0.7},
+	}}
+	found, errs := app.searchProviders(context.Background(), l.List()[0], app.providers)
+	assert.Empty(t, errs)
+	require.Len(t, found, 3)
+	assert.False(t, found[0].(types.Completable).Completed())
+	assert.True(t, found[2].(types.Completable).Completed())
+}
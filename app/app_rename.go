@@ -2,6 +2,7 @@ package app
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"html"
@@ -128,6 +129,13 @@ func truncateSpaces(str string) string {
 
 // RenameMedia traverses the local media list and renames the media
 func (a *Application) RenameMedia(list types.LocalMediaList) error {
+	return a.RenameMediaContext(context.Background(), list)
+}
+
+// RenameMediaContext traverses the local media list and renames the media.
+// When the context is cancelled renaming stops before the next media, and the
+// error of the context is returned
+func (a *Application) RenameMediaContext(ctx context.Context, list types.LocalMediaList) error {
 
 	renamer, ok := Renamers[a.Config().RenameAction()]
 
@@ -135,44 +143,54 @@ func (a *Application) RenameMedia(list types.LocalMediaList) error {
 		return fmt.Errorf("%s: unknown action", a.Config().RenameAction())
 	}
 
-	for _, m := range list.List() {
-		ctx := log.WithField("media", m).WithField("action", a.Config().RenameAction())
+	for i, m := range list.List() {
+		if err := ctx.Err(); err != nil {
+			log.WithField("done", fmt.Sprintf("%v/%v", i, len(list.List()))).
+				Warn("Rename cancelled")
+			return err
+		}
+
+		entry := log.WithField("media", m).WithField("action", a.Config().RenameAction())
 
-		dest, err := a.scrapeAndRenameMedia(m, m)
+		dest, err := a.scrapeAndRenameMedia(ctx, m, m)
 
 		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
 			if a.Config().Strict() {
 				return err
 			}
-			ctx.Error("Could not scrape media")
+			entry.Error("Could not scrape media")
 			continue
 		}
 
 		if !a.Config().Dry() {
 			err = renamer.Rename(m, dest, a.Config().Force())
 		} else {
-			ctx.WithField("reason", "dry-run").Info("Skip rename")
+			entry.WithField("reason", "dry-run").Info("Skip rename")
 			continue
 		}
 
 		if err != nil {
 			if media.IsExistsErr(err) {
-				ctx.WithField("reason", "media already exists").Warn("Rename skipped")
+				entry.WithField("reason", "media already exists").Warn("Rename skipped")
 			} else {
-				ctx.WithError(err).Error("Rename failed")
+				entry.WithError(err).Error("Rename failed")
 			}
 			if a.Config().Strict() {
 				return err
 			}
 		} else {
-			ctx.Info("Media renamed")
+			a.indexRenamed(m, dest)
+			entry.Info("Media renamed")
 		}
 	}
-	return nil
+	return ctx.Err()
 }
 
-func (a *Application) scrapeAndRenameMedia(info os.FileInfo, m types.Media) (string, error) {
-	scraped, err := a.scrapeMedia(m)
+func (a *Application) scrapeAndRenameMedia(ctx context.Context, info os.FileInfo, m types.Media) (string, error) {
+	scraped, err := a.scrapeMedia(ctx, m)
 
 	if err != nil {
 		return "", err
@@ -206,9 +224,13 @@ func (a *Application) renameMedia(info os.FileInfo, m types.Media) (dest string,
 	return "", media.NewUnknownErr()
 }
 
-func (a *Application) scrapeMedia(m types.Media) (types.Media, error) {
+func (a *Application) scrapeMedia(ctx context.Context, m types.Media) (types.Media, error) {
 	for _, s := range a.Scrapers() {
-		scraped, err := s.Scrape(m)
+		if a.scrapedBefore(m, s) {
+			return m, nil
+		}
+
+		scraped, err := scrape(ctx, s, m)
 
 		if err != nil {
 			if provider.IsErrMediaNotSupported(err) {
@@ -216,6 +238,7 @@ func (a *Application) scrapeMedia(m types.Media) (types.Media, error) {
 			}
 			return nil, err
 		}
+		a.indexScraped(m, s, scraped)
 		return scraped, nil
 	}
 	return nil, errors.New("no scrapers to use for media")
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"html/template"
 	"io/ioutil"
@@ -13,6 +14,7 @@ import (
 	"github.com/tympanix/supper/media/score"
 	"golang.org/x/text/language"
 
+	"github.com/tympanix/supper/app/library"
 	"github.com/tympanix/supper/media"
 	"github.com/tympanix/supper/media/provider"
 
@@ -59,6 +61,10 @@ type fakeProvider struct {
 	langs []language.Tag
 }
 
+func (f fakeProvider) Name() string {
+	return "fakeprovider"
+}
+
 func (f fakeProvider) ResolveSubtitle(link types.Linker) (types.Downloadable, error) {
 	return nil, errors.New("test provider does not support resolving of subtitles")
 }
@@ -75,6 +81,10 @@ type fakeProviderDownloadError struct {
 	langs []language.Tag
 }
 
+func (f fakeProviderDownloadError) Name() string {
+	return "fakeproviderdownloaderror"
+}
+
 func (f fakeProviderDownloadError) ResolveSubtitle(link types.Linker) (types.Downloadable, error) {
 	return nil, errors.New("test provider does not support resolving of subtitles")
 }
@@ -93,7 +103,17 @@ type fakeConfig struct {
 	force     bool
 	dry       bool
 	score     int
+	upgrade   int
+	format    string
+	encoding  string
+	validate  string
+	cachedir  string
+	datadir   string
 	delay     time.Duration
+	workers   int
+	index     string
+	watch     fakeWatch
+	cleanup   fakeCleanup
 	scrapers  []types.Scraper
 	providers []types.Provider
 	languages set.Interface
@@ -105,6 +125,7 @@ func (c fakeConfig) Languages() set.Interface       { return c.languages }
 func (c fakeConfig) APIKeys() types.APIKeys         { return fakeAPIKeys{} }
 func (c fakeConfig) Config() string                 { return "" }
 func (c fakeConfig) Delay() time.Duration           { return c.delay }
+func (c fakeConfig) Workers() int                   { return c.workers }
 func (c fakeConfig) Dry() bool                      { return c.dry }
 func (c fakeConfig) Force() bool                    { return c.force }
 func (c fakeConfig) Impaired() bool                 { return false }
@@ -114,6 +135,10 @@ func (c fakeConfig) MediaFilter() types.MediaFilter { return nil }
 func (c fakeConfig) Modified() time.Duration        { return 0 }
 func (c fakeConfig) Plugins() []types.Plugin        { return c.plugins }
 func (c fakeConfig) Score() int                     { return c.score }
+func (c fakeConfig) Upgrade() int                   { return c.upgrade }
+func (c fakeConfig) Format() string                 { return c.format }
+func (c fakeConfig) Encoding() string               { return c.encoding }
+func (c fakeConfig) Validation() string             { return c.validate }
 func (c fakeConfig) Strict() bool                   { return c.strict }
 func (c fakeConfig) Verbose() bool                  { return false }
 func (c fakeConfig) Providers() []types.Provider    { return c.providers }
@@ -121,6 +146,49 @@ func (c fakeConfig) Scrapers() []types.Scraper      { return c.scrapers }
 func (c fakeConfig) RenameAction() string           { return c.action }
 func (c fakeConfig) Evaluator() types.Evaluator     { return c.evaluator }
 func (c fakeConfig) ProxyPath() string              { return "/" }
+func (c fakeConfig) CacheDir() string               { return c.cachedir }
+func (c fakeConfig) DataDir() string                { return c.datadir }
+func (c fakeConfig) Index() string                  { return c.index }
+func (c fakeConfig) Offline() bool                  { return false }
+func (c fakeConfig) Jobs() types.JobsConfig         { return fakeJobs{} }
+func (c fakeConfig) Watch() types.WatchConfig       { return c.watch }
+func (c fakeConfig) Wanted() types.WantedConfig     { return fakeWanted{} }
+func (c fakeConfig) Cleanup() types.CleanupConfig   { return c.cleanup }
+
+type fakeJobs struct{}
+
+func (j fakeJobs) Concurrency() int { return 1 }
+func (j fakeJobs) Queue() int       { return 0 }
+func (j fakeJobs) History() int     { return 10 }
+
+type fakeWatch struct {
+	stable    time.Duration
+	rename    bool
+	extract   bool
+	subtitles bool
+}
+
+func (w fakeWatch) Directories() []string { return nil }
+func (w fakeWatch) Stable() time.Duration { return w.stable }
+func (w fakeWatch) Rename() bool          { return w.rename }
+func (w fakeWatch) Extract() bool         { return w.extract }
+func (w fakeWatch) Subtitles() bool       { return w.subtitles }
+
+type fakeWanted struct{}
+
+func (w fakeWanted) Schedule() []time.Duration { return []time.Duration{time.Hour} }
+func (w fakeWanted) MaxAge() time.Duration     { return 0 }
+
+type fakeCleanup struct {
+	ads      bool
+	hi       bool
+	patterns []string
+}
+
+func (c fakeCleanup) Ads() bool             { return c.ads }
+func (c fakeCleanup) HearingImpaired() bool { return c.hi }
+func (c fakeCleanup) Patterns() []string    { return c.patterns }
+func (c fakeCleanup) Phrases() []string     { return nil }
 
 type fakeTemplates struct {
 	output         string
@@ -144,8 +212,9 @@ func (c fakeTemplates) TVShows() types.MediaConfig {
 
 type fakeAPIKeys struct{}
 
-func (k fakeAPIKeys) TheMovieDB() string { return "" }
-func (k fakeAPIKeys) TheTVDB() string    { return "" }
+func (k fakeAPIKeys) TheMovieDB() string    { return "" }
+func (k fakeAPIKeys) TheTVDB() string       { return "" }
+func (k fakeAPIKeys) OpenSubtitles() string { return "" }
 
 type fakeMediaConfig struct {
 	directory string
@@ -157,6 +226,8 @@ func (m fakeMediaConfig) Template() *template.Template { return m.template }
 
 type fakeScraper []types.Media
 
+func (s fakeScraper) Name() string { return "fakescraper" }
+
 func (s fakeScraper) Scrape(m types.Media) (types.Media, error) {
 	if s, ok := m.TypeSubtitle(); ok {
 		return s.ForMedia(), nil
@@ -320,6 +391,8 @@ func TestRenameTemplateNil(t *testing.T) {
 
 type fakeUnsupportedScraper struct{}
 
+func (fakeUnsupportedScraper) Name() string { return "fakeunsupportedscraper" }
+
 func (fakeUnsupportedScraper) Scrape(m types.Media) (types.Media, error) {
 	return nil, provider.ErrMediaNotSupported{}
 }
@@ -340,8 +413,57 @@ func TestRenameUnsupportedScrapers(t *testing.T) {
 	cleanRenameTest(t)
 }
 
+// fakeCountingScraper counts the media it scrapes
+type fakeCountingScraper struct {
+	scraped *int
+}
+
+func (fakeCountingScraper) Name() string { return "fakecountingscraper" }
+
+func (s fakeCountingScraper) Scrape(m types.Media) (types.Media, error) {
+	*s.scraped++
+	return fakeScraper{}.Scrape(m)
+}
+
+func TestRenameScrapedBefore(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	dir, err := ioutil.TempDir("", "supper-index")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var scraped int
+	config := defaultConfig
+	config.index = filepath.Join(dir, "library.db")
+	config.scrapers = []types.Scraper{fakeCountingScraper{&scraped}}
+
+	require.NoError(t, copyTestFiles("test", "out"))
+
+	app := New(config)
+	defer app.Close()
+	require.NotNil(t, app.index)
+
+	l, err := app.FindMedia("out/Inception.2010.720p.x264.mkv")
+	require.NoError(t, err)
+	require.Equal(t, 1, l.Len())
+
+	// The index is in use while the application is running
+	_, err = library.Open(config.index)
+	assert.Error(t, err)
+
+	// Media which has been scraped into itself is not scraped again
+	for i := 0; i < 2; i++ {
+		m, err := app.scrapeMedia(context.Background(), l.List()[0])
+		require.NoError(t, err)
+		assert.Equal(t, l.List()[0].Identity(), m.Identity())
+	}
+	assert.Equal(t, 1, scraped)
+}
+
 type fakeErrorScraper struct{}
 
+func (fakeErrorScraper) Name() string { return "fakeerrorscraper" }
+
 func (fakeErrorScraper) Scrape(m types.Media) (types.Media, error) {
 	return nil, errors.New("mocked error")
 }
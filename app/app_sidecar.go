@@ -0,0 +1,65 @@
+package app
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tympanix/supper/app/notify"
+	"github.com/tympanix/supper/media/sidecar"
+	"github.com/tympanix/supper/types"
+)
+
+// recordSubtitle stores where the downloaded subtitle came from next to it,
+// such that bad downloads can be audited and the subtitle can be upgraded
+// later. Subtitles scoring below the upgrade threshold are kept in the wanted
+// subtitles until a better subtitle has been found. The outcome of the sanity
+// checks of the subtitle is recorded as well, if any
+func (a *Application) recordSubtitle(note notify.Context, m types.Video, s types.LocalSubtitle, onl types.OnlineSubtitle, score float32, check *sidecar.Check, c chan<- *notify.Entry) {
+	rec := sidecar.Record{
+		Provider:        providerName(onl),
+		Link:            onl.Link(),
+		Release:         releaseName(onl.ForMedia()),
+		Score:           score,
+		HearingImpaired: onl.HearingImpaired(),
+		Downloaded:      time.Now(),
+		Check:           check,
+	}
+
+	hash, err := sidecar.Hash(s.Path())
+	if err != nil {
+		c <- note.WithError(err).Debug("Could not hash subtitle")
+	}
+	rec.Hash = hash
+
+	if err := sidecar.Set(s.Path(), rec); err != nil {
+		c <- note.WithError(err).Debug("Could not record subtitle")
+	}
+
+	if score < a.upgradeScore() {
+		a.want(m.Path(), s.Language())
+	} else {
+		a.found(m.Path(), s.Language())
+	}
+}
+
+// releaseName describes the release which the subtitle was made for, e.g.
+// "Inception (2010) 720p BluRay x264 SPARKS"
+func releaseName(m types.Media) string {
+	if m == nil {
+		return ""
+	}
+	parts := []string{m.String()}
+	if meta := m.Meta(); meta != nil {
+		for _, p := range []string{
+			meta.Quality().String(),
+			meta.Source().String(),
+			meta.Codec().String(),
+			meta.Group(),
+		} {
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -1,14 +1,19 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"time"
+	"io"
+	"strings"
+	"sync"
 
 	"github.com/fatih/set"
 	"github.com/tympanix/supper/app/logutil"
 	"github.com/tympanix/supper/app/notify"
 	"github.com/tympanix/supper/media/list"
+	"github.com/tympanix/supper/media/provider"
+	"github.com/tympanix/supper/media/sidecar"
 	"github.com/tympanix/supper/types"
 	"golang.org/x/text/language"
 	"golang.org/x/text/language/display"
@@ -17,8 +22,16 @@ import (
 // DownloadSubtitles downloads subtitles for a whole list of mediafiles for every
 // langauge in the language set
 func (a *Application) DownloadSubtitles(input types.LocalMediaList, lang set.Interface, c chan<- *notify.Entry) ([]types.LocalSubtitle, error) {
-	var result []types.LocalSubtitle
+	return a.DownloadSubtitlesContext(context.Background(), input, lang, c)
+}
 
+// DownloadSubtitlesContext downloads subtitles for a whole list of mediafiles
+// for every language in the language set. Media is processed concurrently by
+// the configured number of workers, while notifications are sent in the order
+// of the media. When the context is cancelled the download stops before the
+// next media, and the subtitles downloaded so far are returned along with the
+// error of the context
+func (a *Application) DownloadSubtitlesContext(ctx context.Context, input types.LocalMediaList, lang set.Interface, c chan<- *notify.Entry) ([]types.LocalSubtitle, error) {
 	if input == nil {
 		return nil, errors.New("no media supplied for subtitles")
 	}
@@ -33,129 +46,324 @@ func (a *Application) DownloadSubtitles(input types.LocalMediaList, lang set.Int
 		return nil, errors.New("no video media found in path")
 	}
 
-	video, err := video.FilterMissingSubs(lang)
+	// Videos with existing subtitles may still be upgraded
+	if a.Config().Upgrade() <= 0 {
+		var err error
+		video, err = video.FilterMissingSubs(lang)
 
-	if err != nil {
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Iterate all media files in the list
-	for i, item := range video.List() {
-		ctx := notify.WithFields(notify.Fields{
-			"media": item,
-			"item":  fmt.Sprintf("%v/%v", i+1, video.Len()),
-		})
+	items := video.List()
+	results := make([]subtitleResult, len(items))
+	streams := make([]chan *notify.Entry, len(items))
 
-		cursubs, err := item.ExistingSubtitles()
+	for i := range streams {
+		streams[i] = make(chan *notify.Entry, notifyBuffer)
+	}
 
-		if err != nil {
-			return nil, err
+	// Forward notifications one media at a time in the order of the list
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for _, s := range streams {
+			for e := range s {
+				c <- e
+			}
 		}
+	}()
 
-		missingLangs := set.Difference(lang, cursubs.LanguageSet())
+	// Stop every worker when a media fails
+	work, stop := context.WithCancel(ctx)
+	defer stop()
 
-		if missingLangs.Size() == 0 {
-			continue
+	workers := a.Config().Workers()
+	if workers <= 0 {
+		workers = 1
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				r := &results[i]
+				if work.Err() == nil {
+					r.started = true
+					r.subs, r.err = a.downloadMediaSubtitles(work, items[i], i, len(items), lang, streams[i])
+					if r.err != nil {
+						// Errors after the work has stopped are caused by it
+						r.cancelled = work.Err() != nil
+						stop()
+					}
+				}
+				close(streams[i])
+			}
+		}()
+	}
+
+	for i := range items {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	<-forwarded
+
+	var result []types.LocalSubtitle
+	var done int
+	for _, r := range results {
+		if r.err != nil && !r.cancelled {
+			return nil, r.err
+		}
+		result = append(result, r.subs...)
+		if r.started && r.err == nil {
+			done++
 		}
+	}
 
-		var subs = list.Subtitles()
+	if err := ctx.Err(); err != nil {
+		c <- notify.WithField("done", fmt.Sprintf("%v/%v", done, len(items))).
+			Warn("Subtitles cancelled")
+		return result, err
+	}
 
-		if !a.Config().Dry() {
-			var search []types.OnlineSubtitle
-			search, err = a.SearchSubtitles(item)
-			if err != nil {
-				c <- ctx.WithError(err).Error("Subtitle failed")
-				if a.Config().Strict() {
-					return nil, err
+	return result, nil
+}
+
+// notifyBuffer is the number of notifications buffered for each media while
+// waiting for the preceding media to finish
+const notifyBuffer = 64
+
+// subtitleResult is the outcome of downloading subtitles for a single media
+type subtitleResult struct {
+	started   bool
+	cancelled bool
+	subs      []types.LocalSubtitle
+	err       error
+}
+
+// downloadMediaSubtitles downloads subtitles for the media in every language of
+// the set which is missing. Errors which should abort the whole download are
+// returned, while other errors are only notified
+func (a *Application) downloadMediaSubtitles(ctx context.Context, item types.Video, i int, n int, lang set.Interface, c chan<- *notify.Entry) ([]types.LocalSubtitle, error) {
+	var result []types.LocalSubtitle
+
+	note := notify.WithFields(notify.Fields{
+		"media": item,
+		"item":  fmt.Sprintf("%v/%v", i+1, n),
+	})
+
+	cursubs, err := item.ExistingSubtitles()
+
+	if err != nil {
+		return nil, err
+	}
+
+	upgrades := a.upgradable(cursubs, lang)
+	missingLangs := set.Difference(lang, cursubs.LanguageSet())
+	for l := range upgrades {
+		missingLangs.Add(l)
+	}
+
+	if missingLangs.Size() == 0 {
+		return nil, nil
+	}
+
+	var subs = list.Subtitles()
+
+	if !a.Config().Dry() {
+		search, errs := a.searchOfflineFirst(ctx, item, missingLangs)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if len(search) == 0 && len(errs) > 0 {
+			err = joinProviderErrors(errs)
+			c <- note.WithError(err).Error("Subtitle failed")
+			for _, v := range missingLangs.List() {
+				if l, ok := v.(language.Tag); ok {
+					a.want(item.Path(), l)
 				}
-				continue
 			}
-			subs, err = list.NewSubtitlesFromInterface(search)
-			if err != nil {
-				ctx.WithError(err).Fatal("Subtitle error")
+			if a.Config().Strict() {
+				return nil, err
 			}
+			return nil, nil
 		}
-
-		subs = subs.HearingImpaired(a.Config().Impaired())
-
-		// Download subtitle for each language
-		for _, v := range missingLangs.List() {
-			l, ok := v.(language.Tag)
-			if !ok {
-				return nil, logutil.Errorf("unknown language %v", v)
+		for _, e := range errs {
+			if open, ok := e.err.(provider.ErrCircuitOpen); ok {
+				c <- note.WithField("provider", e.provider).WithField("until", open.Until).
+					Warn("Provider unavailable")
+				continue
 			}
+			c <- note.WithField("provider", e.provider).WithError(e.err).
+				Warn("Provider failed")
+		}
+		subs, err = list.NewSubtitlesFromInterface(search)
+		if err != nil {
+			note.WithError(err).Fatal("Subtitle error")
+		}
+	}
 
-			ctx = ctx.WithField("lang", display.English.Languages().Name(l))
+	// Download subtitle for each language
+	for _, v := range missingLangs.List() {
+		l, ok := v.(language.Tag)
+		if !ok {
+			return nil, logutil.Errorf("unknown language %v", v)
+		}
 
-			if a.Config().Delay() > 0 {
-				time.Sleep(a.Config().Delay())
-			}
+		note = note.WithField("lang", display.English.Languages().Name(l))
+
+		if err := a.delay.wait(ctx, a.Config().Delay()); err != nil {
+			return result, err
+		}
 
-			langsubs := subs.FilterLanguage(l)
+		langsubs := a.filterImpaired(subs.FilterLanguage(l).Completed())
+		current, upgrade := upgrades[l]
 
-			if langsubs.Len() == 0 && !a.Config().Dry() {
-				c <- ctx.Warn("No subtitle available")
-				continue
+		if langsubs.Len() == 0 && !a.Config().Dry() {
+			if upgrade {
+				c <- note.Debug("No better subtitle available")
+			} else {
+				c <- note.Warn("No subtitle available")
 			}
+			a.want(item.Path(), l)
+			continue
+		}
 
-			if !a.Config().Dry() {
-				rated := langsubs.RateByMedia(item, a.Config().Evaluator())
-				sub, err := a.downloadBestSubtitle(ctx, item, rated, 3, c)
+		if !a.Config().Dry() {
+			rated := langsubs.RateByMedia(item, a.Config().Evaluator())
+			var backup string
+			if upgrade {
+				rated = betterThan(rated, current.score)
+				if rated.Len() == 0 {
+					c <- note.WithField("score", percent(current.score)).Debug("No better subtitle available")
+					a.want(item.Path(), l)
+					continue
+				}
+				backup, err = backupSubtitle(current.path)
 				if err != nil {
-					if a.Config().Strict() {
-						return nil, err
-					}
-					c <- ctx.WithError(err).Error("Could not download subtitle")
+					c <- note.WithError(err).Error("Could not upgrade subtitle")
 					continue
 				}
-				result = append(result, sub)
-			} else {
-				c <- ctx.WithField("reason", "dry-run").Info("Skip download")
 			}
+			sub, err := a.downloadBestSubtitle(ctx, note, item, rated, 3, c)
+			if err != nil && upgrade {
+				if err := restoreSubtitle(current.path, backup); err != nil {
+					c <- note.WithError(err).Error("Could not restore subtitle")
+				}
+			}
+			if err != nil && ctx.Err() != nil {
+				return result, err
+			}
+			if err != nil {
+				a.want(item.Path(), l)
+				if a.Config().Strict() {
+					return nil, err
+				}
+				c <- note.WithError(err).Error("Could not download subtitle")
+				continue
+			}
+			if upgrade {
+				// The upgraded subtitle may be in another format than before
+				if sub.Path() != current.path {
+					if err := sidecar.Remove(current.path); err != nil {
+						c <- note.WithError(err).Debug("Could not remove subtitle record")
+					}
+				}
+				c <- note.WithField("previous", percent(current.score)).Info("Subtitle upgraded")
+			}
+			result = append(result, sub)
+		} else {
+			c <- note.WithField("reason", "dry-run").Info("Skip download")
 		}
 	}
 	return result, nil
 }
 
-func (a *Application) downloadBestSubtitle(ctx notify.Context, m types.Video, l types.RatedSubtitleList, retries int, c chan<- *notify.Entry) (types.LocalSubtitle, error) {
+func (a *Application) downloadBestSubtitle(ctx context.Context, note notify.Context, m types.Video, l types.RatedSubtitleList, retries int, c chan<- *notify.Entry) (types.LocalSubtitle, error) {
 	if l.Len() == 0 {
-		return nil, ctx.Warn("No subtitles satisfied media")
+		return nil, note.Warn("No subtitles satisfied media")
 	}
 	sub := l.Best()
 	if sub.Score() < (float32(a.Config().Score()) / 100.0) {
-		return nil, ctx.Warn("Score too low %.0f%%", sub.Score()*100.0)
+		return nil, note.Warn("Score too low %.0f%%", sub.Score()*100.0)
 	}
 	onl, ok := sub.Subtitle().(types.OnlineSubtitle)
 	if !ok {
-		ctx.Fatal("Subtitle could not be cast to online subtitle")
+		note.Fatal("Subtitle could not be cast to online subtitle")
+	}
+	srt, check, err := a.downloadAllowed(ctx, m, onl)
+	if err != nil && ctx.Err() != nil {
+		return nil, err
 	}
-	srt, err := onl.Download()
 	if err != nil && retries > 0 {
 		p := list.RatedSubtitles(l.List()[1:])
 		if p.Len() <= 0 {
-			return nil, ctx.Error(err.Error())
+			return nil, note.Error("%v", err)
+		}
+		switch err.(type) {
+		case languageError:
+			c <- note.WithError(err).Warn("Wrong subtitle language")
+		case checkError:
+			c <- note.WithError(err).Warn("Subtitle failed checks")
+		default:
+			c <- note.WithError(err).Debug("Retrying subtitle")
 		}
-		c <- ctx.WithError(err).Debug("Retrying subtitle")
-		return a.downloadBestSubtitle(ctx, m, p, retries-1, c)
+		return a.downloadBestSubtitle(ctx, note, m, p, retries-1, c)
 	}
 	if err != nil {
-		return nil, ctx.Error(err.Error())
+		return nil, note.Error("%v", err)
 	}
 	defer srt.Close()
 	saved, err := m.SaveSubtitle(srt, onl.Language())
 	if err != nil {
-		return nil, ctx.Error(err.Error())
+		return nil, note.Error("%v", err)
 	}
 
-	score := fmt.Sprintf("%.0f%%", sub.Score()*100.0)
-	c <- ctx.WithField("score", score).WithExtra("sub", saved).Info("Subtitle downloaded")
+	c <- note.WithField("score", percent(sub.Score())).WithExtra("sub", saved).Info("Subtitle downloaded")
+	if check != nil && len(check.Problems) > 0 {
+		c <- note.WithField("problems", strings.Join(check.Problems, ", ")).Warn("Subtitle flagged")
+	}
+	a.recordSubtitle(note, m, saved, onl, sub.Score(), check, c)
 
-	if err := a.execPluginsOnSubtitle(ctx, saved, c); err != nil {
+	if err := a.execPluginsOnSubtitle(note, saved, c); err != nil {
 		return nil, err
 	}
 	return saved, nil
 }
 
+// downloadLimited downloads the subtitle, respecting the maximum number of
+// concurrent downloads of the provider which found it
+func (a *Application) downloadLimited(ctx context.Context, s types.OnlineSubtitle) (io.ReadCloser, error) {
+	name := providerName(s)
+	release, err := a.limits.acquire(ctx, "download:"+name, provider.PolicyFor(name).Downloads)
+	if err != nil {
+		return nil, err
+	}
+	srt, err := s.Download()
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &releaseCloser{ReadCloser: srt, release: release}, nil
+}
+
+// releaseCloser releases a download slot once the subtitle has been read
+type releaseCloser struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (r *releaseCloser) Close() error {
+	defer r.once.Do(r.release)
+	return r.ReadCloser.Close()
+}
+
 func (a *Application) execPluginsOnSubtitle(ctx notify.Context, s types.LocalSubtitle, c chan<- *notify.Entry) error {
 	for _, plugin := range a.Config().Plugins() {
 		ctx = ctx.WithField("plugin", plugin.Name())
@@ -167,3 +375,11 @@ func (a *Application) execPluginsOnSubtitle(ctx notify.Context, s types.LocalSub
 	}
 	return nil
 }
+
+// providerName returns the name of the provider which found the subtitle
+func providerName(s types.OnlineSubtitle) string {
+	if p, ok := s.(types.ProvidedSubtitle); ok {
+		return p.Provider()
+	}
+	return ""
+}
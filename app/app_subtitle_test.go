@@ -94,6 +94,10 @@ func (p fakePlugin) Name() string {
 
 type fakeProviderError struct{}
 
+func (p fakeProviderError) Name() string {
+	return "fakeprovidererror"
+}
+
 func (p fakeProviderError) SearchSubtitles(m types.LocalMedia) ([]types.OnlineSubtitle, error) {
 	return nil, errors.New("test provider error")
 }
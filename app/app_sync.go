@@ -0,0 +1,139 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/tympanix/supper/media/charset"
+	"github.com/tympanix/supper/media/sidecar"
+	"github.com/tympanix/supper/media/subformat"
+)
+
+// syncBackupExt is appended to the path of the backup which holds the
+// original timings of a synchronized subtitle
+const syncBackupExt = ".orig"
+
+// SyncSubtitle changes the timing of every cue of the subtitle at the path.
+// Only the times are changed, replacing the file at once.
+// The original subtitle is kept as a backup the first time it is changed
+func (a *Application) SyncSubtitle(path string, t subformat.Timing) error {
+	return a.retimeSubtitle(path, func([]subformat.Cue) (subformat.Timing, error) {
+		return t, nil
+	})
+}
+
+// AlignSubtitle aligns the subtitle at the path with the correctly timed
+// subtitle at the reference path, such as a subtitle in another language for
+// the same release. The subtitle is changed like SyncSubtitle, unless the
+// confidence of the alignment is below the minimum
+func (a *Application) AlignSubtitle(path, reference string, min float64) (subformat.Alignment, error) {
+	_, text, _, err := readSubtitle(reference)
+	if err != nil {
+		return subformat.Alignment{}, err
+	}
+
+	ref, err := subformat.Parse(text)
+	if err != nil {
+		return subformat.Alignment{}, err
+	}
+
+	var alignment subformat.Alignment
+	err = a.retimeSubtitle(path, func(cues []subformat.Cue) (subformat.Timing, error) {
+		if alignment, err = subformat.Align(ref, cues); err != nil {
+			return nil, err
+		}
+		if alignment.Confidence < min {
+			return nil, fmt.Errorf("alignment confidence of %s is too low", percent(float32(alignment.Confidence)))
+		}
+		return alignment.Timing, nil
+	})
+	return alignment, err
+}
+
+// retimeSubtitle applies the timing returned by the function to every cue of
+// the subtitle at the path. Only the times of the subtitle are rewritten,
+// keeping its format, styles, formatting and character encoding
+func (a *Application) retimeSubtitle(path string, fn func([]subformat.Cue) (subformat.Timing, error)) error {
+	data, text, enc, err := readSubtitle(path)
+	if err != nil {
+		return err
+	}
+
+	cues, err := subformat.Parse(text)
+	if err != nil {
+		return err
+	}
+	if len(cues) == 0 {
+		return errors.New("no cues found in subtitle")
+	}
+
+	t, err := fn(cues)
+	if err != nil {
+		return err
+	}
+
+	retimed, err := subformat.Retime(text, t)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := charset.FromUTF8(retimed, enc)
+	if err != nil {
+		return err
+	}
+
+	if a.Config().Dry() {
+		return nil
+	}
+
+	return replaceSubtitle(path, data, encoded)
+}
+
+// replaceSubtitle replaces the original content of the subtitle at the path
+// with the data at once. The original subtitle is kept as a backup the first
+// time it is replaced, and the subtitle is marked as edited in its record
+func replaceSubtitle(path string, original, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	backup := path + syncBackupExt
+	if _, err := os.Stat(backup); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(backup, original, info.Mode()); err != nil {
+			return err
+		}
+	}
+
+	if err := writeAtomic(path, data, info.Mode()); err != nil {
+		return err
+	}
+	return sidecar.MarkEdited(path)
+}
+
+// writeAtomic replaces the file at the path with the data, such that the file
+// is never partially written
+func writeAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
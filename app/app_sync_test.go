@@ -0,0 +1,153 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/media/charset"
+	"github.com/tympanix/supper/media/subformat"
+)
+
+func TestSyncSubtitle(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	const sub = "out/Inception.2010.720p.x264.en.srt"
+
+	require.NoError(t, os.MkdirAll("out", os.ModePerm))
+	original := []byte("1\n00:00:01,000 --> 00:00:02,500\nHello\n\n")
+	require.NoError(t, ioutil.WriteFile(sub, original, 0644))
+
+	app := New(defaultConfig)
+
+	require.NoError(t, app.SyncSubtitle(sub, subformat.Shift(2*time.Second)))
+	assertContent(t, "1\n00:00:03,000 --> 00:00:04,500\nHello\n\n", sub)
+	assertContent(t, string(original), sub+syncBackupExt)
+
+	// The backup keeps the original timings
+	rate, err := subformat.Framerate(25, 24)
+	require.NoError(t, err)
+	require.NoError(t, app.SyncSubtitle(sub, rate))
+	assertContent(t, "1\n00:00:03,125 --> 00:00:04,688\nHello\n\n", sub)
+	assertContent(t, string(original), sub+syncBackupExt)
+
+	files, err := ioutil.ReadDir("out")
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestSyncSubtitleStyled(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	const sub = "out/Inception.2010.720p.x264.en.ass"
+
+	require.NoError(t, os.MkdirAll("out", os.ModePerm))
+	original := "[Script Info]\nScriptType: v4.00+\n\n[V4+ Styles]\n" +
+		"Style: Sign,Arial,28,&H0000FFFF,&H000000FF,&H00000000,&H00000000,1,0,0,0,100,100,0,0,1,2,2,8,10,10,10,1\n\n" +
+		"[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n" +
+		"Dialogue: 1,0:00:01.00,0:00:02.50,Sign,Bob,0,0,0,,{\\pos(192,20)}Hello\n"
+	require.NoError(t, ioutil.WriteFile(sub, []byte(original), 0644))
+
+	app := New(defaultConfig)
+
+	require.NoError(t, app.SyncSubtitle(sub, subformat.Shift(2*time.Second)))
+	assertContent(t, strings.Replace(strings.Replace(original,
+		"0:00:01.00", "0:00:03.00", 1), "0:00:02.50", "0:00:04.50", 1), sub)
+}
+
+func TestSyncSubtitleUTF16(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	const sub = "out/Inception.2010.720p.x264.en.srt"
+
+	utf16, err := charset.Lookup("utf-16")
+	require.NoError(t, err)
+
+	encode := func(text string) []byte {
+		data, err := charset.FromUTF8([]byte(text), utf16)
+		require.NoError(t, err)
+		return data
+	}
+
+	require.NoError(t, os.MkdirAll("out", os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(sub, encode("1\r\n00:00:01,000 --> 00:00:02,500\r\nHello\r\n"), 0644))
+
+	app := New(defaultConfig)
+
+	require.NoError(t, app.SyncSubtitle(sub, subformat.Shift(2*time.Second)))
+	assertContent(t, string(encode("1\r\n00:00:03,000 --> 00:00:04,500\r\nHello\r\n")), sub)
+}
+
+func TestSyncSubtitleInvalid(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	const sub = "out/Inception.2010.720p.x264.en.srt"
+
+	require.NoError(t, os.MkdirAll("out", os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(sub, []byte("not a subtitle"), 0644))
+
+	app := New(defaultConfig)
+	assert.Error(t, app.SyncSubtitle(sub, subformat.Shift(time.Second)))
+	assert.Error(t, app.SyncSubtitle("out/missing.en.srt", subformat.Shift(time.Second)))
+
+	assertContent(t, "not a subtitle", sub)
+}
+
+// writeCues writes the cues as an SRT subtitle with the text on every line
+func writeCues(t *testing.T, path string, cues []subformat.Cue, text string) {
+	for i := range cues {
+		lines := make([]string, len(cues[i].Lines))
+		for l := range lines {
+			lines[l] = text
+		}
+		cues[i].Lines = lines
+	}
+	var buf bytes.Buffer
+	require.NoError(t, subformat.Default.Encode(&buf, cues))
+	require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func TestAlignSubtitle(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	const ref = "out/Inception.2010.720p.x264.en.srt"
+	const sub = "out/Inception.2010.720p.x264.da.srt"
+
+	require.NoError(t, os.MkdirAll("out", os.ModePerm))
+
+	var cues []subformat.Cue
+	at := 10 * time.Second
+	for i := 0; i < 40; i++ {
+		end := at + time.Duration(1000+(i*733)%3000)*time.Millisecond
+		cues = append(cues, subformat.Cue{Start: at, End: end, Lines: make([]string, 1+i%3%2)})
+		at = end + time.Duration(200+(i*1571)%4000)*time.Millisecond
+	}
+
+	writeCues(t, ref, cues, "Hello")
+	writeCues(t, sub, subformat.Shift(-4*time.Second).Apply(cues), "Hej")
+
+	original, err := ioutil.ReadFile(sub)
+	require.NoError(t, err)
+
+	app := New(defaultConfig)
+
+	_, err = app.AlignSubtitle(sub, ref, 1.1)
+	assert.Error(t, err)
+	assertContent(t, string(original), sub)
+
+	a, err := app.AlignSubtitle(sub, ref, 0.5)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, a.Confidence)
+
+	aligned, err := ioutil.ReadFile(sub)
+	require.NoError(t, err)
+	expected, err := ioutil.ReadFile(ref)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Replace(string(expected), "Hello", "Hej", -1), string(aligned))
+	assertContent(t, string(original), sub+syncBackupExt)
+}
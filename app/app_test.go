@@ -35,3 +35,25 @@ func TestAppFromDefault(t *testing.T) {
 	app := NewFromDefault()
 	assert.Equal(t, app.Config(), cfg.Default)
 }
+
+// closingConfig is a configuration which counts the times it is closed
+type closingConfig struct {
+	fakeConfig
+	fakeTemplates
+	closed *int
+}
+
+func (c closingConfig) Close() error {
+	*c.closed++
+	return nil
+}
+
+func TestAppClose(t *testing.T) {
+	var closed int
+	app := New(closingConfig{defaultConfig.fakeConfig, defaultConfig.fakeTemplates, &closed})
+
+	require.NoError(t, app.Close())
+	assert.Equal(t, 1, closed)
+
+	assert.NoError(t, New(defaultConfig).Close())
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/set"
+	"github.com/tympanix/supper/media/list"
+	"github.com/tympanix/supper/media/sidecar"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+// backupExt is appended to the path of a subtitle which has been upgraded
+const backupExt = ".bak"
+
+// upgrade is an existing subtitle which may be replaced by a better subtitle
+type upgrade struct {
+	path  string
+	score float32
+}
+
+// upgradeScore returns the score below which subtitles are upgraded
+func (a *Application) upgradeScore() float32 {
+	return float32(a.Config().Upgrade()) / 100.0
+}
+
+// upgradable returns the existing subtitles in the languages which were
+// downloaded with a score below the upgrade threshold. Languages with any
+// other subtitle are not upgraded, since its score is unknown
+func (a *Application) upgradable(existing types.SubtitleList, lang set.Interface) map[language.Tag]upgrade {
+	upgrades := make(map[language.Tag]upgrade)
+	if a.Config().Upgrade() <= 0 {
+		return upgrades
+	}
+
+	keep := make(map[language.Tag]bool)
+	for _, s := range existing.List() {
+		l := s.Language()
+		if !lang.Has(l) {
+			continue
+		}
+		local, ok := s.(types.Local)
+		if !ok {
+			keep[l] = true
+			continue
+		}
+		rec, ok, err := sidecar.Get(local.Path())
+		if err != nil || !ok || rec.Score >= a.upgradeScore() {
+			keep[l] = true
+			continue
+		}
+		upgrades[l] = upgrade{local.Path(), rec.Score}
+	}
+
+	for l := range keep {
+		delete(upgrades, l)
+	}
+	return upgrades
+}
+
+// betterThan returns the rated subtitles scoring strictly higher than the
+// score
+func betterThan(l types.RatedSubtitleList, score float32) types.RatedSubtitleList {
+	better := make([]types.RatedSubtitle, 0)
+	for _, s := range l.List() {
+		if s.Score() > score {
+			better = append(better, s)
+		}
+	}
+	return list.RatedSubtitles(better)
+}
+
+// backupSubtitle moves the subtitle out of the way of the upgraded subtitle.
+// Backups of earlier upgrades are kept by numbering the backups, such that the
+// original subtitle is never lost. The path of the backup is returned
+func backupSubtitle(path string) (string, error) {
+	backup := path + backupExt
+	for i := 1; ; i++ {
+		_, err := os.Lstat(backup)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		backup = fmt.Sprintf("%s%s.%d", path, backupExt, i)
+	}
+	return backup, os.Rename(path, backup)
+}
+
+// restoreSubtitle moves the subtitle back from the backup when it could not
+// be upgraded
+func restoreSubtitle(path, backup string) error {
+	return os.Rename(backup, path)
+}
+
+func percent(score float32) string {
+	return fmt.Sprintf("%.0f%%", score*100.0)
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/app/wanted"
+	"github.com/tympanix/supper/media/sidecar"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+func TestSubtitleUpgrade(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	const path = "out/Inception.2010.720p.x264.mkv"
+	const sub = "out/Inception.2010.720p.x264.en.srt"
+
+	require.NoError(t, copyTestFiles("test", "out"))
+	require.NoError(t, sidecar.Set(sub, sidecar.Record{Score: 0.5}))
+
+	var rating float32
+	config := defaultConfig
+	config.upgrade = 90
+	config.languages = set.New(language.English)
+	config.evaluator = fakeEvaluator(func(types.Media, types.Media) float32 {
+		return rating
+	})
+
+	app := New(config)
+
+	var err error
+	app.wanted, err = wanted.Open("", nil, 0)
+	require.NoError(t, err)
+
+	download := func() []string {
+		l, err := app.FindMedia(path)
+		require.NoError(t, err)
+		c, messages := collectEntries()
+		_, err = app.DownloadSubtitles(l, config.Languages(), c)
+		require.NoError(t, err)
+		return messages()
+	}
+
+	// Only strictly better subtitles replace the existing subtitle
+	rating = 0.5
+	assert.Contains(t, download(), "No better subtitle available")
+	_, err = os.Stat(sub + backupExt)
+	assert.True(t, os.IsNotExist(err))
+	assert.Len(t, app.Wanted(), 1)
+
+	// A better subtitle is kept wanted while it is below the threshold
+	rating = 0.8
+	assert.Contains(t, download(), "Subtitle upgraded")
+	rec, ok, err := sidecar.Get(sub)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, float32(0.8), rec.Score)
+	assert.Equal(t, "fakeprovider", rec.Provider)
+	assert.Equal(t, "Inception (2010) 720p x264", rec.Release)
+	hash, err := sidecar.Hash(sub)
+	require.NoError(t, err)
+	assert.Equal(t, hash, rec.Hash)
+	assert.Len(t, app.Wanted(), 1)
+
+	data, err := ioutil.ReadFile(sub + backupExt)
+	require.NoError(t, err)
+	orig, err := ioutil.ReadFile("test/Inception.2010.720p.x264.en.srt")
+	require.NoError(t, err)
+	assert.Equal(t, orig, data)
+
+	rating = 0.95
+	assert.Contains(t, download(), "Subtitle upgraded")
+	assert.Empty(t, app.Wanted())
+
+	// The original subtitle is kept when upgrading again
+	data, err = ioutil.ReadFile(sub + backupExt)
+	require.NoError(t, err)
+	assert.Equal(t, orig, data)
+	_, err = os.Stat(sub + backupExt + ".1")
+	assert.NoError(t, err)
+
+	// Subtitles above the threshold are never replaced
+	rating = 1.0
+	assert.NotContains(t, download(), "Subtitle upgraded")
+}
+
+func TestSubtitleUpgradeFormat(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	const path = "out/Inception.2010.720p.x264.mkv"
+	const sub = "out/Inception.2010.720p.x264.de.srt"
+
+	require.NoError(t, copyTestFiles("test", "out"))
+	require.NoError(t, ioutil.WriteFile(sub, []byte(germanSubtitle), 0644))
+	require.NoError(t, sidecar.Set(sub, sidecar.Record{Score: 0.5}))
+
+	config := defaultConfig
+	config.upgrade = 90
+	config.format = "ass"
+	config.languages = set.New(language.German)
+	config.evaluator = fakeEvaluator(func(m types.Media, n types.Media) float32 {
+		if r, ok := n.(rankedMedia); ok {
+			return r.score
+		}
+		return 0
+	})
+	config.providers = []types.Provider{fakeLinkedProvider{
+		{"https://example.com/1", germanSubtitle, 0.8},
+	}}
+
+	app := New(config)
+	l, err := app.FindMedia(path)
+	require.NoError(t, err)
+
+	c, messages := collectEntries()
+	subs, err := app.DownloadSubtitles(l, config.Languages(), c)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Contains(t, messages(), "Subtitle upgraded")
+	assert.Equal(t, "out/Inception.2010.720p.x264.de.ass", subs[0].Path())
+
+	// The record of the replaced subtitle is removed
+	records, err := sidecar.List("out")
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, float32(0.8), records["Inception.2010.720p.x264.de.ass"].Score)
+}
+
+func TestSubtitleUpgradeUnknown(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	require.NoError(t, copyTestFiles("test", "out"))
+
+	config := defaultConfig
+	config.upgrade = 100
+
+	app := New(config)
+	l, err := app.FindMedia("out/Inception.2010.720p.x264.mkv")
+	require.NoError(t, err)
+
+	// Subtitles not downloaded by supper have an unknown score
+	c, messages := collectEntries()
+	subs, err := app.DownloadSubtitles(l, set.New(language.English), c)
+	require.NoError(t, err)
+	assert.Empty(t, subs)
+	assert.Empty(t, messages())
+}
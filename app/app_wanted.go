@@ -0,0 +1,148 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/fatih/set"
+	"github.com/tympanix/supper/app/notify"
+	"github.com/tympanix/supper/app/wanted"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/list"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+// wantedInterval is the duration between checking for wanted subtitles which
+// are due to be searched for again
+var wantedInterval = time.Minute
+
+// openWanted opens the list of wanted subtitles in the data directory. The
+// list is kept in memory only if it can not be stored
+func openWanted(config types.Config) *wanted.List {
+	path := dataFile(config, "wanted.json")
+
+	schedule := config.Wanted().Schedule()
+	maxAge := config.Wanted().MaxAge()
+
+	l, err := wanted.Open(path, schedule, maxAge)
+	if err != nil {
+		log.WithError(err).WithField("path", path).Warn("Could not read wanted subtitles")
+		l, _ = wanted.Open("", schedule, maxAge)
+	}
+	return l
+}
+
+// Wanted returns the subtitles which are missing, and when they are searched
+// for again
+func (a *Application) Wanted() []wanted.Item {
+	return a.wanted.Items()
+}
+
+// want records that the video at the path is missing a subtitle in the
+// language
+func (a *Application) want(path string, l language.Tag) {
+	if a.Config().Dry() {
+		return
+	}
+	if err := a.wanted.Want(path, l); err != nil {
+		log.WithError(err).WithField("path", path).Debug("Could not update wanted subtitles")
+	}
+}
+
+// found removes the subtitle in the language for the video at the path from
+// the wanted subtitles
+func (a *Application) found(path string, l language.Tag) {
+	if a.Config().Dry() {
+		return
+	}
+	if err := a.wanted.Found(path, l); err != nil {
+		log.WithError(err).WithField("path", path).Debug("Could not update wanted subtitles")
+	}
+}
+
+// ScheduleWantedContext calls the function whenever wanted subtitles are due
+// to be searched for again, until the context is cancelled. The function
+// should search for the subtitles using RetryWantedContext
+func (a *Application) ScheduleWantedContext(ctx context.Context, fn func()) error {
+	tick := time.NewTicker(wantedInterval)
+	defer tick.Stop()
+
+	for {
+		items := a.wanted.Items()
+		if len(items) > 0 && !items[0].Next.After(time.Now()) {
+			fn()
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// RetryWantedContext searches for the wanted subtitles which are due. Found
+// subtitles are removed from the wanted subtitles, while subtitles which are
+// still missing are scheduled to be searched for again later. Wanted
+// subtitles for videos which no longer exist, or which have received the
+// subtitle elsewhere, are removed. Subtitles which may still be upgraded are
+// searched for again
+func (a *Application) RetryWantedContext(ctx context.Context, c chan<- *notify.Entry) error {
+	due, err := a.wanted.Due(time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Group the videos by language, such that each language is downloaded
+	// for all videos at once
+	var langs []language.Tag
+	videos := make(map[language.Tag][]types.LocalMedia)
+
+	for _, item := range due {
+		lang := item.Language()
+		note := notify.WithField("file", filepath.Base(item.Path)).WithField("lang", item.Lang)
+
+		m, err := media.NewLocalFile(item.Path)
+		if err != nil {
+			c <- note.WithError(err).Debug("Wanted media removed")
+			a.found(item.Path, lang)
+			continue
+		}
+
+		video, ok := m.(types.Video)
+		if !ok {
+			a.found(item.Path, lang)
+			continue
+		}
+
+		existing, err := video.ExistingSubtitles()
+		if err != nil {
+			c <- note.WithError(err).Error("Could not read subtitles")
+			a.want(item.Path, lang)
+			continue
+		}
+		_, upgrade := a.upgradable(existing, set.New(lang))[lang]
+		if existing.LanguageSet().Has(lang) && !upgrade {
+			c <- note.Debug("Wanted subtitle exists")
+			a.found(item.Path, lang)
+			continue
+		}
+
+		if _, ok := videos[lang]; !ok {
+			langs = append(langs, lang)
+		}
+		videos[lang] = append(videos[lang], video)
+	}
+
+	for _, lang := range langs {
+		input := list.NewLocalMedia(videos[lang]...)
+		if _, err := a.DownloadSubtitlesContext(ctx, input, set.New(lang), c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/app/notify"
+	"github.com/tympanix/supper/app/wanted"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+func TestRetryWanted(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	config := defaultConfig
+	config.languages = set.New(language.German, language.French)
+
+	require.NoError(t, copyTestFiles("test", "out"))
+
+	app := New(config)
+
+	// Retry immediately instead of waiting for the schedule
+	var err error
+	app.wanted, err = wanted.Open("", nil, 0)
+	require.NoError(t, err)
+
+	l, err := app.FindMedia("out")
+	require.NoError(t, err)
+
+	c := notify.AsyncDiscard()
+	defer close(c)
+
+	subs, err := app.DownloadSubtitles(l, config.Languages(), c)
+	require.NoError(t, err)
+	assert.Len(t, subs, 2)
+
+	items := app.Wanted()
+	require.Len(t, items, 2)
+	for _, i := range items {
+		assert.Equal(t, language.French, i.Language())
+	}
+
+	// French subtitles are released later, while one video has been removed
+	app.providers = []types.Provider{fakeProvider{[]language.Tag{language.French}}}
+	require.NoError(t, os.Remove("out/Game.of.Thrones.s01e02.mp4"))
+
+	require.NoError(t, app.RetryWantedContext(context.Background(), c))
+	assert.Empty(t, app.Wanted())
+
+	m, err := media.NewLocalFile("out/Inception.2010.720p.x264.mkv")
+	require.NoError(t, err)
+	existing, err := m.(types.Video).ExistingSubtitles()
+	require.NoError(t, err)
+	assert.True(t, existing.LanguageSet().Has(language.French))
+}
+
+func TestWantedDataDir(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	config := defaultConfig
+	config.cachedir = "out/cache"
+	config.datadir = "out/data"
+
+	// Wanted subtitles are moved from the cache directory of older versions
+	require.NoError(t, os.MkdirAll(config.cachedir, os.ModePerm))
+	old, err := wanted.Open("out/cache/wanted.json", nil, 0)
+	require.NoError(t, err)
+	require.NoError(t, old.Want("out/Inception.2010.720p.x264.mkv", language.French))
+
+	app := New(config)
+	assert.Len(t, app.Wanted(), 1)
+
+	_, err = os.Stat("out/data/wanted.json")
+	assert.NoError(t, err)
+	_, err = os.Stat("out/cache/wanted.json")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestScheduleWanted(t *testing.T) {
+	app := New(defaultConfig)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var err error
+	app.wanted, err = wanted.Open("", nil, 0)
+	require.NoError(t, err)
+
+	// Nothing is due when no subtitles are wanted
+	calls := 0
+	cancel()
+	assert.Equal(t, context.Canceled, app.ScheduleWantedContext(ctx, func() { calls++ }))
+	assert.Equal(t, 0, calls)
+
+	require.NoError(t, app.wanted.Want("out/Inception.2010.720p.x264.mkv", language.French))
+	assert.Equal(t, context.Canceled, app.ScheduleWantedContext(ctx, func() { calls++ }))
+	assert.Equal(t, 1, calls)
+}
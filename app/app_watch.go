@@ -0,0 +1,258 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/tympanix/supper/app/notify"
+	"github.com/tympanix/supper/app/watch"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/extract"
+	"github.com/tympanix/supper/media/list"
+	"github.com/tympanix/supper/types"
+)
+
+// WatchContext watches the directories for new media files and archives. The
+// function is called with the path of each new file once it has not changed
+// for the stable duration of the watch configuration, and should return
+// quickly. Watching stops when the context is cancelled
+func (a *Application) WatchContext(ctx context.Context, dirs []string, fn func(string)) error {
+	return watch.Watch(ctx, dirs, a.Config().Watch().Stable(), func(path string) {
+		if a.watchable(path) {
+			fn(path)
+		}
+	})
+}
+
+// watchable returns true for files which can be processed by the watch
+// pipeline. Files in the library are ignored when renaming, since those are
+// the result of processing new media
+func (a *Application) watchable(path string) bool {
+	if a.Config().Watch().Rename() {
+		for _, c := range []types.MediaConfig{a.Config().Movies(), a.Config().TVShows()} {
+			if c.Directory() != "" && inDirectory(path, c.Directory()) {
+				return false
+			}
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".zip" || ext == ".rar" {
+		return true
+	}
+	for _, t := range filetypes {
+		if ext == t {
+			return true
+		}
+	}
+	return false
+}
+
+func inDirectory(path string, dir string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
+
+// ProcessMediaContext runs the file through the watch pipeline. Media is
+// extracted from archives and renamed into the library, after which
+// subtitles are downloaded for the videos, as enabled in the watch
+// configuration. Failures for the file are sent as notifications, while the
+// error of the context is returned if processing has been cancelled
+func (a *Application) ProcessMediaContext(ctx context.Context, path string, c chan<- *notify.Entry) error {
+	config := a.Config().Watch()
+	note := notify.WithField("file", filepath.Base(path))
+
+	var paths []string
+
+	archive, err := extract.OpenMediaArchive(path)
+
+	if err == nil {
+		defer archive.Close()
+		if !config.Extract() {
+			c <- note.WithField("reason", "extraction disabled").Debug("Archive skipped")
+			return nil
+		}
+		if paths, err = a.extractWatched(ctx, note, archive, c); err != nil {
+			return err
+		}
+	} else if extract.IsNotArchive(err) {
+		m, err := media.NewLocalFile(path)
+		if err != nil {
+			c <- note.WithError(err).Debug("Not media")
+			return nil
+		}
+		if media.IsSample(m) {
+			c <- note.WithField("reason", "sample").Debug("Media skipped")
+			return nil
+		}
+		if filter := a.Config().MediaFilter(); filter != nil && !filter(m) {
+			return nil
+		}
+		if config.Rename() {
+			dest, err := a.renameWatched(ctx, note, m, c)
+			if err != nil {
+				return err
+			}
+			if dest != "" {
+				path = dest
+			}
+		}
+		paths = append(paths, path)
+	} else {
+		c <- note.WithError(err).Error("Could not open archive")
+		return nil
+	}
+
+	if !config.Subtitles() || len(paths) == 0 {
+		return nil
+	}
+
+	if a.Config().Languages() == nil || a.Config().Languages().Size() == 0 {
+		c <- note.WithField("reason", "no languages").Warn("Subtitles skipped")
+		return nil
+	}
+
+	videos := make([]types.LocalMedia, 0, len(paths))
+	for _, p := range paths {
+		if m, err := media.NewLocalFile(p); err == nil {
+			if _, ok := m.(types.Video); ok {
+				videos = append(videos, m)
+			}
+		}
+	}
+
+	if len(videos) == 0 {
+		return nil
+	}
+
+	_, err = a.DownloadSubtitlesContext(ctx, list.NewLocalMedia(videos...), a.Config().Languages(), c)
+	return err
+}
+
+// renameWatched renames the media into the library and returns its new path.
+// An empty path is returned if the media has not been renamed
+func (a *Application) renameWatched(ctx context.Context, note notify.Context, m types.LocalMedia, c chan<- *notify.Entry) (string, error) {
+	note = note.WithField("media", m).WithField("action", a.Config().RenameAction())
+
+	renamer, ok := Renamers[a.Config().RenameAction()]
+	if !ok {
+		return "", fmt.Errorf("%s: unknown action", a.Config().RenameAction())
+	}
+
+	dest, err := a.scrapeAndRenameMedia(ctx, m, m)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		c <- note.WithError(err).Error("Could not scrape media")
+		return "", nil
+	}
+
+	if dest == "" {
+		c <- note.WithField("reason", "unknown media").Warn("Rename skipped")
+		return "", nil
+	}
+
+	if a.Config().Dry() {
+		c <- note.WithField("reason", "dry-run").Info("Skip rename")
+		return "", nil
+	}
+
+	if err := renamer.Rename(m, dest, a.Config().Force()); err != nil {
+		if media.IsExistsErr(err) {
+			c <- note.WithField("reason", "media already exists").Warn("Rename skipped")
+		} else {
+			c <- note.WithError(err).Error("Rename failed")
+		}
+		return "", nil
+	}
+
+	a.indexRenamed(m, dest)
+	c <- note.WithField("dest", dest).Info("Media renamed")
+	return dest, nil
+}
+
+// extractWatched extracts the media in the archive into the library and
+// returns the paths of the extracted media
+func (a *Application) extractWatched(ctx context.Context, note notify.Context, archive types.MediaArchive, c chan<- *notify.Entry) ([]string, error) {
+	note = note.WithField("action", "extract")
+
+	var paths []string
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return paths, err
+		}
+
+		m, err := archive.Next()
+		if err == io.EOF {
+			return paths, nil
+		}
+		if err != nil {
+			c <- note.WithError(err).Error("Extraction failed")
+			return paths, nil
+		}
+
+		dest, err := a.extractWatchedMedia(ctx, note.WithField("media", m), m, c)
+		m.Close()
+
+		if err != nil {
+			return paths, err
+		}
+		if dest != "" {
+			paths = append(paths, dest)
+		}
+	}
+}
+
+func (a *Application) extractWatchedMedia(ctx context.Context, note notify.Context, m types.MediaReadCloser, c chan<- *notify.Entry) (string, error) {
+	if filter := a.Config().MediaFilter(); filter != nil && !filter(m) {
+		return "", nil
+	}
+
+	dest, err := a.scrapeAndRenameMedia(ctx, m, m)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		c <- note.WithError(err).Error("Could not scrape media")
+		return "", nil
+	}
+
+	if dest == "" {
+		return "", nil
+	}
+
+	if a.Config().Dry() {
+		c <- note.WithField("reason", "dry-run").Info("Skip extraction")
+		return "", nil
+	}
+
+	if err := ensurePath(dest, a.Config().Force()); err != nil {
+		if media.IsExistsErr(err) {
+			c <- note.WithField("reason", "media already exists").Warn("Extraction skipped")
+		} else {
+			c <- note.WithError(err).Error("Extraction failed")
+		}
+		return "", nil
+	}
+
+	if err := copyMedia(m, dest); err != nil {
+		c <- note.WithError(err).Error("Extraction failed")
+		return "", nil
+	}
+
+	c <- note.WithField("dest", dest).Info("Media extracted")
+	return dest, nil
+}
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fatih/set"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/app/notify"
+	"golang.org/x/text/language"
+)
+
+var defaultWatch = fakeWatch{
+	stable:    100 * time.Millisecond,
+	rename:    true,
+	extract:   true,
+	subtitles: true,
+}
+
+// collectEntries returns a notification channel and a function which closes
+// the channel and returns the messages sent
+func collectEntries() (chan<- *notify.Entry, func() []string) {
+	c := make(chan *notify.Entry)
+	done := make(chan []string)
+	go func() {
+		var messages []string
+		for e := range c {
+			messages = append(messages, e.Message)
+		}
+		done <- messages
+	}()
+	return c, func() []string {
+		close(c)
+		return <-done
+	}
+}
+
+func TestProcessMedia(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	config := defaultConfig
+	config.output = "out/library"
+	config.watch = defaultWatch
+	config.languages = set.New(language.German)
+
+	require.NoError(t, copyTestFiles("test", "out/downloads"))
+
+	app := New(config)
+	c, messages := collectEntries()
+
+	err := app.ProcessMediaContext(context.Background(), "out/downloads/Inception.2010.720p.x264.mkv", c)
+	require.NoError(t, err)
+
+	assert.Contains(t, messages(), "Media renamed")
+	for _, f := range []string{"Inception (2010) 720p.mkv", "Inception (2010) 720p.de.srt"} {
+		_, err := os.Stat(filepath.Join("out", "library", f))
+		assert.NoError(t, err)
+	}
+}
+
+func TestProcessArchive(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	config := defaultConfig
+	config.watch = defaultWatch
+	config.watch.subtitles = false
+
+	app := New(config)
+	c, messages := collectEntries()
+
+	err := app.ProcessMediaContext(context.Background(), "../test/archives/Inception.2010.rar", c)
+	require.NoError(t, err)
+	assert.Contains(t, messages(), "Media extracted")
+
+	files, err := ioutil.ReadDir("out")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(files))
+
+	config.watch.extract = false
+	app = New(config)
+	c, messages = collectEntries()
+
+	err = app.ProcessMediaContext(context.Background(), "../test/archives/Game.of.Thrones.zip", c)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Archive skipped"}, messages())
+}
+
+func TestProcessCancelled(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	config := defaultConfig
+	config.output = "out/library"
+	config.watch = defaultWatch
+	config.languages = set.New(language.German)
+
+	require.NoError(t, copyTestFiles("test", "out/downloads"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	app := New(config)
+	c, messages := collectEntries()
+
+	err := app.ProcessMediaContext(ctx, "out/downloads/Inception.2010.720p.x264.mkv", c)
+	assert.Equal(t, context.Canceled, err)
+	messages()
+
+	_, err = os.Stat("out/library")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWatchMedia(t *testing.T) {
+	defer cleanRenameTest(t)
+
+	config := defaultConfig
+	config.output = "out/library"
+	config.watch = defaultWatch
+
+	require.NoError(t, os.MkdirAll("out/downloads", os.ModePerm))
+	require.NoError(t, os.MkdirAll("out/library", os.ModePerm))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	found := make(chan string, 10)
+	go New(config).WatchContext(ctx, []string{"out"}, func(path string) {
+		found <- path
+	})
+	time.Sleep(50 * time.Millisecond)
+
+	// Subtitles and files in the library are not processed
+	require.NoError(t, copyTestFiles("test", "out/library"))
+	require.NoError(t, copyTestFiles("test", "out/downloads"))
+
+	var paths []string
+	timeout := time.After(5 * time.Second)
+	for len(paths) < 2 {
+		select {
+		case path := <-found:
+			paths = append(paths, path)
+		case <-timeout:
+			t.Fatalf("expected new media, found %v", paths)
+		}
+	}
+
+	sort.Strings(paths)
+	assert.Equal(t, []string{
+		filepath.Join("out", "downloads", "Game.of.Thrones.s01e02.mp4"),
+		filepath.Join("out", "downloads", "Inception.2010.720p.x264.mkv"),
+	}, paths)
+
+	select {
+	case path := <-found:
+		t.Fatalf("unexpected media %v", path)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
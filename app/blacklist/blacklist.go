@@ -0,0 +1,152 @@
+package blacklist
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry is a subtitle which has been rejected for a media. The subtitle is
+// identified by the link it was downloaded from and the hash of its content
+type Entry struct {
+	Media    string    `json:"media"`
+	Link     string    `json:"link,omitempty"`
+	Hash     string    `json:"hash,omitempty"`
+	Rejected time.Time `json:"rejected"`
+}
+
+type key struct {
+	media string
+	value string
+}
+
+// List keeps track of subtitles which have been rejected, such that they are
+// never downloaded again for the same media. The list is persisted to a file,
+// unless no file is given. A list is safe for concurrent use
+type List struct {
+	mu      sync.Mutex
+	path    string
+	entries []Entry
+	links   map[key]bool
+	hashes  map[key]bool
+}
+
+// Open reads the list from the file at the path. An empty list is returned if
+// the file does not exist
+func Open(path string) (*List, error) {
+	l := &List{
+		path:   path,
+		links:  make(map[key]bool),
+		hashes: make(map[key]bool),
+	}
+
+	if path == "" {
+		return l, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		l.add(e)
+	}
+
+	return l, nil
+}
+
+// Add rejects the subtitle with the link or content hash for the media
+// identity. Empty links and hashes are ignored
+func (l *List) Add(media, link, hash string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.add(Entry{
+		Media:    media,
+		Link:     link,
+		Hash:     hash,
+		Rejected: time.Now(),
+	})
+
+	return l.save()
+}
+
+// Link returns true if the subtitle at the link has been rejected for the
+// media identity
+func (l *List) Link(media, link string) bool {
+	if link == "" {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.links[key{media, link}]
+}
+
+// Hash returns true if a subtitle with the content hash has been rejected for
+// the media identity
+func (l *List) Hash(media, hash string) bool {
+	if hash == "" {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.hashes[key{media, hash}]
+}
+
+// Entries returns every rejected subtitle in the order they were rejected
+func (l *List) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := make([]Entry, len(l.entries))
+	copy(entries, l.entries)
+	return entries
+}
+
+// add adds the entry to the list. The list must be locked
+func (l *List) add(e Entry) {
+	if e.Link != "" {
+		l.links[key{e.Media, e.Link}] = true
+	}
+	if e.Hash != "" {
+		l.hashes[key{e.Media, e.Hash}] = true
+	}
+	l.entries = append(l.entries, e)
+}
+
+// save writes the list to disk. The list must be locked
+func (l *List) save() error {
+	if l.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(l.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp := l.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
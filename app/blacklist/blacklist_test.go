@@ -0,0 +1,52 @@
+package blacklist
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlacklist(t *testing.T) {
+	l, err := Open("")
+	require.NoError(t, err)
+
+	require.NoError(t, l.Add("inception:2010", "https://example.com/1", "abc"))
+	require.NoError(t, l.Add("inception:2010", "", "def"))
+
+	assert.True(t, l.Link("inception:2010", "https://example.com/1"))
+	assert.False(t, l.Link("inception:2010", "https://example.com/2"))
+	assert.False(t, l.Link("interstellar:2014", "https://example.com/1"))
+	assert.False(t, l.Link("inception:2010", ""))
+
+	assert.True(t, l.Hash("inception:2010", "abc"))
+	assert.True(t, l.Hash("inception:2010", "def"))
+	assert.False(t, l.Hash("interstellar:2014", "abc"))
+	assert.False(t, l.Hash("inception:2010", ""))
+
+	entries := l.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "abc", entries[0].Hash)
+	assert.Equal(t, "def", entries[1].Hash)
+}
+
+func TestBlacklistPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cache", "blacklist.json")
+
+	l, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, l.Add("inception:2010", "https://example.com/1", "abc"))
+
+	l, err = Open(path)
+	require.NoError(t, err)
+	assert.True(t, l.Link("inception:2010", "https://example.com/1"))
+	assert.True(t, l.Hash("inception:2010", "abc"))
+	assert.Len(t, l.Entries(), 1)
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/provider"
+	"github.com/tympanix/supper/types"
+)
+
+// ScrapeTTL is the duration scraped media is cached
+const ScrapeTTL = 30 * 24 * time.Hour
+
+// NotFoundTTL is the duration media which could not be found is cached
+const NotFoundTTL = 24 * time.Hour
+
+// scrapeBucket is the prefix for buckets of scraped media
+const scrapeBucket = "scrape-"
+
+// scraped is the cached representation of scraped media
+type scraped struct {
+	NotFound    bool   `json:"not_found,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Year        int    `json:"year,omitempty"`
+	Season      int    `json:"season,omitempty"`
+	Episode     int    `json:"episode,omitempty"`
+	EpisodeName string `json:"episode_name,omitempty"`
+}
+
+func newScraped(m types.Media) (*scraped, bool) {
+	if movie, ok := m.TypeMovie(); ok {
+		return &scraped{
+			Type: "movie",
+			Name: movie.MovieName(),
+			Year: movie.Year(),
+		}, true
+	} else if episode, ok := m.TypeEpisode(); ok {
+		return &scraped{
+			Type:        "episode",
+			Name:        episode.TVShow(),
+			Season:      episode.Season(),
+			Episode:     episode.Episode(),
+			EpisodeName: episode.EpisodeName(),
+		}, true
+	}
+	return nil, false
+}
+
+func (s *scraped) media() (types.Media, error) {
+	switch s.Type {
+	case "movie":
+		return &media.Movie{
+			NameX: s.Name,
+			YearX: s.Year,
+		}, nil
+	case "episode":
+		return &media.Episode{
+			NameX:        s.Name,
+			EpisodeNameX: s.EpisodeName,
+			SeasonX:      s.Season,
+			EpisodeX:     s.Episode,
+		}, nil
+	}
+	return nil, errors.New("cache: unknown media type")
+}
+
+// Scraper wraps the scraper such that scraped media is cached in the store.
+// Media which could not be found is cached as well, but for a shorter duration
+func Scraper(store *Store, s types.Scraper) types.Scraper {
+	return &scraper{
+		Scraper: s,
+		store:   store,
+		bucket:  scrapeBucket + s.Name(),
+	}
+}
+
+type scraper struct {
+	types.Scraper
+	store  *Store
+	bucket string
+}
+
+func (s *scraper) Scrape(m types.Media) (types.Media, error) {
+	return s.ScrapeContext(context.Background(), m)
+}
+
+func (s *scraper) ScrapeContext(ctx context.Context, m types.Media) (types.Media, error) {
+	if m == nil {
+		return s.scrape(ctx, m)
+	}
+
+	key := m.Identity()
+	if sub, ok := m.TypeSubtitle(); ok {
+		key = sub.ForMedia().Identity()
+	}
+
+	entry := log.WithField("scraper", s.Name()).WithField("media", key)
+
+	var c scraped
+	if ok, err := s.store.Get(s.bucket, key, &c); err != nil {
+		entry.WithError(err).Warn("Could not read scraper cache")
+	} else if ok {
+		if c.NotFound {
+			return nil, provider.NewErrNotFound(s.Name())
+		}
+		if cached, err := c.media(); err == nil {
+			return cached, nil
+		}
+	}
+
+	result, err := s.scrape(ctx, m)
+
+	if provider.IsErrNotFound(err) {
+		if err := s.store.Put(s.bucket, key, scraped{NotFound: true}, NotFoundTTL); err != nil {
+			entry.WithError(err).Warn("Could not write scraper cache")
+		}
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	if c, ok := newScraped(result); ok {
+		if err := s.store.Put(s.bucket, key, c, ScrapeTTL); err != nil {
+			entry.WithError(err).Warn("Could not write scraper cache")
+		}
+	}
+
+	return result, nil
+}
+
+// scrape scrapes the media using the underlying scraper, with cancellation if
+// the scraper supports it
+func (s *scraper) scrape(ctx context.Context, m types.Media) (types.Media, error) {
+	if c, ok := s.Scraper.(types.ContextScraper); ok {
+		return c.ScrapeContext(ctx, m)
+	}
+	return s.Scraper.Scrape(m)
+}
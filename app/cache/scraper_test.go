@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/provider"
+	"github.com/tympanix/supper/types"
+)
+
+type countingScraper struct {
+	calls  int32
+	result types.Media
+	err    error
+}
+
+func (s *countingScraper) Name() string { return "counting" }
+
+func (s *countingScraper) Scrape(m types.Media) (types.Media, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return s.result, s.err
+}
+
+func TestScraperCachesMovie(t *testing.T) {
+	store := newTestStore(t)
+	defer os.RemoveAll(store.Dir())
+
+	local, err := media.NewMovie("inception.2010.720p.bluray.x264")
+	require.NoError(t, err)
+
+	s := &countingScraper{result: &media.Movie{NameX: "Inception", YearX: 2010}}
+	c := Scraper(store, s)
+
+	assert.Equal(t, "counting", c.Name())
+
+	for i := 0; i < 3; i++ {
+		scraped, err := c.Scrape(local)
+		require.NoError(t, err)
+
+		movie, ok := scraped.TypeMovie()
+		require.True(t, ok)
+		assert.Equal(t, "Inception", movie.MovieName())
+		assert.Equal(t, 2010, movie.Year())
+	}
+
+	assert.Equal(t, int32(1), s.calls)
+
+	stats, err := store.Stats()
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, "scrape-counting", stats[0].Bucket)
+	assert.Equal(t, 1, stats[0].Entries)
+}
+
+func TestScraperCachesEpisode(t *testing.T) {
+	store := newTestStore(t)
+	defer os.RemoveAll(store.Dir())
+
+	local, err := media.NewEpisode("game.of.thrones.s01e02.720p.hdtv")
+	require.NoError(t, err)
+
+	s := &countingScraper{result: &media.Episode{
+		NameX:        "Game of Thrones",
+		EpisodeNameX: "The Kingsroad",
+		SeasonX:      1,
+		EpisodeX:     2,
+	}}
+	c := Scraper(store, s)
+
+	for i := 0; i < 2; i++ {
+		scraped, err := c.Scrape(local)
+		require.NoError(t, err)
+
+		episode, ok := scraped.TypeEpisode()
+		require.True(t, ok)
+		assert.Equal(t, "Game of Thrones", episode.TVShow())
+		assert.Equal(t, "The Kingsroad", episode.EpisodeName())
+		assert.Equal(t, 1, episode.Season())
+		assert.Equal(t, 2, episode.Episode())
+	}
+
+	assert.Equal(t, int32(1), s.calls)
+}
+
+func TestScraperNegativeCache(t *testing.T) {
+	store := newTestStore(t)
+	defer os.RemoveAll(store.Dir())
+
+	local, err := media.NewMovie("unknown.movie.2010.720p.bluray.x264")
+	require.NoError(t, err)
+
+	s := &countingScraper{err: provider.NewErrNotFound("counting")}
+	c := Scraper(store, s)
+
+	for i := 0; i < 3; i++ {
+		_, err := c.Scrape(local)
+		assert.True(t, provider.IsErrNotFound(err))
+	}
+
+	assert.Equal(t, int32(1), s.calls)
+}
+
+func TestScraperErrorsNotCached(t *testing.T) {
+	store := newTestStore(t)
+	defer os.RemoveAll(store.Dir())
+
+	local, err := media.NewMovie("inception.2010.720p.bluray.x264")
+	require.NoError(t, err)
+
+	s := &countingScraper{err: errors.New("mocked error")}
+	c := Scraper(store, s)
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Scrape(local)
+		assert.EqualError(t, err, "mocked error")
+	}
+
+	s.err = provider.NewErrMediaNotSupported("counting")
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Scrape(local)
+		assert.True(t, provider.IsErrMediaNotSupported(err))
+	}
+
+	assert.Equal(t, int32(4), s.calls)
+}
+
+func TestScraperConcurrent(t *testing.T) {
+	store := newTestStore(t)
+	defer os.RemoveAll(store.Dir())
+
+	local, err := media.NewMovie("inception.2010.720p.bluray.x264")
+	require.NoError(t, err)
+
+	s := &countingScraper{result: &media.Movie{NameX: "Inception", YearX: 2010}}
+	c := Scraper(store, s)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scraped, err := c.Scrape(local)
+			assert.NoError(t, err)
+			if movie, ok := scraped.TypeMovie(); assert.True(t, ok) {
+				assert.Equal(t, "Inception", movie.MovieName())
+			}
+		}()
+	}
+
+	wg.Wait()
+}
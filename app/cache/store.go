@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// entryExt is the file extension of cache entries on disk
+const entryExt = ".json"
+
+// Store is a persistent key-value cache on disk. Entries are grouped in
+// buckets and expire after their time to live. A store is safe for concurrent
+// use, and entries are written atomically such that several processes may
+// share the same directory
+type Store struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// New returns a store which keeps its entries in the directory. The directory
+// is created when the first entry is stored
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory of the store
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+type entry struct {
+	Key     string          `json:"key"`
+	Expires time.Time       `json:"expires"`
+	Value   json.RawMessage `json:"value"`
+}
+
+func (e *entry) expired() bool {
+	return time.Now().After(e.Expires)
+}
+
+func (s *Store) path(bucket string, key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(s.dir, bucket, hex.EncodeToString(sum[:])+entryExt)
+}
+
+// Get retrieves the value for the key in the bucket and decodes it into v.
+// False is returned if the entry does not exist or has expired
+func (s *Store) Get(bucket string, key string, v interface{}) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, err := ioutil.ReadFile(s.path(bucket, key))
+
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key || e.expired() {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(e.Value, v); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Put stores the value for the key in the bucket for the duration of ttl
+func (s *Store) Put(bucket string, key string, v interface{}, ttl time.Duration) error {
+	value, err := json.Marshal(v)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry{
+		Key:     key,
+		Expires: time.Now().Add(ttl),
+		Value:   value,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(bucket, key)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp")
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete removes the entry for the key in the bucket
+func (s *Store) Delete(bucket string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(bucket, key))
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Clear removes every bucket in the store. Other files in the directory of
+// the store are kept
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buckets, err := ioutil.ReadDir(s.dir)
+
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, b := range buckets {
+		if !b.IsDir() {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(s.dir, b.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats is the statistics for a bucket in the store
+type Stats struct {
+	Bucket  string
+	Entries int
+	Expired int
+	Size    int64
+}
+
+// Stats returns the statistics for each bucket in the store
+func (s *Store) Stats() ([]Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	buckets, err := ioutil.ReadDir(s.dir)
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var stats []Stats
+
+	for _, b := range buckets {
+		if !b.IsDir() {
+			continue
+		}
+
+		files, err := ioutil.ReadDir(filepath.Join(s.dir, b.Name()))
+
+		if err != nil {
+			return nil, err
+		}
+
+		st := Stats{Bucket: b.Name()}
+
+		for _, f := range files {
+			if filepath.Ext(f.Name()) != entryExt {
+				continue
+			}
+
+			st.Entries++
+			st.Size += f.Size()
+
+			data, err := ioutil.ReadFile(filepath.Join(s.dir, b.Name(), f.Name()))
+
+			if err != nil {
+				return nil, err
+			}
+
+			var e entry
+			if err := json.Unmarshal(data, &e); err != nil || e.expired() {
+				st.Expired++
+			}
+		}
+
+		stats = append(stats, st)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) *Store {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	return New(dir)
+}
+
+func TestStorePutGet(t *testing.T) {
+	s := newTestStore(t)
+	defer os.RemoveAll(s.Dir())
+
+	var v string
+	ok, err := s.Get("bucket", "key", &v)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, s.Put("bucket", "key", "value", time.Hour))
+
+	ok, err = s.Get("bucket", "key", &v)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value", v)
+
+	ok, err = s.Get("other", "key", &v)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, s.Delete("bucket", "key"))
+
+	ok, err = s.Get("bucket", "key", &v)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestStoreExpired(t *testing.T) {
+	s := newTestStore(t)
+	defer os.RemoveAll(s.Dir())
+
+	require.NoError(t, s.Put("bucket", "key", 42, -time.Second))
+
+	var v int
+	ok, err := s.Get("bucket", "key", &v)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestStoreStatsClear(t *testing.T) {
+	s := newTestStore(t)
+	defer os.RemoveAll(s.Dir())
+
+	stats, err := s.Stats()
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	require.NoError(t, s.Put("a", "one", 1, time.Hour))
+	require.NoError(t, s.Put("a", "two", 2, -time.Hour))
+	require.NoError(t, s.Put("b", "three", 3, time.Hour))
+
+	stats, err = s.Stats()
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+
+	assert.Equal(t, "a", stats[0].Bucket)
+	assert.Equal(t, 2, stats[0].Entries)
+	assert.Equal(t, 1, stats[0].Expired)
+	assert.True(t, stats[0].Size > 0)
+
+	assert.Equal(t, "b", stats[1].Bucket)
+	assert.Equal(t, 1, stats[1].Entries)
+	assert.Equal(t, 0, stats[1].Expired)
+
+	// Files which are not in a bucket are kept
+	other := filepath.Join(s.Dir(), "wanted.json")
+	require.NoError(t, ioutil.WriteFile(other, []byte("[]"), 0644))
+
+	require.NoError(t, s.Clear())
+
+	stats, err = s.Stats()
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	_, err = os.Stat(other)
+	assert.NoError(t, err)
+}
+
+func TestStoreConcurrent(t *testing.T) {
+	s := newTestStore(t)
+	defer os.RemoveAll(s.Dir())
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key%v", i%4)
+			assert.NoError(t, s.Put("bucket", key, i, time.Hour))
+
+			var v int
+			ok, err := s.Get("bucket", key, &v)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, i%4, v%4)
+		}(i)
+	}
+
+	wg.Wait()
+
+	stats, err := s.Stats()
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 4, stats[0].Entries)
+}
@@ -3,6 +3,8 @@ package cfg
 import (
 	"fmt"
 	"html/template"
+	"io"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -11,8 +13,11 @@ import (
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/tympanix/supper/app/plugin"
+	"github.com/tympanix/supper/media/charset"
+	"github.com/tympanix/supper/media/cleanup"
 	"github.com/tympanix/supper/media/parse"
 	"github.com/tympanix/supper/media/provider"
+	"github.com/tympanix/supper/media/subformat"
 	"github.com/tympanix/supper/types"
 
 	"github.com/apex/log"
@@ -38,6 +43,103 @@ func (m mediaConfig) Interface() *Media {
 	}
 }
 
+type jobsConfig struct {
+	ConcurrencyX int `mapstructure:"concurrency"`
+	QueueX       int `mapstructure:"queue"`
+	HistoryX     int `mapstructure:"history"`
+}
+
+// Concurrency returns the number of jobs running at the same time
+func (j jobsConfig) Concurrency() int {
+	return j.ConcurrencyX
+}
+
+// Queue returns the number of jobs which may wait to run
+func (j jobsConfig) Queue() int {
+	return j.QueueX
+}
+
+// History returns the number of finished jobs to remember
+func (j jobsConfig) History() int {
+	return j.HistoryX
+}
+
+type watchConfig struct {
+	DirectoriesX []string
+	StableX      time.Duration
+	RenameX      bool
+	ExtractX     bool
+	SubtitlesX   bool
+}
+
+// Directories returns the directories to watch for new media
+func (w watchConfig) Directories() []string {
+	return w.DirectoriesX
+}
+
+// Stable returns the duration a file must be unchanged before it is processed
+func (w watchConfig) Stable() time.Duration {
+	return w.StableX
+}
+
+// Rename returns true if new media should be renamed
+func (w watchConfig) Rename() bool {
+	return w.RenameX
+}
+
+// Extract returns true if media should be extracted from new archives
+func (w watchConfig) Extract() bool {
+	return w.ExtractX
+}
+
+// Subtitles returns true if subtitles should be downloaded for new media
+func (w watchConfig) Subtitles() bool {
+	return w.SubtitlesX
+}
+
+type wantedConfig struct {
+	ScheduleX []time.Duration
+	MaxAgeX   time.Duration
+}
+
+// Schedule returns the delays between searching for missing subtitles
+func (w wantedConfig) Schedule() []time.Duration {
+	return w.ScheduleX
+}
+
+// MaxAge returns the duration to search for missing subtitles
+func (w wantedConfig) MaxAge() time.Duration {
+	return w.MaxAgeX
+}
+
+type cleanupConfig struct {
+	AdsX             bool
+	HearingImpairedX bool
+	PatternsX        []string
+	PhrasesX         []string
+}
+
+// Ads returns true if ads and credits should be removed from subtitles
+func (c cleanupConfig) Ads() bool {
+	return c.AdsX
+}
+
+// HearingImpaired returns true if hearing impaired annotations should be
+// stripped from subtitles
+func (c cleanupConfig) HearingImpaired() bool {
+	return c.HearingImpairedX
+}
+
+// Patterns returns regular expressions of cues to remove from subtitles
+func (c cleanupConfig) Patterns() []string {
+	return c.PatternsX
+}
+
+// Phrases returns phrases of cues to remove from subtitles
+func (c cleanupConfig) Phrases() []string {
+	return c.PhrasesX
+}
+
 // Media is a configuration object for media collections
 type Media struct {
 	directory string
@@ -57,6 +159,29 @@ func (m *Media) Template() *template.Template {
 // Default holds the global application configuration instance
 var Default types.Config
 
+// defaultFormat is the format downloaded subtitles are converted to, unless
+// the format is configured
+const defaultFormat = "srt"
+
+// formatOriginal keeps downloaded subtitles in the format they were found in
+const formatOriginal = "original"
+
+// defaultEncoding is the character encoding downloaded subtitles are converted
+// to, unless the encoding is configured
+const defaultEncoding = "utf-8"
+
+// encodingOriginal keeps downloaded subtitles in the character encoding they
+// were found in
+const encodingOriginal = "original"
+
+// defaultValidation discards downloaded subtitles which fail the sanity
+// checks, unless the validation is configured. Subtitles can be flagged
+// instead, while validationOff skips the checks
+const defaultValidation = "discard"
+
+// validationOff skips the sanity checks of downloaded subtitles
+const validationOff = "off"
+
 var homePath string
 
 func init() {
@@ -95,6 +220,14 @@ type viperConfig struct {
 	filters   int
 	providers []types.Provider
 	scrapers  []types.Scraper
+	closers   []io.Closer
+	jobs      jobsConfig
+	watch     watchConfig
+	wanted    wantedConfig
+	cleanup   cleanupConfig
+	format    string
+	encoding  string
+	validate  string
 }
 
 // Initialize construct the default configuration object using viper.
@@ -125,6 +258,40 @@ func Initialize() {
 			Fatal("Invalid duration")
 	}
 
+	// Parse preferred subtitle format
+	format := viper.GetString("format")
+	if format == "" {
+		format = defaultFormat
+	}
+	if format == formatOriginal {
+		format = ""
+	} else if _, err := subformat.ByName(format); err != nil {
+		log.WithField("format", format).Fatal("Unknown subtitle format")
+	}
+
+	// Parse preferred subtitle character encoding
+	encoding := viper.GetString("encoding")
+	if encoding == "" {
+		encoding = defaultEncoding
+	}
+	if encoding == encodingOriginal {
+		encoding = ""
+	} else if _, err := charset.Lookup(encoding); err != nil {
+		log.WithField("encoding", encoding).Fatal("Unknown character encoding")
+	}
+
+	// Parse handling of subtitles failing the sanity checks
+	validate := viper.GetString("validation")
+	switch validate {
+	case "":
+		validate = defaultValidation
+	case validationOff:
+		validate = ""
+	case "discard", "flag":
+	default:
+		log.WithField("validation", validate).Fatal("Unknown subtitle validation")
+	}
+
 	// Parse plugins
 	var _plugins []plugin.Plugin
 	if err := viper.UnmarshalKey("plugins", &_plugins); err != nil {
@@ -132,11 +299,32 @@ func Initialize() {
 	}
 
 	plugins := make([]types.Plugin, 0)
-	for _, p := range _plugins {
+	var pluginProviders []types.Provider
+	var pluginScrapers []types.Scraper
+	var closers []io.Closer
+	for i := range _plugins {
+		p := &_plugins[i]
 		if p.PluginName == "" || p.Exec == "" {
 			log.Fatal("Invalid plugin definitions, missing name and/or exec")
 		}
-		plugins = append(plugins, &p)
+		switch p.Type {
+		case "":
+			plugins = append(plugins, p)
+		case plugin.TypeProvider:
+			external := plugin.NewProvider(p.PluginName, p.Exec)
+			if c, ok := external.(io.Closer); ok {
+				closers = append(closers, c)
+			}
+			pluginProviders = append(pluginProviders, external)
+		case plugin.TypeScraper:
+			external := plugin.NewScraper(p.PluginName, p.Exec)
+			if c, ok := external.(io.Closer); ok {
+				closers = append(closers, c)
+			}
+			pluginScrapers = append(pluginScrapers, external)
+		default:
+			log.WithField("plugin", p.PluginName).Fatalf("Unknown plugin type %v", p.Type)
+		}
 	}
 
 	media := map[string]*Media{
@@ -167,8 +355,126 @@ func Initialize() {
 		}
 	}
 
+	// Parse http policies, where each client may override the defaults
+	policy := provider.DefaultPolicy
+	if err := viper.UnmarshalKey("http", &policy); err != nil {
+		log.WithError(err).Fatal("Invalid http configuration")
+	}
+
+	clients := make(map[string]provider.Policy)
+	for name := range viper.GetStringMap("http.clients") {
+		p := policy
+		if err := viper.UnmarshalKey("http.clients."+name, &p); err != nil {
+			log.WithError(err).WithField("client", name).Fatal("Invalid http configuration")
+		}
+		clients[name] = p
+	}
+
+	provider.SetPolicies(policy, clients)
+
+	jobs := jobsConfig{
+		ConcurrencyX: 1,
+		QueueX:       100,
+		HistoryX:     50,
+	}
+	if err := viper.UnmarshalKey("jobs", &jobs); err != nil {
+		log.WithError(err).Fatal("Invalid jobs configuration")
+	}
+
+	// Watch settings are read key by key, such that they may be overridden by
+	// command line flags
+	watch := watchConfig{
+		DirectoriesX: viper.GetStringSlice("watch.directories"),
+		StableX:      30 * time.Second,
+		RenameX:      true,
+		ExtractX:     true,
+		SubtitlesX:   true,
+	}
+	if viper.IsSet("watch.stable") {
+		watch.StableX = viper.GetDuration("watch.stable")
+	}
+	for key, b := range map[string]*bool{
+		"watch.rename":    &watch.RenameX,
+		"watch.extract":   &watch.ExtractX,
+		"watch.subtitles": &watch.SubtitlesX,
+	} {
+		if viper.IsSet(key) {
+			*b = viper.GetBool(key)
+		}
+	}
+
+	wanted := wantedConfig{
+		ScheduleX: []time.Duration{time.Hour, 6 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour},
+		MaxAgeX:   30 * 24 * time.Hour,
+	}
+	if viper.IsSet("wanted.schedule") {
+		wanted.ScheduleX = nil
+		for _, s := range viper.GetStringSlice("wanted.schedule") {
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				log.WithField("delay", s).Fatal("Invalid wanted schedule")
+			}
+			wanted.ScheduleX = append(wanted.ScheduleX, d)
+		}
+	}
+	if viper.IsSet("wanted.maxage") {
+		wanted.MaxAgeX = viper.GetDuration("wanted.maxage")
+	}
+
+	// Cleanup settings are read key by key, such that they may be overridden
+	// by command line flags
+	clean := cleanupConfig{
+		AdsX:             true,
+		HearingImpairedX: false,
+		PatternsX:        viper.GetStringSlice("cleanup.patterns"),
+		PhrasesX:         viper.GetStringSlice("cleanup.phrases"),
+	}
+	for key, b := range map[string]*bool{
+		"cleanup.ads": &clean.AdsX,
+		"cleanup.hi":  &clean.HearingImpairedX,
+	} {
+		if viper.IsSet(key) {
+			*b = viper.GetBool(key)
+		}
+	}
+	if _, err := cleanup.New(cleanup.Rules{Patterns: clean.PatternsX}); err != nil {
+		log.WithError(err).Fatal("Invalid cleanup pattern")
+	}
+
 	apikeys := viper.GetStringMapString("apikeys")
 
+	var providers []types.Provider
+
+	if archive := viper.GetString("archive"); archive != "" {
+		providers = append(providers, provider.Directory(archive))
+	}
+
+	providers = append(providers, provider.Subscene(), provider.Addic7ed())
+
+	// Parse declarative subtitle site providers
+	var sites []provider.SiteConfig
+	if err := viper.UnmarshalKey("providers", &sites); err != nil {
+		log.WithError(err).Fatal("Invalid provider definition")
+	}
+
+	for _, c := range sites {
+		site, err := provider.Site(c)
+		if err != nil {
+			log.WithError(err).Fatal("Invalid provider definition")
+		}
+		providers = append(providers, site)
+	}
+
+	providers = append(providers, pluginProviders...)
+
+	if apikeys["opensubtitles"] != "" {
+		providers = append(providers, provider.OpenSubtitles(
+			apikeys["opensubtitles"],
+			apikeys["opensubtitles-username"],
+			apikeys["opensubtitles-password"],
+		))
+	}
+
 	Default = viperConfig{
 		languages: lang,
 		modified:  modified,
@@ -178,13 +484,19 @@ func Initialize() {
 		movies:    media["movies"],
 		tvshows:   media["tvshows"],
 		filters:   filters,
-		providers: []types.Provider{
-			provider.Subscene(),
-		},
-		scrapers: []types.Scraper{
+		providers: providers,
+		closers:   closers,
+		jobs:      jobs,
+		watch:     watch,
+		wanted:    wanted,
+		cleanup:   clean,
+		format:    format,
+		encoding:  encoding,
+		validate:  validate,
+		scrapers: append(pluginScrapers,
 			provider.TheMovieDB(apikeys["themoviedb"]),
 			provider.TheTVDB(apikeys["thetvdb"]),
-		},
+		),
 	}
 }
 
@@ -238,6 +550,10 @@ func (v viperConfig) Delay() time.Duration {
 	return v.delay
 }
 
+func (v viperConfig) Workers() int {
+	return viper.GetInt("workers")
+}
+
 func (v viperConfig) Force() bool {
 	return viper.GetBool("force")
 }
@@ -258,6 +574,22 @@ func (v viperConfig) Score() int {
 	return viper.GetInt("score")
 }
 
+func (v viperConfig) Upgrade() int {
+	return viper.GetInt("upgrade")
+}
+
+func (v viperConfig) Format() string {
+	return v.format
+}
+
+func (v viperConfig) Encoding() string {
+	return v.encoding
+}
+
+func (v viperConfig) Validation() string {
+	return v.validate
+}
+
 func (v viperConfig) Plugins() []types.Plugin {
 	return v.plugins
 }
@@ -274,6 +606,45 @@ func (v viperConfig) TheMovieDB() string {
 	return v.apikeys["themoviedb"]
 }
 
+func (v viperConfig) OpenSubtitles() string {
+	return v.apikeys["opensubtitles"]
+}
+
+func (v viperConfig) CacheDir() string {
+	return viper.GetString("cachedir")
+}
+
+func (v viperConfig) DataDir() string {
+	return viper.GetString("datadir")
+}
+
+func (v viperConfig) Index() string {
+	if viper.IsSet("index") || v.DataDir() == "" {
+		return viper.GetString("index")
+	}
+	return filepath.Join(v.DataDir(), "library.db")
+}
+
+func (v viperConfig) Offline() bool {
+	return viper.GetBool("offline")
+}
+
+func (v viperConfig) Jobs() types.JobsConfig {
+	return v.jobs
+}
+
+func (v viperConfig) Watch() types.WatchConfig {
+	return v.watch
+}
+
+func (v viperConfig) Wanted() types.WantedConfig {
+	return v.wanted
+}
+
+func (v viperConfig) Cleanup() types.CleanupConfig {
+	return v.cleanup
+}
+
 func (v viperConfig) Movies() types.MediaConfig {
 	return v.movies
 }
@@ -290,6 +661,18 @@ func (v viperConfig) Scrapers() []types.Scraper {
 	return v.scrapers
 }
 
+// Close stops the external plugins of the configuration and removes their
+// temporary directories
+func (v viperConfig) Close() error {
+	var err error
+	for _, c := range v.closers {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
 func (v viperConfig) RenameAction() string {
 	return viper.GetString("action")
 }
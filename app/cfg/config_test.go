@@ -2,9 +2,12 @@ package cfg
 
 import (
 	"bytes"
+	"io"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/tympanix/supper/app/plugin"
 	"github.com/tympanix/supper/media"
 
 	"github.com/tympanix/supper/media/provider"
@@ -29,6 +32,8 @@ func TestConfigFromViper(t *testing.T) {
 	viper.Set("logfile", "/foo/bar/baz/log")
 	viper.Set("action", "move")
 	viper.Set("proxypath", "/somepath")
+	viper.Set("cachedir", "/foo/cache")
+	viper.Set("datadir", "/foo/data")
 
 	viper.Set("limit", 64)
 	viper.Set("score", 89)
@@ -53,6 +58,9 @@ func TestConfigFromViper(t *testing.T) {
 	assert.Equal(t, Default.Logfile(), "/foo/bar/baz/log")
 	assert.Equal(t, Default.RenameAction(), "move")
 	assert.Equal(t, Default.ProxyPath(), "/somepath")
+	assert.Equal(t, Default.CacheDir(), "/foo/cache")
+	assert.Equal(t, Default.DataDir(), "/foo/data")
+	assert.Equal(t, Default.Index(), filepath.Join("/foo/data", "library.db"))
 
 	assert.Equal(t, Default.Limit(), 64)
 	assert.Equal(t, Default.Score(), 89)
@@ -80,6 +88,195 @@ func TestConfigThirdParty(t *testing.T) {
 	assert.Contains(t, Default.Scrapers(), provider.TheTVDB("tvdb_test_key"))
 }
 
+func TestConfigOpenSubtitles(t *testing.T) {
+	viper.Set("apikeys", map[string]string{
+		"opensubtitles":          "opensubtitles_test_key",
+		"opensubtitles-username": "username",
+		"opensubtitles-password": "password",
+	})
+
+	Initialize()
+
+	assert.Equal(t, "opensubtitles_test_key", Default.APIKeys().OpenSubtitles())
+
+	var names []string
+	for _, p := range Default.Providers() {
+		names = append(names, p.Name())
+	}
+	assert.Contains(t, names, "opensubtitles")
+}
+
+func TestConfigArchive(t *testing.T) {
+	viper.Set("archive", "/media/subtitles")
+	defer viper.Set("archive", "")
+
+	Initialize()
+
+	require.NotEmpty(t, Default.Providers())
+	assert.Equal(t, "directory", Default.Providers()[0].Name())
+}
+
+func TestConfigSites(t *testing.T) {
+	viper.Set("providers", []map[string]interface{}{
+		{
+			"name":    "mysite",
+			"search":  "https://example.com/search?q={{ .Title | urlquery }}",
+			"results": "tr",
+			"link":    "a",
+			"lang":    "da",
+			"media":   []string{"movie"},
+		},
+	})
+	defer viper.Set("providers", nil)
+
+	Initialize()
+
+	var names []string
+	for _, p := range Default.Providers() {
+		names = append(names, p.Name())
+	}
+	assert.Contains(t, names, "mysite")
+}
+
+func TestConfigHTTP(t *testing.T) {
+	viper.Set("http", map[string]interface{}{
+		"timeout": "10s",
+		"retries": 4,
+		"clients": map[string]interface{}{
+			"subscene": map[string]interface{}{
+				"retries": 6,
+				"breaker": 2,
+			},
+		},
+	})
+	defer viper.Set("http", nil)
+
+	Initialize()
+
+	def := provider.PolicyFor("addic7ed")
+	assert.Equal(t, 10*time.Second, def.Timeout)
+	assert.Equal(t, 4, def.Retries)
+	assert.Equal(t, provider.DefaultPolicy.Breaker, def.Breaker)
+
+	sub := provider.PolicyFor("Subscene")
+	assert.Equal(t, 10*time.Second, sub.Timeout)
+	assert.Equal(t, 6, sub.Retries)
+	assert.Equal(t, 2, sub.Breaker)
+}
+
+func TestConfigJobs(t *testing.T) {
+	viper.Set("jobs", map[string]interface{}{
+		"concurrency": 3,
+	})
+	defer viper.Set("jobs", nil)
+
+	Initialize()
+
+	assert.Equal(t, 3, Default.Jobs().Concurrency())
+	assert.Equal(t, 100, Default.Jobs().Queue())
+	assert.Equal(t, 50, Default.Jobs().History())
+}
+
+func TestConfigWatch(t *testing.T) {
+	viper.Set("watch", map[string]interface{}{
+		"directories": []string{"/downloads"},
+		"stable":      "1m",
+		"extract":     false,
+	})
+	defer viper.Set("watch", nil)
+
+	Initialize()
+
+	assert.Equal(t, []string{"/downloads"}, Default.Watch().Directories())
+	assert.Equal(t, time.Minute, Default.Watch().Stable())
+	assert.True(t, Default.Watch().Rename())
+	assert.False(t, Default.Watch().Extract())
+	assert.True(t, Default.Watch().Subtitles())
+}
+
+func TestConfigWanted(t *testing.T) {
+	Initialize()
+
+	assert.Len(t, Default.Wanted().Schedule(), 4)
+	assert.Equal(t, 30*24*time.Hour, Default.Wanted().MaxAge())
+
+	viper.Set("wanted", map[string]interface{}{
+		"schedule": []string{"2h", "12h"},
+		"maxage":   "168h",
+	})
+	defer viper.Set("wanted", nil)
+
+	Initialize()
+
+	assert.Equal(t, []time.Duration{2 * time.Hour, 12 * time.Hour}, Default.Wanted().Schedule())
+	assert.Equal(t, 7*24*time.Hour, Default.Wanted().MaxAge())
+}
+
+func TestConfigFormat(t *testing.T) {
+	Initialize()
+	assert.Equal(t, "srt", Default.Format())
+
+	defer viper.Set("format", nil)
+
+	viper.Set("format", "vtt")
+	Initialize()
+	assert.Equal(t, "vtt", Default.Format())
+
+	viper.Set("format", "original")
+	Initialize()
+	assert.Equal(t, "", Default.Format())
+}
+
+func TestConfigEncoding(t *testing.T) {
+	Initialize()
+	assert.Equal(t, "utf-8", Default.Encoding())
+
+	defer viper.Set("encoding", nil)
+
+	viper.Set("encoding", "windows-1251")
+	Initialize()
+	assert.Equal(t, "windows-1251", Default.Encoding())
+
+	viper.Set("encoding", "original")
+	Initialize()
+	assert.Equal(t, "", Default.Encoding())
+}
+
+func TestConfigValidation(t *testing.T) {
+	Initialize()
+	assert.Equal(t, "discard", Default.Validation())
+
+	defer viper.Set("validation", nil)
+
+	viper.Set("validation", "flag")
+	Initialize()
+	assert.Equal(t, "flag", Default.Validation())
+
+	viper.Set("validation", "off")
+	Initialize()
+	assert.Equal(t, "", Default.Validation())
+}
+
+func TestConfigCleanup(t *testing.T) {
+	Initialize()
+	assert.True(t, Default.Cleanup().Ads())
+	assert.False(t, Default.Cleanup().HearingImpaired())
+
+	viper.Set("cleanup", map[string]interface{}{
+		"ads":      false,
+		"hi":       true,
+		"patterns": []string{"(?i)^resync"},
+		"phrases":  []string{"brought to you by"},
+	})
+	defer viper.Set("cleanup", nil)
+
+	Initialize()
+	assert.False(t, Default.Cleanup().Ads())
+	assert.True(t, Default.Cleanup().HearingImpaired())
+	assert.Equal(t, []string{"(?i)^resync"}, Default.Cleanup().Patterns())
+	assert.Equal(t, []string{"brought to you by"}, Default.Cleanup().Phrases())
+}
+
 func TestConfigPlugins(t *testing.T) {
 	plugins := []map[string]string{
 		{
@@ -100,6 +297,21 @@ func TestConfigPlugins(t *testing.T) {
 	}
 }
 
+func TestConfigPluginsClose(t *testing.T) {
+	viper.Set("plugins", []map[string]string{
+		{"name": "provider", "exec": "provider_exec", "type": plugin.TypeProvider},
+		{"name": "scraper", "exec": "scraper_exec", "type": plugin.TypeScraper},
+	})
+	defer viper.Set("plugins", nil)
+
+	Initialize()
+
+	config, ok := Default.(io.Closer)
+	require.True(t, ok)
+	assert.Len(t, Default.(viperConfig).closers, 2)
+	assert.NoError(t, config.Close())
+}
+
 func TestConfigMedia(t *testing.T) {
 
 	viper.Set("movies", map[string]interface{}{
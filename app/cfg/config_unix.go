@@ -21,3 +21,13 @@ func GlobalPath(app string) string {
 func DefaultPath(app string) string {
 	return filepath.Join("/etc/defaults")
 }
+
+// CachePath returns the default path for cached data
+func CachePath(app string) string {
+	return filepath.Join(homePath, ".cache", strings.ToLower(app))
+}
+
+// DataPath returns the default path for persistent data
+func DataPath(app string) string {
+	return filepath.Join(homePath, ".config", strings.ToLower(app))
+}
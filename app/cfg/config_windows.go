@@ -20,3 +20,13 @@ func GlobalPath(app string) string {
 func DefaultPath(app string) string {
 	return filepath.Join("C:\\ProgramData", app)
 }
+
+// CachePath returns the default path for cached data
+func CachePath(app string) string {
+	return filepath.Join(homePath, "AppData", "Local", app, "cache")
+}
+
+// DataPath returns the default path for persistent data
+func DataPath(app string) string {
+	return filepath.Join(homePath, "AppData", "Roaming", app)
+}
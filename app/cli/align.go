@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"github.com/tympanix/supper/media/subformat"
+)
+
+func init() {
+	flags := alignCmd.Flags()
+
+	flags.String("reference", "", "correctly timed subtitle to align with (e.g. the subtitle in another language)")
+	flags.Int("confidence", 50, "minimum confidence in percent for the subtitle to be changed")
+
+	rootCmd.AddCommand(alignCmd)
+}
+
+var alignCmd = &cobra.Command{
+	Use:   "align --reference <subtitle> <subtitles...>",
+	Short: "Align the timing of subtitles with a reference subtitle",
+	Long: `Align the timing of subtitles with a correctly timed reference subtitle for
+the same release, such as a subtitle in another language. Cues are matched by
+their timing and number of lines, and the subtitle is changed using the best
+piecewise linear timing. Subtitles are only changed if the confidence of the
+alignment is high enough. The original subtitle is kept with an .orig
+extension`,
+	Args: validateMedia,
+	Run:  alignSubtitles,
+}
+
+func alignSubtitles(cmd *cobra.Command, args []string) {
+	reference, _ := cmd.Flags().GetString("reference")
+	confidence, _ := cmd.Flags().GetInt("confidence")
+
+	if reference == "" {
+		log.Fatal("Missing reference subtitle")
+	}
+	if confidence < 0 || confidence > 100 {
+		log.WithField("confidence", confidence).Fatal("Invalid confidence")
+	}
+
+	app := newApp()
+
+	for _, arg := range args {
+		a, err := app.AlignSubtitle(arg, reference, float64(confidence)/100)
+		ctx := alignFields(log.WithField("path", arg), a)
+		if err != nil {
+			fatal(ctx.WithError(err), "Could not align subtitle")
+		}
+		if app.Config().Dry() {
+			ctx.WithField("reason", "dry-run").Info("Skip align")
+			continue
+		}
+		ctx.Info("Subtitle aligned")
+	}
+}
+
+// alignFields adds the details of the alignment to the log entry
+func alignFields(ctx *log.Entry, a subformat.Alignment) *log.Entry {
+	if a.Timing == nil {
+		return ctx
+	}
+	return ctx.WithFields(log.Fields{
+		"confidence": fmt.Sprintf("%.0f%%", a.Confidence*100),
+		"matched":    a.Matched,
+		"segments":   a.Segments,
+	})
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"github.com/tympanix/supper/app/cache"
+	"github.com/tympanix/supper/app/cfg"
+)
+
+func init() {
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cache of scraped metadata and http responses",
+	Args:  cobra.NoArgs,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every entry from the cache",
+	Args:  cobra.NoArgs,
+	Run:   clearCache,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show statistics for the cache",
+	Args:  cobra.NoArgs,
+	Run:   showCacheStats,
+}
+
+func clearCache(cmd *cobra.Command, args []string) {
+	store := cache.New(cfg.Default.CacheDir())
+
+	if cfg.Default.Dry() {
+		log.WithField("path", store.Dir()).Info("Dry run: cache not cleared")
+		return
+	}
+
+	if err := store.Clear(); err != nil {
+		log.WithError(err).Fatal("Could not clear cache")
+	}
+
+	log.WithField("path", store.Dir()).Info("Cache cleared")
+}
+
+func showCacheStats(cmd *cobra.Command, args []string) {
+	store := cache.New(cfg.Default.CacheDir())
+	stats, err := store.Stats()
+
+	if err != nil {
+		log.WithError(err).Fatal("Could not read cache")
+	}
+
+	if len(stats) == 0 {
+		log.WithField("path", store.Dir()).Info("Cache is empty")
+		return
+	}
+
+	for _, s := range stats {
+		log.WithField("entries", s.Entries).
+			WithField("expired", s.Expired).
+			WithField("size", fmt.Sprintf("%.1f KiB", float64(s.Size)/1024)).
+			Info(s.Bucket)
+	}
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tympanix/supper/media/subformat"
+)
+
+func init() {
+	flags := cleanCmd.Flags()
+
+	flags.Bool("ads", true, "remove ads and credits from subtitles")
+	flags.Bool("hi", false, "strip hearing impaired annotations and speaker labels from subtitles")
+
+	viper.BindPFlag("cleanup.ads", flags.Lookup("ads"))
+	viper.BindPFlag("cleanup.hi", flags.Lookup("hi"))
+
+	subtitleCmd.AddCommand(cleanCmd)
+}
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean <paths...>",
+	Short: "Remove ads, credits and hearing impaired annotations from subtitles",
+	Long: `Remove ads, credits and hearing impaired annotations from existing subtitles,
+using the cleanup rules of the configuration. Cues advertising subtitle sites
+or crediting the people who made the subtitle are removed, while --hi strips
+sound descriptions (e.g. [MUSIC] or (laughs)) and speaker labels. Directories
+are searched for subtitles recursively. The original subtitle is kept with an
+.orig extension`,
+	Args: validateMedia,
+	Run:  cleanSubtitles,
+}
+
+func cleanSubtitles(cmd *cobra.Command, args []string) {
+	app := newApp()
+
+	paths := findSubtitles(args)
+
+	var cleaned int
+	for _, path := range paths {
+		ctx := log.WithField("path", path)
+		res, err := app.CleanSubtitle(path)
+		if err == subformat.ErrUnknownFormat {
+			ctx.WithField("reason", "unknown format").Warn("Skip clean")
+			continue
+		}
+		if err != nil {
+			ctx.WithError(err).Error("Could not clean subtitle")
+			continue
+		}
+		if !res.Changed() {
+			ctx.Debug("Subtitle already clean")
+			continue
+		}
+		cleaned++
+		ctx = ctx.WithField("ads", res.Ads).WithField("annotations", res.Annotations)
+		if app.Config().Dry() {
+			ctx.WithField("reason", "dry-run").Info("Skip clean")
+			continue
+		}
+		ctx.Info("Subtitle cleaned")
+	}
+
+	log.WithField("subtitles", len(paths)).WithField("cleaned", cleaned).Info("Clean finished")
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"github.com/tympanix/supper/app/notify"
+)
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+}
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run in the background and retry missing subtitles",
+	Long: `Run in the background and retry missing subtitles. Subtitles which could
+not be found are searched for again on a decaying schedule, as given by the
+wanted configuration. The directories from the watch configuration are
+watched for new media as well`,
+	Args: cobra.NoArgs,
+	Run:  runDaemon,
+}
+
+func runDaemon(cmd *cobra.Command, args []string) {
+	app := newApp()
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	c, done := notify.AsyncLogger()
+	var wg sync.WaitGroup
+
+	if dirs := app.Config().Watch().Directories(); len(dirs) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := watchDirectories(ctx, app, dirs, c)
+			if err != nil && err != context.Canceled {
+				fatal(log.WithError(err), "Could not watch directories")
+			}
+		}()
+		log.WithField("directories", len(dirs)).Info("Watching for new media")
+	}
+
+	log.WithField("wanted", len(app.Wanted())).Info("Retrying missing subtitles")
+
+	err := app.ScheduleWantedContext(ctx, func() {
+		if err := app.RetryWantedContext(ctx, c); err != nil && err != ctx.Err() {
+			log.WithError(err).Error("Could not retry wanted subtitles")
+		}
+	})
+
+	wg.Wait()
+	close(c)
+	<-done
+
+	exitOnCancel(err, "Daemon stopped")
+}
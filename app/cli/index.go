@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"github.com/tympanix/supper/app/cfg"
+	"github.com/tympanix/supper/app/library"
+	"github.com/tympanix/supper/types"
+)
+
+func init() {
+	indexCmd.AddCommand(indexRebuildCmd)
+	rootCmd.AddCommand(indexCmd)
+}
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage the persistent index of the media library",
+	Args:  cobra.NoArgs,
+}
+
+var indexRebuildCmd = &cobra.Command{
+	Use:   "rebuild [media paths...]",
+	Short: "Read and parse every media file in the library again",
+	Long: `Read and parse every media file in the library again. The movies and tv
+shows directories from the configuration are used if no paths are given`,
+	Run: rebuildIndex,
+}
+
+func rebuildIndex(cmd *cobra.Command, args []string) {
+	path := cfg.Default.Index()
+
+	if path == "" {
+		log.Fatal("Library index is disabled")
+	}
+
+	roots := args
+	if len(roots) == 0 {
+		for _, c := range []types.MediaConfig{cfg.Default.Movies(), cfg.Default.TVShows()} {
+			if c.Directory() != "" {
+				roots = append(roots, c.Directory())
+			}
+		}
+	}
+
+	if len(roots) == 0 {
+		log.Fatal("No media paths given and no library directories configured")
+	}
+
+	index, err := library.Open(path)
+	if err != nil {
+		log.WithError(err).WithField("path", path).Fatal("Could not open library index")
+	}
+	defer index.Close()
+
+	stats, err := index.Rebuild(roots...)
+	if err != nil {
+		log.WithError(err).Fatal("Could not rebuild library index")
+	}
+
+	log.WithField("path", path).
+		WithField("directories", stats.Scanned).
+		WithField("files", stats.Parsed).
+		WithField("removed", stats.Removed).
+		Info("Library index rebuilt")
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/sidecar"
+	"github.com/tympanix/supper/media/subformat"
+	"github.com/tympanix/supper/types"
+)
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info <files...>",
+	Short: "Show where downloaded subtitles came from",
+	Long: `Show where downloaded subtitles came from. For each subtitle the provider,
+link, release name, score and time of download is shown, along with whether
+the subtitle has been modified since it was downloaded. Given a video, every
+subtitle of the video is shown`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  showInfo,
+}
+
+func showInfo(cmd *cobra.Command, args []string) {
+	for _, arg := range args {
+		if _, err := os.Stat(arg); err != nil {
+			log.WithError(err).WithField("path", arg).Fatal("Invalid file")
+		}
+
+		subs, err := subtitlesOf(arg)
+		if err != nil {
+			log.WithError(err).WithField("path", arg).Fatal("Could not read subtitles")
+		}
+
+		if len(subs) == 0 {
+			log.WithField("path", arg).Warn("No subtitles found")
+		}
+
+		for _, s := range subs {
+			showSubtitleInfo(s)
+		}
+	}
+}
+
+// subtitlesOf returns the subtitle at the path, or the subtitles of the video
+// at the path
+func subtitlesOf(path string) ([]types.LocalSubtitle, error) {
+	if subformat.IsSubtitle(path) {
+		s, err := media.NewLocalSubtitle(path)
+		if err != nil {
+			return nil, err
+		}
+		return []types.LocalSubtitle{s}, nil
+	}
+
+	m, err := media.NewLocalFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	video, ok := m.(types.Video)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a video", filepath.Base(path))
+	}
+
+	existing, err := video.ExistingSubtitles()
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []types.LocalSubtitle
+	for _, s := range existing.List() {
+		if local, ok := s.(types.LocalSubtitle); ok {
+			subs = append(subs, local)
+		}
+	}
+	return subs, nil
+}
+
+func showSubtitleInfo(s types.LocalSubtitle) {
+	ctx := log.WithField("file", filepath.Base(s.Path()))
+
+	rec, ok, err := sidecar.Get(s.Path())
+	if err != nil {
+		ctx.WithError(err).Error("Could not read subtitle record")
+		return
+	}
+	if !ok {
+		ctx.Info("Subtitle not downloaded by supper")
+		return
+	}
+
+	hash, err := sidecar.Hash(s.Path())
+	if err != nil {
+		ctx.WithError(err).Error("Could not read subtitle")
+		return
+	}
+
+	if rec.Check != nil {
+		ctx = ctx.WithFields(log.Fields{
+			"cues": rec.Check.Cues,
+			"cps":  fmt.Sprintf("%.1f", rec.Check.CPS),
+		})
+		if len(rec.Check.Problems) > 0 {
+			ctx = ctx.WithField("problems", strings.Join(rec.Check.Problems, ", "))
+		}
+	}
+
+	ctx.WithFields(log.Fields{
+		"provider":   rec.Provider,
+		"link":       rec.Link,
+		"release":    rec.Release,
+		"score":      fmt.Sprintf("%.0f%%", rec.Score*100.0),
+		"hi":         rec.HearingImpaired,
+		"downloaded": rec.Downloaded.Format(time.RFC3339),
+		"modified":   rec.Hash != "" && rec.Hash != hash,
+		"edited":     rec.Edited,
+	}).Info("Subtitle downloaded")
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"github.com/tympanix/supper/media/subformat"
+)
+
+func init() {
+	subtitleCmd.AddCommand(normalizeCmd)
+}
+
+var normalizeCmd = &cobra.Command{
+	Use:   "normalize <paths...>",
+	Short: "Convert existing subtitles to the preferred character encoding",
+	Long: `Convert existing subtitles to the preferred character encoding (UTF-8 by
+default, or given by --encoding). The current encoding of each subtitle is detected using the language
+of the subtitle. Directories are searched for subtitles recursively. The
+original subtitle is kept with an .orig extension`,
+	Args: validateMedia,
+	Run:  normalizeSubtitles,
+}
+
+func normalizeSubtitles(cmd *cobra.Command, args []string) {
+	app := newApp()
+
+	if app.Config().Encoding() == "" {
+		fatal(log.Log, "Missing character encoding")
+	}
+
+	paths := findSubtitles(args)
+
+	var normalized int
+	for _, path := range paths {
+		ctx := log.WithField("path", path)
+		from, changed, err := app.NormalizeSubtitle(path)
+		if err == subformat.ErrUnknownFormat {
+			ctx.WithField("reason", "unknown format").Warn("Skip normalize")
+			continue
+		}
+		if err != nil {
+			ctx.WithError(err).Error("Could not normalize subtitle")
+			continue
+		}
+		ctx = ctx.WithField("encoding", from)
+		if !changed {
+			ctx.Debug("Subtitle already normalized")
+			continue
+		}
+		normalized++
+		if app.Config().Dry() {
+			ctx.WithField("reason", "dry-run").Info("Skip normalize")
+			continue
+		}
+		ctx.Info("Subtitle normalized")
+	}
+
+	log.WithField("subtitles", len(paths)).WithField("normalized", normalized).Info("Normalize finished")
+}
+
+// findSubtitles returns the subtitles given as arguments, searching
+// directories for subtitles recursively. Hidden files are skipped
+func findSubtitles(args []string) []string {
+	var paths []string
+	for _, arg := range args {
+		err := filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && !strings.HasPrefix(info.Name(), ".") && subformat.IsSubtitle(path) {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			log.WithError(err).WithField("path", arg).Fatal("Could not search for subtitles")
+		}
+	}
+	return paths
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"github.com/tympanix/supper/app/notify"
+	"github.com/tympanix/supper/media/subformat"
+	"github.com/tympanix/supper/types"
+)
+
+func init() {
+	subtitleCmd.AddCommand(rejectCmd)
+}
+
+var rejectCmd = &cobra.Command{
+	Use:   "reject <subtitles...>",
+	Short: "Reject bad subtitles and download the next best subtitles",
+	Long: `Reject bad subtitles (e.g. subtitles which are out of sync) and download the
+next best subtitles instead. Rejected subtitles are removed and never
+downloaded for the same media again`,
+	Args: validateReject,
+	Run:  rejectSubtitles,
+}
+
+func validateReject(cmd *cobra.Command, args []string) error {
+	if err := validateMedia(cmd, args); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if !subformat.IsSubtitle(arg) {
+			log.WithField("path", arg).Fatal("Not a subtitle")
+		}
+	}
+	return nil
+}
+
+func rejectSubtitles(cmd *cobra.Command, args []string) {
+	app := newApp()
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	c, done := notify.AsyncLogger()
+
+	var downloaded int
+	var err error
+	for _, arg := range args {
+		var subs []types.LocalSubtitle
+		subs, err = app.RejectSubtitleContext(ctx, arg, c)
+		downloaded += len(subs)
+		if err != nil {
+			break
+		}
+	}
+
+	close(c)
+	<-done
+
+	exitOnCancel(err, fmt.Sprintf("Reject cancelled, %v subtitle(s) downloaded", downloaded))
+
+	if err != nil {
+		fatal(log.WithError(err), "Could not reject subtitle")
+	}
+}
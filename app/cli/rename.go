@@ -54,20 +54,24 @@ func validateRenameFlags(cmd *cobra.Command, args []string) {
 }
 
 func renameMedia(cmd *cobra.Command, args []string) {
-	app := app.NewFromDefault()
+	app := newApp()
 
 	medialist, err := app.FindMedia(args...)
 
 	if err != nil {
-		log.WithError(err).Fatal("Could not find media in path")
+		fatal(log.WithError(err), "Could not find media in path")
 	}
 
 	if app.Config().MediaFilter() != nil {
 		medialist = medialist.Filter(app.Config().MediaFilter())
 	}
 
-	if err := app.RenameMedia(medialist); err != nil {
-		log.WithError(err).Fatal("Could not rename media files")
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	if err := app.RenameMediaContext(ctx, medialist); err != nil {
+		exitOnCancel(err, "Rename cancelled")
+		fatal(log.WithError(err), "Could not rename media files")
 	}
 
 	if viper.GetBool("extract") {
@@ -76,11 +80,11 @@ func renameMedia(cmd *cobra.Command, args []string) {
 }
 
 func extractMedia(cmd *cobra.Command, args []string) {
-	app := app.NewFromDefault()
+	app := newApp()
 
 	archives, err := app.FindArchives(args...)
 	if err != nil {
-		log.WithError(err).Fatal("Could not open archives")
+		fatal(log.WithError(err), "Could not open archives")
 	}
 	for _, a := range archives {
 		defer a.Close()
@@ -90,7 +94,7 @@ func extractMedia(cmd *cobra.Command, args []string) {
 			if err = app.ExtractMedia(m); err != nil {
 				if !media.IsExistsErr(err) {
 					if app.Config().Strict() {
-						log.WithError(err).Fatal("Extraction failed")
+						fatal(log.WithError(err), "Extraction failed")
 					} else {
 						log.WithError(err).Error("Extraction failed")
 					}
@@ -101,7 +105,7 @@ func extractMedia(cmd *cobra.Command, args []string) {
 		}
 
 		if err != io.EOF {
-			log.WithError(err).Fatal("Extraction failed")
+			fatal(log.WithError(err), "Extraction failed")
 		}
 	}
 }
@@ -8,8 +8,10 @@ import (
 	"github.com/apex/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/tympanix/supper/app"
 	"github.com/tympanix/supper/app/cfg"
 	"github.com/tympanix/supper/app/logutil"
+	"github.com/tympanix/supper/types"
 )
 
 var (
@@ -50,6 +52,11 @@ var rootCmd = &cobra.Command{
 	Short: AppDesc(),
 	Args:  validateArgs,
 	Run:   rootRun,
+
+	// Stop the plugins of the application once any command has finished
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		closeAll()
+	},
 }
 
 // Execute executes the CLI application
@@ -72,6 +79,7 @@ func init() {
 	flags.BoolP("verbose", "v", false, "enable verbose logging")
 	flags.Bool("strict", false, "exit the application on any error")
 	flags.Bool("version", false, "show the application version and exit")
+	flags.Bool("offline", false, "serve http requests from the cache only")
 
 	// Set up aliases
 	viper.RegisterAlias("lang", "languages")
@@ -84,9 +92,12 @@ func init() {
 	viper.BindPFlag("verbose", flags.Lookup("verbose"))
 	viper.BindPFlag("strict", flags.Lookup("strict"))
 	viper.BindPFlag("version", flags.Lookup("version"))
+	viper.BindPFlag("offline", flags.Lookup("offline"))
 
 	viper.SetDefault("author", "tympanix <tympanix@gmail.com>")
 	viper.SetDefault("license", "GNUv3.0")
+	viper.SetDefault("cachedir", cfg.CachePath(AppName()))
+	viper.SetDefault("datadir", cfg.DataPath(AppName()))
 }
 
 func abortOnConfigErr(err error) {
@@ -158,6 +169,14 @@ func readConfigFiles() {
 	logutil.Initialize(cfg.Default)
 }
 
+// newApp returns the application from the default configuration, which is
+// closed when the command exits
+func newApp() types.App {
+	a := app.NewFromDefault()
+	closeOnExit(a)
+	return a
+}
+
 func validateMedia(cmd *cobra.Command, args []string) error {
 	if len(args) == 0 {
 		log.WithField("args", fmt.Sprintf("%v", len(args))).
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/apex/log"
+)
+
+// exitInterrupted is the exit code when the application has been interrupted
+const exitInterrupted = 130
+
+// closers are closed before the application exits
+var closers struct {
+	sync.Mutex
+	list []io.Closer
+}
+
+// closeOnExit closes c when the command has finished, or before the
+// application exits early because of an error or an interrupt
+func closeOnExit(c io.Closer) {
+	closers.Lock()
+	defer closers.Unlock()
+	closers.list = append(closers.list, c)
+}
+
+// closeAll closes everything which must be closed before the application exits
+func closeAll() {
+	closers.Lock()
+	list := closers.list
+	closers.list = nil
+	closers.Unlock()
+
+	for i := len(list) - 1; i >= 0; i-- {
+		if err := list[i].Close(); err != nil {
+			log.WithError(err).Warn("Could not close application")
+		}
+	}
+}
+
+// exit closes everything which must be closed and exits the application
+func exit(code int) {
+	closeAll()
+	os.Exit(code)
+}
+
+// fatal logs the message as an error and exits the application
+func fatal(ctx log.Interface, msg string) {
+	ctx.Error(msg)
+	exit(1)
+}
+
+// interruptContext returns a context which is cancelled on the first
+// interrupt, such that the application can stop gracefully after the current
+// media. A second interrupt exits the application immediately
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-sig:
+			log.Warn("Interrupted, stopping after current media (interrupt again to exit)")
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(sig)
+			return
+		}
+		<-sig
+		log.Error("Interrupted")
+		exit(exitInterrupted)
+	}()
+
+	return ctx, cancel
+}
+
+// exitOnCancel exits the application if the error is caused by an interrupt
+func exitOnCancel(err error, msg string) {
+	if err == context.Canceled {
+		log.Warn(msg)
+		exit(exitInterrupted)
+	}
+}
@@ -1,22 +1,31 @@
 package cli
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/apex/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
-	"github.com/tympanix/supper/app"
 	"github.com/tympanix/supper/app/cfg"
 	"github.com/tympanix/supper/app/notify"
 )
 
+// upgradeToScore is the value of the upgrade flag when given without a percent,
+// which upgrades subtitles ranking lower than the score
+const upgradeToScore = -1
+
 func init() {
 	flags := subtitleCmd.Flags()
 
 	flags.IntP("score", "s", 0, "only download subtitles ranking higher than specified percent")
+	flags.Int("upgrade", 0, "replace subtitles ranking lower than specified percent (or the score) with better subtitles")
+	flags.Lookup("upgrade").NoOptDefVal = strconv.Itoa(upgradeToScore)
+	flags.String("format", "", "convert subtitles to specified format (srt, ass, ssa, vtt, microdvd, subviewer or original)")
+	flags.String("validate", "", "discard or flag downloaded subtitles failing sanity checks (discard, flag or off)")
 	flags.String("delay", "", "wait specified duration before downloading next subtitle")
+	flags.IntP("workers", "w", 1, "number of media to download subtitles for concurrently")
 	flags.StringSliceP("lang", "l", []string{}, "download subtitle in specified language")
 	flags.BoolP("impaired", "i", false, "hearing impaired subtitles only")
 	flags.Int("limit", 12, "limit maximum number of media to process")
@@ -27,7 +36,15 @@ func init() {
 	viper.BindPFlag("limit", flags.Lookup("limit"))
 	viper.BindPFlag("modified", flags.Lookup("modified"))
 	viper.BindPFlag("score", flags.Lookup("score"))
+	viper.BindPFlag("upgrade", flags.Lookup("upgrade"))
+	viper.BindPFlag("format", flags.Lookup("format"))
+	viper.BindPFlag("validation", flags.Lookup("validate"))
 	viper.BindPFlag("delay", flags.Lookup("delay"))
+	viper.BindPFlag("workers", flags.Lookup("workers"))
+
+	persistent := subtitleCmd.PersistentFlags()
+	persistent.String("encoding", "", "convert subtitles to specified character encoding (e.g. utf-8, utf-16, windows-1251 or original)")
+	viper.BindPFlag("encoding", persistent.Lookup("encoding"))
 
 	rootCmd.AddCommand(subtitleCmd)
 }
@@ -50,18 +67,32 @@ func validateSubtitleFlags(cmd *cobra.Command, args []string) {
 	if cfg.Default.Score() < 0 || cfg.Default.Score() > 100 {
 		log.WithField("score", cfg.Default.Score()).Fatalf("Score must be between 0 and 100")
 	}
+
+	// Upgrade subtitles ranking lower than the score, or every subtitle
+	// downloaded by supper if there is no score
+	if viper.GetInt("upgrade") == upgradeToScore {
+		score := cfg.Default.Score()
+		if score == 0 {
+			score = 100
+		}
+		viper.Set("upgrade", score)
+	}
+
+	if cfg.Default.Upgrade() < 0 || cfg.Default.Upgrade() > 100 {
+		log.WithField("upgrade", cfg.Default.Upgrade()).Fatalf("Upgrade must be between 0 and 100")
+	}
 }
 
 func downloadSubtitles(cmd *cobra.Command, args []string) {
 	// Create new application
-	app := app.NewFromDefault()
+	app := newApp()
 	config := app.Config()
 
 	// Search all argument paths for media
 	media, err := app.FindMedia(args...)
 
 	if err != nil {
-		log.WithError(err).Fatal("Online search failed")
+		fatal(log.WithError(err), "Online search failed")
 	}
 
 	if config.Modified() > 0 {
@@ -69,21 +100,26 @@ func downloadSubtitles(cmd *cobra.Command, args []string) {
 	}
 
 	if media.Len() > config.Limit() && !config.Dry() && config.Limit() != -1 {
-		log.WithFields(log.Fields{
+		fatal(log.WithFields(log.Fields{
 			"media": strconv.Itoa(media.Len()),
 			"limit": strconv.Itoa(config.Limit()),
-		}).Fatal("Media limit exceeded")
+		}), "Media limit exceeded")
 	}
 
+	ctx, cancel := interruptContext()
+	defer cancel()
+
 	c, done := notify.AsyncLogger()
 
-	_, err = app.DownloadSubtitles(media, config.Languages(), c)
+	subs, err := app.DownloadSubtitlesContext(ctx, media, config.Languages(), c)
 
 	close(c)
 	<-done
 
+	exitOnCancel(err, fmt.Sprintf("Download cancelled, %v subtitle(s) downloaded", len(subs)))
+
 	if err != nil {
-		log.WithError(err).Fatal("Download incomplete")
+		fatal(log.WithError(err), "Download incomplete")
 	}
 
 	if config.Dry() {
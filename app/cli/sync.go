@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"github.com/tympanix/supper/media/subformat"
+)
+
+func init() {
+	flags := syncCmd.Flags()
+
+	flags.String("offset", "", "shift subtitles by the duration (e.g. 1.5s or -200ms)")
+	flags.StringSlice("anchor", []string{}, "rescale subtitles such that the time in the subtitle becomes the time in the video (e.g. 01:00:10=01:00:12.5), given twice")
+	flags.String("fps", "", "convert subtitles from one frame rate to another (e.g. 25:23.976)")
+
+	rootCmd.AddCommand(syncCmd)
+}
+
+var syncCmd = &cobra.Command{
+	Use:   "sync <subtitles...>",
+	Short: "Synchronize subtitles by changing their timing",
+	Long: `Synchronize subtitles by changing their timing. Subtitles can be converted
+between frame rates, rescaled between two anchor points and shifted by an
+offset, in that order. The original subtitle is kept with an .orig extension`,
+	Args: validateMedia,
+	Run:  syncSubtitles,
+}
+
+func syncSubtitles(cmd *cobra.Command, args []string) {
+	timing, err := syncTiming(cmd)
+	if err != nil {
+		log.WithError(err).Fatal("Invalid timing")
+	}
+
+	app := newApp()
+
+	for _, arg := range args {
+		if err := app.SyncSubtitle(arg, timing); err != nil {
+			fatal(log.WithError(err).WithField("path", arg), "Could not synchronize subtitle")
+		}
+		if app.Config().Dry() {
+			log.WithField("path", arg).WithField("reason", "dry-run").Info("Skip synchronize")
+			continue
+		}
+		log.WithField("path", arg).Info("Subtitle synchronized")
+	}
+}
+
+// syncTiming returns the timing given by the flags
+func syncTiming(cmd *cobra.Command) (subformat.Timing, error) {
+	offset, _ := cmd.Flags().GetString("offset")
+	anchors, _ := cmd.Flags().GetStringSlice("anchor")
+	fps, _ := cmd.Flags().GetString("fps")
+
+	return subformat.ParseTiming(offset, anchors, fps)
+}
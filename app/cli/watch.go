@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tympanix/supper/app/cfg"
+	"github.com/tympanix/supper/app/notify"
+	"github.com/tympanix/supper/types"
+)
+
+// watchQueue is the number of new files waiting to be processed before the
+// watcher blocks
+const watchQueue = 64
+
+func init() {
+	flags := watchCmd.Flags()
+
+	flags.String("stable", "30s", "wait until files have not changed for the specified duration")
+	flags.Bool("rename", true, "rename new media into the library")
+	flags.Bool("extract", true, "extract media from new archives")
+	flags.Bool("subtitles", true, "download subtitles for new media")
+
+	viper.BindPFlag("watch.stable", flags.Lookup("stable"))
+	viper.BindPFlag("watch.rename", flags.Lookup("rename"))
+	viper.BindPFlag("watch.extract", flags.Lookup("extract"))
+	viper.BindPFlag("watch.subtitles", flags.Lookup("subtitles"))
+
+	rootCmd.AddCommand(watchCmd)
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [directories...]",
+	Short: "Watch directories and process new media automatically",
+	Long: `Watch directories and process new media automatically. New videos and
+archives are renamed or extracted into the library and subtitles are
+downloaded, once the files have not changed for a while. The directories
+from the watch configuration are used if no directories are given`,
+	Args: validateWatch,
+	Run:  watchMedia,
+}
+
+func validateWatch(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && len(cfg.Default.Watch().Directories()) == 0 {
+		log.Fatal("Missing directories to watch")
+	}
+
+	for _, arg := range args {
+		if info, err := os.Stat(arg); err != nil || !info.IsDir() {
+			log.WithField("path", arg).Fatal("Invalid directory")
+		}
+	}
+	return nil
+}
+
+func watchMedia(cmd *cobra.Command, args []string) {
+	app := newApp()
+
+	dirs := args
+	if len(dirs) == 0 {
+		dirs = app.Config().Watch().Directories()
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	c, done := notify.AsyncLogger()
+
+	log.WithField("directories", len(dirs)).Info("Watching for new media")
+
+	err := watchDirectories(ctx, app, dirs, c)
+
+	close(c)
+	<-done
+
+	exitOnCancel(err, "Watching stopped")
+
+	if err != nil {
+		fatal(log.WithError(err), "Could not watch directories")
+	}
+}
+
+// watchDirectories watches the directories and processes new media one file
+// at a time, until the context is cancelled
+func watchDirectories(ctx context.Context, app types.App, dirs []string, c chan<- *notify.Entry) error {
+	queue := make(chan string, watchQueue)
+	processed := make(chan struct{})
+
+	go func() {
+		defer close(processed)
+		for path := range queue {
+			if err := app.ProcessMediaContext(ctx, path, c); err != nil && err != ctx.Err() {
+				log.WithError(err).WithField("path", path).Error("Could not process media")
+			}
+		}
+	}()
+
+	err := app.WatchContext(ctx, dirs, func(path string) {
+		queue <- path
+	})
+
+	close(queue)
+	<-processed
+
+	return err
+}
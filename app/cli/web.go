@@ -1,23 +1,28 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/tympanix/supper/app"
+	"github.com/tympanix/supper/app/cfg"
 )
 
 func init() {
 	webCmd.Flags().IntP("port", "p", 5670, "port used to serve the web application")
 	webCmd.Flags().String("static", "", "path to the web files to serve")
 	webCmd.Flags().String("proxypath", "/", "base path for reverse proxy")
+	webCmd.Flags().StringSlice("watch", []string{}, "watch directories and process new media")
 
 	viper.BindPFlag("port", webCmd.Flags().Lookup("port"))
 	viper.BindPFlag("static", webCmd.Flags().Lookup("static"))
 	viper.BindPFlag("proxypath", webCmd.Flags().Lookup("proxypath"))
+	viper.BindPFlag("watch.directories", webCmd.Flags().Lookup("watch"))
 
 	rootCmd.AddCommand(webCmd)
 }
@@ -29,11 +34,46 @@ var webCmd = &cobra.Command{
 	Run:   startWebServer,
 }
 
+// webShutdownTimeout is how long requests in progress may take to finish when
+// the web application is stopped
+const webShutdownTimeout = 10 * time.Second
+
 func startWebServer(cmd *cobra.Command, args []string) {
-	app := app.NewFromDefault()
-	address := fmt.Sprintf(":%v", viper.GetInt("port"))
+	app := app.New(cfg.Default)
+	closeOnExit(app)
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%v", viper.GetInt("port")),
+		Handler: app,
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	if dirs := app.Config().Watch().Directories(); len(dirs) > 0 {
+		go func() {
+			if err := app.WatchJobs(ctx, dirs); err != nil && ctx.Err() == nil {
+				fatal(log.WithError(err), "Watching directories exited abnormally")
+			}
+		}()
+		log.WithField("directories", len(dirs)).Info("Watching for new media")
+	}
+
+	go app.ScheduleJobs(ctx)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdown, done := context.WithTimeout(context.Background(), webShutdownTimeout)
+		defer done()
+		server.Shutdown(shutdown)
+	}()
 
 	log.Infof("Listening on %v...\n", viper.GetInt("port"))
-	log.WithError(http.ListenAndServe(address, app)).
-		Fatal("Web application exited abnormally")
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		fatal(log.WithError(err), "Web application exited abnormally")
+	}
+	<-stopped
+	log.Warn("Web application stopped")
 }
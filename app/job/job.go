@@ -0,0 +1,270 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tympanix/supper/app/notify"
+)
+
+// State is the state of a job
+type State string
+
+const (
+	// StateQueued is for jobs waiting for a worker
+	StateQueued State = "queued"
+
+	// StateRunning is for jobs currently running
+	StateRunning State = "running"
+
+	// StateDone is for jobs which finished without errors
+	StateDone State = "done"
+
+	// StateFailed is for jobs which finished with an error
+	StateFailed State = "failed"
+
+	// StateCancelled is for jobs which were cancelled
+	StateCancelled State = "cancelled"
+)
+
+// Finished returns true if the state is final
+func (s State) Finished() bool {
+	return s == StateDone || s == StateFailed || s == StateCancelled
+}
+
+// Task is the work performed by a job. Notifications sent on the channel are
+// recorded as results of the job. The task must stop when the context is
+// cancelled
+type Task func(ctx context.Context, c chan<- *notify.Entry) error
+
+// Progress is the item currently being processed by a job out of the total
+// number of items
+type Progress struct {
+	Current int `json:"current"`
+	Total   int `json:"total"`
+}
+
+// Result is a notification reported by a job
+type Result struct {
+	Time    time.Time    `json:"time"`
+	Level   notify.Level `json:"level"`
+	Message string       `json:"message"`
+	Fields  notify.Map   `json:"data"`
+}
+
+// Job is a task which runs in the background. A job is safe for concurrent
+// use
+type Job struct {
+	mu       sync.RWMutex
+	id       int
+	name     string
+	key      string
+	task     Task
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	state    State
+	created  time.Time
+	started  time.Time
+	finished time.Time
+	progress Progress
+	results  []Result
+	err      error
+}
+
+func newJob(id int, name string, key string, task Task) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Job{
+		id:      id,
+		name:    name,
+		key:     key,
+		task:    task,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		state:   StateQueued,
+		created: time.Now(),
+		results: make([]Result, 0),
+	}
+}
+
+// ID returns the unique id of the job
+func (j *Job) ID() int {
+	return j.id
+}
+
+// Name returns the description of the job
+func (j *Job) Name() string {
+	return j.name
+}
+
+// Key returns the resource the job is working on
+func (j *Job) Key() string {
+	return j.key
+}
+
+// State returns the current state of the job
+func (j *Job) State() State {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.state
+}
+
+// Progress returns the current progress of the job
+func (j *Job) Progress() Progress {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.progress
+}
+
+// Results returns the notifications reported by the job so far
+func (j *Job) Results() []Result {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return append([]Result(nil), j.results...)
+}
+
+// Err returns the error of a failed job
+func (j *Job) Err() error {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.err
+}
+
+// Done returns a channel which is closed when the job has finished
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// start marks the job as running. False is returned if the job has already
+// been cancelled
+func (j *Job) start() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.state != StateQueued {
+		return false
+	}
+	j.state = StateRunning
+	j.started = time.Now()
+	return true
+}
+
+// finish records the final state of the job
+func (j *Job) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.end(err)
+}
+
+// end records the final state of the job, which must be locked
+func (j *Job) end(err error) {
+	if j.state.Finished() {
+		return
+	}
+	if err == context.Canceled || (err != nil && j.ctx.Err() != nil) {
+		j.state = StateCancelled
+	} else if err != nil {
+		j.state = StateFailed
+		j.err = err
+	} else {
+		j.state = StateDone
+	}
+	j.finished = time.Now()
+	j.cancel()
+	close(j.done)
+}
+
+// stop cancels the job. A queued job is finished right away, while a running
+// job finishes once the task has returned. False is returned if the job has
+// already finished
+func (j *Job) stop() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch j.state {
+	case StateQueued:
+		j.end(context.Canceled)
+	case StateRunning:
+		j.cancel()
+	default:
+		return false
+	}
+	return true
+}
+
+// record adds the notification to the results of the job. Notifications with
+// an item field of the form current/total advances the progress
+func (j *Job) record(e *notify.Entry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if item, ok := e.Fields["item"].(string); ok {
+		var p Progress
+		if _, err := fmt.Sscanf(item, "%d/%d", &p.Current, &p.Total); err == nil {
+			j.progress = p
+		}
+	}
+
+	if e.Level == notify.LevelDebug {
+		return
+	}
+
+	fields := make(notify.Map)
+	for k, v := range e.Fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		fields[k] = v
+	}
+
+	j.results = append(j.results, Result{
+		Time:    time.Now(),
+		Level:   e.Level,
+		Message: e.Message,
+		Fields:  fields,
+	})
+}
+
+// MarshalJSON returns the json representation of the job
+func (j *Job) MarshalJSON() ([]byte, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	var errmsg string
+	if j.err != nil {
+		errmsg = j.err.Error()
+	}
+
+	return json.Marshal(struct {
+		ID       int        `json:"id"`
+		Name     string     `json:"name"`
+		Key      string     `json:"key"`
+		State    State      `json:"state"`
+		Created  time.Time  `json:"created"`
+		Started  *time.Time `json:"started,omitempty"`
+		Finished *time.Time `json:"finished,omitempty"`
+		Progress Progress   `json:"progress"`
+		Results  []Result   `json:"results"`
+		Error    string     `json:"error,omitempty"`
+	}{
+		ID:       j.id,
+		Name:     j.name,
+		Key:      j.key,
+		State:    j.state,
+		Created:  j.created,
+		Started:  timeOrNil(j.started),
+		Finished: timeOrNil(j.finished),
+		Progress: j.progress,
+		Results:  j.results,
+		Error:    errmsg,
+	})
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,228 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tympanix/supper/app/notify"
+)
+
+var (
+	// ErrBusy is returned when another job is working on the same resource
+	ErrBusy = errors.New("resource is busy")
+
+	// ErrQueueFull is returned when too many jobs are waiting for a worker
+	ErrQueueFull = errors.New("job queue is full")
+
+	// ErrNotFound is returned for jobs which does not exist
+	ErrNotFound = errors.New("job not found")
+
+	// ErrFinished is returned when cancelling a job which has finished
+	ErrFinished = errors.New("job has already finished")
+)
+
+// Listener receives the notifications of every job
+type Listener func(*Job, *notify.Entry)
+
+// Manager runs jobs on a fixed number of workers. Jobs waiting for a worker
+// are kept in a bounded queue, and a number of finished jobs are retained as
+// history. A manager is safe for concurrent use
+type Manager struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	next      int
+	limit     int
+	history   int
+	queue     []*Job
+	jobs      []*Job
+	listeners []Listener
+}
+
+// NewManager returns a manager which runs the given number of jobs
+// concurrently. At most queue jobs may wait for a worker (unbounded if zero),
+// and the given number of finished jobs are kept as history
+func NewManager(concurrency int, queue int, history int) *Manager {
+	m := &Manager{
+		limit:   queue,
+		history: history,
+	}
+	m.cond = sync.NewCond(&m.mu)
+
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	for i := 0; i < concurrency; i++ {
+		go m.work()
+	}
+
+	return m
+}
+
+// Listen registers a listener which receives the notifications of every job
+func (m *Manager) Listen(l Listener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, l)
+}
+
+// Submit queues a new job which performs the task. The key identifies the
+// resource the job is working on, such that only a single job is active for
+// each resource at a time. An empty key is never busy
+func (m *Manager) Submit(name string, key string, task Task) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if key != "" {
+		for _, j := range m.jobs {
+			if j.Key() == key && !j.State().Finished() {
+				return nil, ErrBusy
+			}
+		}
+	}
+
+	if m.limit > 0 && len(m.queue) >= m.limit {
+		return nil, ErrQueueFull
+	}
+
+	m.next++
+	j := newJob(m.next, name, key, task)
+	m.jobs = append(m.jobs, j)
+	m.queue = append(m.queue, j)
+	m.cond.Signal()
+
+	return j, nil
+}
+
+// Get returns the job with the id
+func (m *Manager) Get(id int) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.jobs {
+		if j.ID() == id {
+			return j, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// List returns every active job and the history of finished jobs, with the
+// most recently submitted job first
+func (m *Manager) List() []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]*Job, 0, len(m.jobs))
+	for i := len(m.jobs) - 1; i >= 0; i-- {
+		jobs = append(jobs, m.jobs[i])
+	}
+	return jobs
+}
+
+// Cancel stops the job with the id. A running job finishes when its task
+// returns
+func (m *Manager) Cancel(id int) error {
+	j, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !j.stop() {
+		return ErrFinished
+	}
+
+	for i, q := range m.queue {
+		if q == j {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			break
+		}
+	}
+	m.prune()
+
+	return nil
+}
+
+// work runs jobs from the queue forever
+func (m *Manager) work() {
+	for {
+		m.mu.Lock()
+		for len(m.queue) == 0 {
+			m.cond.Wait()
+		}
+		j := m.queue[0]
+		m.queue = m.queue[1:]
+		m.mu.Unlock()
+
+		m.run(j)
+	}
+}
+
+// run performs the task of the job and records its notifications
+func (m *Manager) run(j *Job) {
+	if !j.start() {
+		return
+	}
+
+	c := make(chan *notify.Entry)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for e := range c {
+			j.record(e)
+			m.notify(j, e)
+		}
+	}()
+
+	err := m.perform(j, c)
+	close(c)
+	<-done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j.finish(err)
+	m.prune()
+}
+
+// perform runs the task, such that a panicking task only fails the job
+func (m *Manager) perform(j *Job, c chan<- *notify.Entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return j.task(j.ctx, c)
+}
+
+func (m *Manager) notify(j *Job, e *notify.Entry) {
+	m.mu.Lock()
+	listeners := m.listeners
+	m.mu.Unlock()
+	for _, l := range listeners {
+		l(j, e)
+	}
+}
+
+// prune removes the oldest finished jobs exceeding the history. The manager
+// must be locked
+func (m *Manager) prune() {
+	var finished int
+	for _, j := range m.jobs {
+		if j.State().Finished() {
+			finished++
+		}
+	}
+
+	jobs := m.jobs[:0]
+	for _, j := range m.jobs {
+		if finished > m.history && j.State().Finished() {
+			finished--
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+	m.jobs = jobs
+}
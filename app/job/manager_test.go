@@ -0,0 +1,161 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/app/notify"
+)
+
+func waitJob(t *testing.T, j *Job) {
+	select {
+	case <-j.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("job %v did not finish", j.ID())
+	}
+}
+
+// blockingTask returns a task which runs until released or cancelled
+func blockingTask(release <-chan struct{}) Task {
+	return func(ctx context.Context, c chan<- *notify.Entry) error {
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func TestJobResults(t *testing.T) {
+	m := NewManager(1, 0, 10)
+
+	var listened []string
+	m.Listen(func(j *Job, e *notify.Entry) {
+		listened = append(listened, e.Message)
+	})
+
+	j, err := m.Submit("test", "", func(ctx context.Context, c chan<- *notify.Entry) error {
+		c <- notify.WithField("item", "1/2").Info("first")
+		c <- notify.WithField("item", "2/2").WithError(errors.New("oops")).Error("second")
+		c <- notify.Debug("debugging")
+		return nil
+	})
+	require.NoError(t, err)
+	waitJob(t, j)
+
+	assert.Equal(t, StateDone, j.State())
+	assert.Equal(t, Progress{2, 2}, j.Progress())
+	assert.Equal(t, []string{"first", "second", "debugging"}, listened)
+
+	results := j.Results()
+	require.Len(t, results, 2)
+	assert.Equal(t, "first", results[0].Message)
+	assert.Equal(t, "oops", results[1].Fields["error"])
+
+	data, err := json.Marshal(j)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"state":"done"`)
+	assert.Contains(t, string(data), `"progress":{"current":2,"total":2}`)
+}
+
+func TestJobFailed(t *testing.T) {
+	m := NewManager(1, 0, 10)
+
+	j, err := m.Submit("fail", "", func(ctx context.Context, c chan<- *notify.Entry) error {
+		return errors.New("failure")
+	})
+	require.NoError(t, err)
+	waitJob(t, j)
+
+	assert.Equal(t, StateFailed, j.State())
+	assert.EqualError(t, j.Err(), "failure")
+
+	j, err = m.Submit("panic", "", func(ctx context.Context, c chan<- *notify.Entry) error {
+		panic("boom")
+	})
+	require.NoError(t, err)
+	waitJob(t, j)
+
+	assert.Equal(t, StateFailed, j.State())
+}
+
+func TestJobBusy(t *testing.T) {
+	m := NewManager(1, 0, 10)
+	release := make(chan struct{})
+
+	j, err := m.Submit("first", "/media/movie", blockingTask(release))
+	require.NoError(t, err)
+
+	_, err = m.Submit("second", "/media/movie", blockingTask(release))
+	assert.Equal(t, ErrBusy, err)
+
+	close(release)
+	waitJob(t, j)
+
+	j, err = m.Submit("third", "/media/movie", blockingTask(release))
+	require.NoError(t, err)
+	waitJob(t, j)
+}
+
+func TestJobQueue(t *testing.T) {
+	m := NewManager(1, 1, 10)
+	release := make(chan struct{})
+
+	running, err := m.Submit("running", "", blockingTask(release))
+	require.NoError(t, err)
+
+	for running.State() != StateRunning {
+		time.Sleep(time.Millisecond)
+	}
+
+	queued, err := m.Submit("queued", "", blockingTask(release))
+	require.NoError(t, err)
+	assert.Equal(t, StateQueued, queued.State())
+
+	_, err = m.Submit("full", "", blockingTask(release))
+	assert.Equal(t, ErrQueueFull, err)
+
+	// Cancelling a queued job finishes it right away and frees the queue
+	require.NoError(t, m.Cancel(queued.ID()))
+	assert.Equal(t, StateCancelled, queued.State())
+
+	other, err := m.Submit("other", "", blockingTask(release))
+	require.NoError(t, err)
+
+	require.NoError(t, m.Cancel(running.ID()))
+	waitJob(t, running)
+	assert.Equal(t, StateCancelled, running.State())
+
+	assert.Equal(t, ErrFinished, m.Cancel(running.ID()))
+	assert.Equal(t, ErrNotFound, m.Cancel(1000))
+
+	close(release)
+	waitJob(t, other)
+	assert.Equal(t, StateDone, other.State())
+}
+
+func TestJobHistory(t *testing.T) {
+	m := NewManager(2, 0, 2)
+
+	for i := 0; i < 5; i++ {
+		j, err := m.Submit("test", "", func(ctx context.Context, c chan<- *notify.Entry) error {
+			return nil
+		})
+		require.NoError(t, err)
+		waitJob(t, j)
+	}
+
+	jobs := m.List()
+	require.Len(t, jobs, 2)
+	assert.Equal(t, 5, jobs[0].ID())
+	assert.Equal(t, 4, jobs[1].ID())
+
+	_, err := m.Get(1)
+	assert.Equal(t, ErrNotFound, err)
+}
@@ -0,0 +1,523 @@
+package library
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/list"
+	"github.com/tympanix/supper/media/parse"
+	"github.com/tympanix/supper/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	filesBucket = []byte("files")
+	dirsBucket  = []byte("dirs")
+)
+
+// racy is the duration after a modification of a directory in which its
+// modification time is not trusted, since filesystems may store timestamps
+// with a coarse resolution
+const racy = 2 * time.Second
+
+// Index is a persistent index of the media files in the library. Directories
+// which have not been modified since they were indexed are not read again,
+// and files are only parsed again if their size or modification time has
+// changed. The index is stored in a single file which may only be opened by
+// one process at a time
+type Index struct {
+	db *bolt.DB
+}
+
+// Entry is a media file in the index, along with the media parsed from its
+// filename
+type Entry struct {
+	Path    string            `json:"path"`
+	Size    int64             `json:"size"`
+	Mode    os.FileMode       `json:"mode"`
+	ModTime time.Time         `json:"modtime"`
+	Media   *media.Record     `json:"media,omitempty"`
+	Scraped map[string]string `json:"scraped,omitempty"`
+}
+
+// Info returns the file information of the entry
+func (e *Entry) Info() os.FileInfo {
+	return fileInfo{e}
+}
+
+// local returns the media of the entry for the file at the path, as well as
+// the subtitle if the file is a subtitle. Entries without parsed media are
+// parsed from the filename
+func (e *Entry) local(path string) (types.LocalMedia, types.LocalSubtitle, error) {
+	if e.Media == nil {
+		m, err := media.NewLocalFileInfo(path, e.Info())
+		if err != nil {
+			return nil, nil, err
+		}
+		s, _ := media.NewLocalSubtitleInfo(path, e.Info())
+		return m, s, nil
+	}
+	m, err := e.Media.LocalFile(path, e.Info())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !e.Media.Subtitle {
+		return m, nil, nil
+	}
+	s, err := e.Media.LocalSubtitle(path, e.Info())
+	return m, s, err
+}
+
+func (e *Entry) changed(info os.FileInfo) bool {
+	return e.Size != info.Size() || !e.ModTime.Equal(info.ModTime())
+}
+
+type fileInfo struct {
+	*Entry
+}
+
+func (f fileInfo) Name() string       { return filepath.Base(f.Path) }
+func (f fileInfo) Size() int64        { return f.Entry.Size }
+func (f fileInfo) Mode() os.FileMode  { return f.Entry.Mode }
+func (f fileInfo) ModTime() time.Time { return f.Entry.ModTime }
+func (f fileInfo) IsDir() bool        { return false }
+func (f fileInfo) Sys() interface{}   { return nil }
+
+// dir is a directory in the index
+type dir struct {
+	ModTime time.Time `json:"modtime"`
+	Scanned time.Time `json:"scanned"`
+	Files   []string  `json:"files"`
+	Dirs    []string  `json:"dirs"`
+}
+
+// unchanged returns true if the directory has not been modified since it was
+// indexed
+func (d *dir) unchanged(info os.FileInfo) bool {
+	return d.ModTime.Equal(info.ModTime()) && d.Scanned.Sub(d.ModTime) > racy
+}
+
+// Stats describes the work performed when refreshing the index
+type Stats struct {
+	Scanned int `json:"scanned"`
+	Skipped int `json:"skipped"`
+	Parsed  int `json:"parsed"`
+	Removed int `json:"removed"`
+}
+
+// Open opens the index at the path, creating it if it does not exist. An
+// error is returned if the index is in use by another process
+func Open(path string) (*Index, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{filesBucket, dirsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Index{db}, nil
+}
+
+// Close closes the index
+func (x *Index) Close() error {
+	return x.db.Close()
+}
+
+// Refresh updates the index for the directories. Only directories and files
+// which have changed since they were indexed are read and parsed again
+func (x *Index) Refresh(roots ...string) (Stats, error) {
+	return x.refresh(false, roots)
+}
+
+// Rebuild reads and parses every file in the directories again, even if they
+// have not changed since they were indexed
+func (x *Index) Rebuild(roots ...string) (Stats, error) {
+	return x.refresh(true, roots)
+}
+
+func (x *Index) refresh(force bool, roots []string) (stats Stats, err error) {
+	err = x.db.Update(func(tx *bolt.Tx) error {
+		r := &refresher{
+			files: tx.Bucket(filesBucket),
+			dirs:  tx.Bucket(dirsBucket),
+			force: force,
+			stats: &stats,
+		}
+		for _, root := range roots {
+			abs, err := filepath.Abs(root)
+			if err != nil {
+				return err
+			}
+			info, err := os.Stat(abs)
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				continue
+			}
+			if err := r.refresh(abs, info); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// refresher updates the index within a single transaction
+type refresher struct {
+	files *bolt.Bucket
+	dirs  *bolt.Bucket
+	force bool
+	stats *Stats
+}
+
+func (r *refresher) refresh(path string, info os.FileInfo) error {
+	var stored *dir
+	if data := r.dirs.Get([]byte(path)); data != nil {
+		stored = new(dir)
+		if err := json.Unmarshal(data, stored); err != nil {
+			stored = nil
+		}
+	}
+
+	if !r.force && stored != nil && stored.unchanged(info) {
+		r.stats.Skipped++
+		for _, name := range stored.Dirs {
+			sub := filepath.Join(path, name)
+			subinfo, err := os.Stat(sub)
+			if err != nil || !subinfo.IsDir() {
+				// The directory changed while its timestamp did not
+				return r.scan(path, info, stored)
+			}
+			if err := r.refresh(sub, subinfo); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return r.scan(path, info, stored)
+}
+
+// scan reads the directory from disk and updates the entries of the files
+func (r *refresher) scan(path string, info os.FileInfo, stored *dir) error {
+	r.stats.Scanned++
+
+	infos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	current := &dir{
+		ModTime: info.ModTime(),
+		Scanned: time.Now(),
+		Files:   make([]string, 0),
+		Dirs:    make([]string, 0),
+	}
+
+	for _, f := range infos {
+		if strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		sub := filepath.Join(path, f.Name())
+		if f.IsDir() {
+			current.Dirs = append(current.Dirs, f.Name())
+			if err := r.refresh(sub, f); err != nil {
+				return err
+			}
+			continue
+		}
+		ok, err := r.update(sub, f)
+		if err != nil {
+			return err
+		}
+		if ok {
+			current.Files = append(current.Files, f.Name())
+		}
+	}
+
+	if stored != nil {
+		if err := r.removeStale(path, stored, current); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	return r.dirs.Put([]byte(path), data)
+}
+
+// update indexes the file if it has changed. False is returned if the file is
+// not media
+func (r *refresher) update(path string, info os.FileInfo) (bool, error) {
+	var entry Entry
+	if data := r.files.Get([]byte(path)); data != nil {
+		if err := json.Unmarshal(data, &entry); err == nil && !r.force && !entry.changed(info) {
+			return true, nil
+		}
+	}
+
+	r.stats.Parsed++
+
+	m, err := media.NewLocalFileInfo(path, info)
+	if err != nil || media.IsSample(m) {
+		return false, r.files.Delete([]byte(path))
+	}
+
+	entry.Path = path
+	entry.Size = info.Size()
+	entry.Mode = info.Mode()
+	entry.ModTime = info.ModTime()
+	entry.Media, _ = media.NewRecord(m)
+
+	data, err := json.Marshal(&entry)
+	if err != nil {
+		return false, err
+	}
+	return true, r.files.Put([]byte(path), data)
+}
+
+// removeStale removes files and directories which no longer exists
+func (r *refresher) removeStale(path string, stored *dir, current *dir) error {
+	for _, name := range difference(stored.Files, current.Files) {
+		r.stats.Removed++
+		if err := r.files.Delete([]byte(filepath.Join(path, name))); err != nil {
+			return err
+		}
+	}
+	for _, name := range difference(stored.Dirs, current.Dirs) {
+		sub := filepath.Join(path, name)
+		if err := r.dirs.Delete([]byte(sub)); err != nil {
+			return err
+		}
+		for _, b := range []*bolt.Bucket{r.files, r.dirs} {
+			n, err := deletePrefix(b, sub+string(filepath.Separator))
+			if err != nil {
+				return err
+			}
+			if b == r.files {
+				r.stats.Removed += n
+			}
+		}
+	}
+	return nil
+}
+
+// Entry returns the entry of the file at the path
+func (x *Index) Entry(path string) (*Entry, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry *Entry
+	err = x.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(filesBucket).Get([]byte(abs))
+		if data == nil {
+			return nil
+		}
+		entry = new(Entry)
+		return json.Unmarshal(data, entry)
+	})
+	return entry, err
+}
+
+// SetScraped records the identity of the media at the path as found by the
+// scraper
+func (x *Index) SetScraped(path string, scraper string, id string) error {
+	return x.update(path, func(entry *Entry) {
+		if entry.Scraped == nil {
+			entry.Scraped = make(map[string]string)
+		}
+		entry.Scraped[scraper] = id
+	})
+}
+
+// CopyScraped records the identities of the media at the source path for the
+// media at the destination path, i.e. when the media has been renamed
+func (x *Index) CopyScraped(src string, dst string) error {
+	entry, err := x.Entry(src)
+	if err != nil || entry == nil || len(entry.Scraped) == 0 {
+		return err
+	}
+	return x.update(dst, func(e *Entry) {
+		if e.Scraped == nil {
+			e.Scraped = make(map[string]string)
+		}
+		for k, v := range entry.Scraped {
+			e.Scraped[k] = v
+		}
+	})
+}
+
+// update modifies the entry of the file at the path. The file is added to the
+// index if it is not indexed already
+func (x *Index) update(path string, fn func(*Entry)) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	return x.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(filesBucket)
+		var entry Entry
+		if data := b.Get([]byte(abs)); data != nil {
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return err
+			}
+		} else {
+			info, err := os.Stat(abs)
+			if err != nil {
+				return err
+			}
+			entry = Entry{
+				Path:    abs,
+				Size:    info.Size(),
+				Mode:    info.Mode(),
+				ModTime: info.ModTime(),
+			}
+		}
+		fn(&entry)
+		data, err := json.Marshal(&entry)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(abs), data)
+	})
+}
+
+// Find returns the indexed media in the directories. The existing subtitles
+// of videos are found using the index as well. Paths are relative if the
+// directory is given as a relative path
+func (x *Index) Find(roots ...string) (types.LocalMediaList, error) {
+	result := make([]types.LocalMedia, 0)
+
+	err := x.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(filesBucket).Cursor()
+
+		for _, root := range roots {
+			abs, err := filepath.Abs(root)
+			if err != nil {
+				return err
+			}
+
+			var found []types.LocalMedia
+			subs := make(map[string][]types.LocalSubtitle)
+
+			prefix := []byte(abs + string(filepath.Separator))
+			for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+				var entry Entry
+				if err := json.Unmarshal(v, &entry); err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(abs, entry.Path)
+				if err != nil {
+					return err
+				}
+				path := filepath.Join(root, rel)
+				m, s, err := entry.local(path)
+				if err != nil {
+					continue
+				}
+				if s != nil {
+					dir := filepath.Dir(path)
+					subs[dir] = append(subs[dir], s)
+				}
+				found = append(found, m)
+			}
+
+			for _, m := range found {
+				if v, ok := m.(types.Video); ok {
+					m = indexedVideo{v, existingSubtitles(v, subs[filepath.Dir(v.Path())])}
+				}
+				result = append(result, m)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list.NewLocalMedia(result...), nil
+}
+
+// existingSubtitles returns the subtitles which belongs to the video, in the
+// same way as the subtitles are found on disk
+func existingSubtitles(v types.Video, subs []types.LocalSubtitle) types.SubtitleList {
+	name := parse.Filename(v.Path())
+	existing := make([]types.Subtitle, 0)
+	for _, s := range subs {
+		if strings.HasPrefix(filepath.Base(s.Path()), name) {
+			existing = append(existing, s)
+		}
+	}
+	return list.Subtitles(existing...)
+}
+
+// indexedVideo is a video where the existing subtitles are known from the
+// index
+type indexedVideo struct {
+	types.Video
+	subs types.SubtitleList
+}
+
+func (v indexedVideo) ExistingSubtitles() (types.SubtitleList, error) {
+	return v.subs, nil
+}
+
+func (v indexedVideo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Video)
+}
+
+func difference(a []string, b []string) []string {
+	keep := make(map[string]bool, len(b))
+	for _, s := range b {
+		keep[s] = true
+	}
+	var diff []string
+	for _, s := range a {
+		if !keep[s] {
+			diff = append(diff, s)
+		}
+	}
+	return diff
+}
+
+func deletePrefix(b *bolt.Bucket, prefix string) (int, error) {
+	var keys [][]byte
+	c := b.Cursor()
+	for k, _ := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, _ = c.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
@@ -0,0 +1,208 @@
+package library
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/media/meta/quality"
+	"github.com/tympanix/supper/media/meta/source"
+	"github.com/tympanix/supper/types"
+)
+
+func newTestLibrary(t *testing.T) (string, *Index) {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+
+	for _, f := range []string{
+		"movies/Inception (2010)/Inception.2010.720p.BluRay.x264.mkv",
+		"movies/Inception (2010)/Inception.2010.720p.BluRay.x264.en.srt",
+		"movies/Inception (2010)/sample.mkv",
+		"movies/Inception (2010)/readme.txt",
+		"movies/Alien.1979.1080p.WEB-DL.mp4",
+	} {
+		touch(t, filepath.Join(dir, f))
+	}
+
+	index, err := Open(filepath.Join(dir, "index", "library.db"))
+	require.NoError(t, err)
+
+	return dir, index
+}
+
+func touch(t *testing.T, path string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(path, []byte{}, 0644))
+}
+
+// settle makes the directories old enough for the index to trust their
+// modification times
+func settle(t *testing.T, root string) {
+	past := time.Now().Add(-time.Hour)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.Chtimes(path, past, past)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestIndexRefresh(t *testing.T) {
+	dir, index := newTestLibrary(t)
+	defer os.RemoveAll(dir)
+	defer index.Close()
+
+	movies := filepath.Join(dir, "movies")
+	settle(t, movies)
+
+	stats, err := index.Refresh(movies)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Scanned)
+	assert.Equal(t, 0, stats.Skipped)
+
+	found, err := index.Find(movies)
+	require.NoError(t, err)
+	assert.Equal(t, 3, found.Len())
+
+	stats, err = index.Refresh(movies)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Skipped: 2}, stats)
+
+	stats, err = index.Rebuild(movies)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Scanned)
+	assert.Equal(t, 5, stats.Parsed)
+}
+
+func TestIndexRemoved(t *testing.T) {
+	dir, index := newTestLibrary(t)
+	defer os.RemoveAll(dir)
+	defer index.Close()
+
+	movies := filepath.Join(dir, "movies")
+	settle(t, movies)
+
+	_, err := index.Refresh(movies)
+	require.NoError(t, err)
+
+	require.NoError(t, os.RemoveAll(filepath.Join(movies, "Inception (2010)")))
+	touch(t, filepath.Join(movies, "Heat.1995.720p.BluRay.mkv"))
+
+	stats, err := index.Refresh(movies)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Removed)
+	assert.Equal(t, 1, stats.Scanned)
+
+	found, err := index.Find(movies)
+	require.NoError(t, err)
+	assert.Equal(t, 2, found.Len())
+	assert.Equal(t, 2, found.FilterMovies().Len())
+
+	entry, err := index.Entry(filepath.Join(movies, "Inception (2010)", "Inception.2010.720p.BluRay.x264.mkv"))
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+}
+
+func TestIndexFindSubtitles(t *testing.T) {
+	dir, index := newTestLibrary(t)
+	defer os.RemoveAll(dir)
+	defer index.Close()
+
+	movies := filepath.Join(dir, "movies")
+	_, err := index.Refresh(movies)
+	require.NoError(t, err)
+
+	found, err := index.Find(movies)
+	require.NoError(t, err)
+
+	for _, v := range found.FilterVideo().List() {
+		subs, err := v.ExistingSubtitles()
+		require.NoError(t, err)
+		movie, ok := v.TypeMovie()
+		require.True(t, ok)
+		if movie.MovieName() == "Inception" {
+			assert.Equal(t, 1, subs.Len())
+		} else {
+			assert.Equal(t, 0, subs.Len())
+		}
+	}
+}
+
+func TestIndexFindStored(t *testing.T) {
+	dir, index := newTestLibrary(t)
+	defer os.RemoveAll(dir)
+	defer index.Close()
+
+	movies := filepath.Join(dir, "movies")
+	_, err := index.Refresh(movies)
+	require.NoError(t, err)
+
+	sub := filepath.Join(movies, "Inception (2010)", "Inception.2010.720p.BluRay.x264.en.srt")
+	entry, err := index.Entry(sub)
+	require.NoError(t, err)
+	require.NotNil(t, entry.Media)
+	assert.True(t, entry.Media.Subtitle)
+	assert.Equal(t, "en", entry.Media.Language)
+
+	// The stored media is used, rather than parsing the filenames again
+	src := filepath.Join(movies, "Alien.1979.1080p.WEB-DL.mp4")
+	require.NoError(t, index.update(src, func(e *Entry) {
+		e.Media.Name = "Alien Director's Cut"
+	}))
+
+	found, err := index.Find(movies)
+	require.NoError(t, err)
+	require.Equal(t, 3, found.Len())
+
+	var alien types.LocalMedia
+	for _, m := range found.List() {
+		if movie, ok := m.TypeMovie(); ok && movie.Year() == 1979 {
+			alien = m
+		}
+	}
+	require.NotNil(t, alien)
+	movie, _ := alien.TypeMovie()
+	assert.Equal(t, "Alien Director's Cut", movie.MovieName())
+	assert.Equal(t, quality.HD1080p, alien.Meta().Quality())
+	assert.Equal(t, source.WEBDL, alien.Meta().Source())
+}
+
+func TestIndexScraped(t *testing.T) {
+	dir, index := newTestLibrary(t)
+	defer os.RemoveAll(dir)
+	defer index.Close()
+
+	movies := filepath.Join(dir, "movies")
+	_, err := index.Refresh(movies)
+	require.NoError(t, err)
+
+	src := filepath.Join(movies, "Alien.1979.1080p.WEB-DL.mp4")
+	require.NoError(t, index.SetScraped(src, "tmdb", "348"))
+
+	dst := filepath.Join(dir, "renamed", "Alien (1979).mp4")
+	touch(t, dst)
+	require.NoError(t, index.CopyScraped(src, dst))
+
+	for _, path := range []string{src, dst} {
+		entry, err := index.Entry(path)
+		require.NoError(t, err)
+		require.NotNil(t, entry)
+		assert.Equal(t, map[string]string{"tmdb": "348"}, entry.Scraped)
+	}
+
+	// Scraped identities are kept when the file is parsed again
+	_, err = index.Rebuild(movies)
+	require.NoError(t, err)
+
+	entry, err := index.Entry(src)
+	require.NoError(t, err)
+	assert.Equal(t, "348", entry.Scraped["tmdb"])
+}
@@ -0,0 +1,312 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/provider"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// rpcMedia is the representation of media exchanged with external plugins
+type rpcMedia struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Year        int    `json:"year,omitempty"`
+	Season      int    `json:"season,omitempty"`
+	Episode     int    `json:"episode,omitempty"`
+	EpisodeName string `json:"episode_name,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Quality     string `json:"quality,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Codec       string `json:"codec,omitempty"`
+	Group       string `json:"group,omitempty"`
+}
+
+func newRPCMedia(m types.Media) (*rpcMedia, bool) {
+	r := &rpcMedia{
+		Quality: m.Meta().Quality().String(),
+		Source:  m.Meta().Source().String(),
+		Codec:   m.Meta().Codec().String(),
+		Group:   m.Meta().Group(),
+	}
+	if movie, ok := m.TypeMovie(); ok {
+		r.Type = "movie"
+		r.Name = movie.MovieName()
+		r.Year = movie.Year()
+	} else if episode, ok := m.TypeEpisode(); ok {
+		r.Type = "episode"
+		r.Name = episode.TVShow()
+		r.Season = episode.Season()
+		r.Episode = episode.Episode()
+		r.EpisodeName = episode.EpisodeName()
+	} else {
+		return nil, false
+	}
+	if p, ok := m.(types.Pather); ok {
+		r.Path = p.Path()
+	}
+	return r, true
+}
+
+// Media returns the media described by the plugin, using the release name
+// for metadata
+func (r *rpcMedia) Media(release string) (types.Media, error) {
+	switch r.Type {
+	case "movie":
+		return &media.Movie{
+			Metadata: media.ParseMetadata(release),
+			NameX:    r.Name,
+			YearX:    r.Year,
+		}, nil
+	case "episode":
+		return &media.Episode{
+			Metadata:     media.ParseMetadata(release),
+			NameX:        r.Name,
+			EpisodeNameX: r.EpisodeName,
+			SeasonX:      r.Season,
+			EpisodeX:     r.Episode,
+		}, nil
+	}
+	return nil, errors.New("plugin returned unknown media type")
+}
+
+// rpcSubtitle is a subtitle found by an external provider plugin
+type rpcSubtitle struct {
+	Link            string    `json:"link"`
+	Language        string    `json:"language"`
+	HearingImpaired bool      `json:"hearing_impaired"`
+	Release         string    `json:"release"`
+	Media           *rpcMedia `json:"media"`
+}
+
+// rpcDownload is a downloaded subtitle, either base64 encoded or stored in a
+// file, which is removed after reading if it is in the temporary directory of
+// the plugin
+type rpcDownload struct {
+	Data string `json:"data"`
+	Path string `json:"path"`
+}
+
+func (c *rpcClient) notSupported(err error) error {
+	if e, ok := err.(*rpcError); ok && e.Code == rpcNotSupported {
+		return provider.NewErrMediaNotSupported(c.name)
+	}
+	return err
+}
+
+// NewProvider returns a subtitle provider which is implemented by an external
+// executable speaking JSON-RPC on stdin/stdout
+func NewProvider(name string, exec string) types.Provider {
+	return &Provider{newRPCClient(name, exec)}
+}
+
+// Provider is a subtitle provider implemented by an external plugin
+type Provider struct {
+	client *rpcClient
+}
+
+// Name returns the name of the plugin
+func (p *Provider) Name() string {
+	return p.client.name
+}
+
+// SearchSubtitles asks the plugin to search for subtitles for the media
+func (p *Provider) SearchSubtitles(local types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	return p.SearchSubtitlesContext(context.Background(), local)
+}
+
+// SearchSubtitlesContext asks the plugin to search for subtitles for the media
+// until the context is cancelled
+func (p *Provider) SearchSubtitlesContext(ctx context.Context, local types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	m, ok := newRPCMedia(local)
+
+	if !ok {
+		return nil, provider.NewErrMediaNotSupported(p.Name())
+	}
+
+	var res []rpcSubtitle
+	if err := p.client.CallContext(ctx, "search", m, &res); err != nil {
+		return nil, p.client.notSupported(err)
+	}
+
+	subs := make([]types.OnlineSubtitle, 0, len(res))
+
+	for _, r := range res {
+		lang, err := language.Parse(r.Language)
+
+		if err != nil || r.Link == "" {
+			continue
+		}
+
+		desc := r.Media
+		if desc == nil {
+			desc = m
+		}
+
+		med, err := desc.Media(r.Release)
+
+		if err != nil {
+			return nil, err
+		}
+
+		subs = append(subs, &pluginSubtitle{
+			Media:      med,
+			pluginLink: pluginLink{p.client, r.Link},
+			lang:       lang,
+			hi:         r.HearingImpaired,
+		})
+	}
+
+	return subs, nil
+}
+
+// ResolveSubtitle asks the plugin to resolve the link of a subtitle
+func (p *Provider) ResolveSubtitle(l types.Linker) (types.Downloadable, error) {
+	var res struct {
+		Link string `json:"link"`
+	}
+
+	err := p.client.Call("resolve", struct {
+		Link string `json:"link"`
+	}{l.Link()}, &res)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Link == "" {
+		res.Link = l.Link()
+	}
+
+	return pluginLink{p.client, res.Link}, nil
+}
+
+// Close stops the plugin process
+func (p *Provider) Close() error {
+	return p.client.Close()
+}
+
+type pluginLink struct {
+	client *rpcClient
+	link   string
+}
+
+func (l pluginLink) Link() string {
+	return l.link
+}
+
+func (l pluginLink) Download() (io.ReadCloser, error) {
+	var res rpcDownload
+
+	err := l.client.Call("download", struct {
+		Link string `json:"link"`
+	}{l.link}, &res)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Path != "" {
+		return l.readFile(res.Path)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(res.Data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// readFile reads the subtitle downloaded to a file by the plugin. The file is
+// only removed if it is within the temporary directory of the plugin
+func (l pluginLink) readFile(path string) (io.ReadCloser, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if l.client.temporary(path) {
+		os.Remove(path)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+type pluginSubtitle struct {
+	types.Media
+	pluginLink
+	lang language.Tag
+	hi   bool
+}
+
+func (s *pluginSubtitle) String() string {
+	return display.English.Languages().Name(s.Language())
+}
+
+func (s *pluginSubtitle) ForMedia() types.Media {
+	return s.Media
+}
+
+func (s *pluginSubtitle) Language() language.Tag {
+	return s.lang
+}
+
+func (s *pluginSubtitle) HearingImpaired() bool {
+	return s.hi
+}
+
+// NewScraper returns a media scraper which is implemented by an external
+// executable speaking JSON-RPC on stdin/stdout
+func NewScraper(name string, exec string) types.Scraper {
+	return &Scraper{newRPCClient(name, exec)}
+}
+
+// Scraper is a media scraper implemented by an external plugin
+type Scraper struct {
+	client *rpcClient
+}
+
+// Name returns the name of the plugin
+func (s *Scraper) Name() string {
+	return s.client.name
+}
+
+// Scrape asks the plugin to scrape metadata for the media
+func (s *Scraper) Scrape(m types.Media) (types.Media, error) {
+	return s.ScrapeContext(context.Background(), m)
+}
+
+// ScrapeContext asks the plugin to scrape metadata for the media until the
+// context is cancelled
+func (s *Scraper) ScrapeContext(ctx context.Context, m types.Media) (types.Media, error) {
+	if sub, ok := m.TypeSubtitle(); ok {
+		return s.ScrapeContext(ctx, sub.ForMedia())
+	}
+
+	req, ok := newRPCMedia(m)
+
+	if !ok {
+		return nil, provider.NewErrMediaNotSupported(s.Name())
+	}
+
+	var res rpcMedia
+	if err := s.client.CallContext(ctx, "scrape", req, &res); err != nil {
+		return nil, s.client.notSupported(err)
+	}
+
+	return res.Media("")
+}
+
+// Close stops the plugin process
+func (s *Scraper) Close() error {
+	return s.client.Close()
+}
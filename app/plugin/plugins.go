@@ -30,8 +30,16 @@ func NewFromMap(i interface{}) (*Plugin, error) {
 type Plugin struct {
 	PluginName string `mapstructure:"name"`
 	Exec       string `mapstructure:"exec"`
+	Type       string `mapstructure:"type"`
 }
 
+// Plugin types for external providers and scrapers. Plugins without a type
+// are run after a subtitle has been downloaded
+const (
+	TypeProvider = "provider"
+	TypeScraper  = "scraper"
+)
+
 // Run executes the plugin
 func (p *Plugin) Run(s types.LocalSubtitle) error {
 	cmd := exec.Command(shell[0], shell[1], p.Exec)
@@ -52,6 +60,9 @@ func (p *Plugin) valid() error {
 	if p.Exec == "" {
 		return fmt.Errorf("Missing plugin exec for %v", p.Name())
 	}
+	if p.Type != "" && p.Type != TypeProvider && p.Type != TypeScraper {
+		return fmt.Errorf("Unknown plugin type %v for %v", p.Type, p.Name())
+	}
 	return nil
 }
 
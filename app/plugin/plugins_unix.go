@@ -2,4 +2,20 @@
 
 package plugin
 
+import (
+	"os/exec"
+	"syscall"
+)
+
 var shell = []string{"sh", "-c"}
+
+// setProcessGroup starts the command in a process group of its own, such that
+// processes started by the command are killed along with it
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcess kills the process group of the command
+func killProcess(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -2,4 +2,14 @@
 
 package plugin
 
+import "os/exec"
+
 var shell = []string{"cmd.exe", "/C"}
+
+// setProcessGroup does nothing on windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcess kills the process of the command
+func killProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
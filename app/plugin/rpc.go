@@ -0,0 +1,359 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+)
+
+// rpcVersion is the JSON-RPC version spoken with external plugins
+const rpcVersion = "2.0"
+
+// rpcNotSupported is the error code returned by plugins for unsupported media
+const rpcNotSupported = -32001
+
+// rpcMaxRestarts is the number of times a plugin may be restarted within
+// rpcRestartWindow before it is considered broken
+const rpcMaxRestarts = 5
+
+// rpcRestartWindow is the duration in which plugin restarts are counted
+const rpcRestartWindow = time.Minute
+
+// rpcTimeout is the time a plugin has to respond to a call before it is
+// considered hung and killed
+var rpcTimeout = 2 * time.Minute
+
+type rpcRequest struct {
+	Version string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+}
+
+// rpcError is an error returned by an external plugin
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// rpcClient supervises an external plugin process and performs JSON-RPC calls
+// on its stdin/stdout, one line per message. The process is started on the
+// first call and restarted if it exits, until the client is closed. The
+// plugin is given its own temporary directory, which is removed when the
+// client is closed
+type rpcClient struct {
+	name string
+	exec string
+
+	sem       chan struct{}
+	closing   chan struct{}
+	closeOnce sync.Once
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	stdout    io.Closer
+	lines     chan []byte
+	quit      chan struct{}
+	done      chan struct{}
+	tmpdir    string
+	id        int
+	cancelled int
+	restarts  []time.Time
+}
+
+func newRPCClient(name string, exec string) *rpcClient {
+	return &rpcClient{
+		name:    name,
+		exec:    exec,
+		sem:     make(chan struct{}, 1),
+		closing: make(chan struct{}),
+	}
+}
+
+func (c *rpcClient) alive() bool {
+	if c.cmd == nil {
+		return false
+	}
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// start starts the plugin process, unless it has been restarted too many
+// times recently
+func (c *rpcClient) start() error {
+	now := time.Now()
+	var recent []time.Time
+	for _, t := range c.restarts {
+		if now.Sub(t) < rpcRestartWindow {
+			recent = append(recent, t)
+		}
+	}
+	c.restarts = recent
+
+	if len(c.restarts) >= rpcMaxRestarts {
+		return fmt.Errorf("plugin %v crashed too many times", c.name)
+	}
+
+	if c.cmd != nil {
+		c.restarts = append(c.restarts, now)
+		log.WithField("plugin", c.name).Warn("Restarting plugin")
+	}
+
+	if c.tmpdir == "" {
+		dir, err := ioutil.TempDir("", "supper-plugin-")
+		if err != nil {
+			return err
+		}
+		if dir, err = filepath.EvalSymlinks(dir); err != nil {
+			return err
+		}
+		c.tmpdir = dir
+	}
+
+	cmd := exec.Command(shell[0], shell[1], c.exec)
+	cmd.Env = append(os.Environ(), "TMPDIR="+c.tmpdir, "TMP="+c.tmpdir, "TEMP="+c.tmpdir)
+	setProcessGroup(cmd)
+
+	stdin, err := cmd.StdinPipe()
+
+	if err != nil {
+		return err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+
+	if err != nil {
+		return err
+	}
+
+	stderr, err := cmd.StderrPipe()
+
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	lines := make(chan []byte)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+
+	var readers sync.WaitGroup
+	readers.Add(2)
+
+	go func() {
+		defer readers.Done()
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.WithField("plugin", c.name).Debug(scanner.Text())
+		}
+	}()
+
+	go func() {
+		defer readers.Done()
+		defer close(lines)
+		r := bufio.NewReader(stdout)
+		for {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			select {
+			case lines <- line:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	go func() {
+		// The pipes must be read to the end before waiting for the process
+		readers.Wait()
+		err := cmd.Wait()
+		close(done)
+		log.WithError(err).WithField("plugin", c.name).Debug("Plugin exited")
+	}()
+
+	c.cmd = cmd
+	c.stdin = stdin
+	c.stdout = stdout
+	c.lines = lines
+	c.quit = quit
+	c.done = done
+	c.cancelled = 0
+
+	return nil
+}
+
+// kill stops the plugin process such that it is restarted on the next call
+func (c *rpcClient) kill() {
+	if c.alive() {
+		close(c.quit)
+		c.stdin.Close()
+		killProcess(c.cmd)
+		c.stdout.Close()
+		<-c.done
+	}
+}
+
+// Call performs a remote procedure call and decodes the result into v. If the
+// plugin process has exited it is restarted before the call is made
+func (c *rpcClient) Call(method string, params interface{}, v interface{}) error {
+	return c.CallContext(context.Background(), method, params, v)
+}
+
+// CallContext performs a remote procedure call until the context is
+// cancelled. The response of a cancelled call is discarded by the next call
+func (c *rpcClient) CallContext(ctx context.Context, method string, params interface{}, v interface{}) error {
+	if err := c.lock(ctx); err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	select {
+	case <-c.closing:
+		return fmt.Errorf("plugin %v: closed", c.name)
+	default:
+	}
+
+	if !c.alive() {
+		if err := c.start(); err != nil {
+			return err
+		}
+	}
+
+	c.id++
+
+	req, err := json.Marshal(rpcRequest{rpcVersion, c.id, method, params})
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.stdin.Write(append(req, '\n')); err != nil {
+		c.kill()
+		return fmt.Errorf("plugin %v: %v", c.name, err)
+	}
+
+	timer := time.NewTimer(rpcTimeout)
+	defer timer.Stop()
+
+	for {
+		var line []byte
+		select {
+		case l, ok := <-c.lines:
+			if !ok {
+				c.kill()
+				return fmt.Errorf("plugin %v: plugin exited unexpectedly", c.name)
+			}
+			line = l
+		case <-timer.C:
+			c.kill()
+			return fmt.Errorf("plugin %v: no response within %v", c.name, rpcTimeout)
+		case <-ctx.Done():
+			c.cancelled++
+			return ctx.Err()
+		case <-c.closing:
+			c.kill()
+			return fmt.Errorf("plugin %v: closed", c.name)
+		}
+
+		var resp rpcResponse
+		if err := json.Unmarshal(line, &resp); err != nil {
+			c.kill()
+			return fmt.Errorf("plugin %v: invalid response: %v", c.name, err)
+		}
+
+		if c.cancelled > 0 && resp.ID < c.id {
+			c.cancelled--
+			continue
+		}
+
+		if resp.ID != c.id {
+			c.kill()
+			return fmt.Errorf("plugin %v: unexpected response id %v", c.name, resp.ID)
+		}
+
+		if resp.Error != nil {
+			return resp.Error
+		}
+
+		if v == nil {
+			return nil
+		}
+
+		return json.Unmarshal(resp.Result, v)
+	}
+}
+
+// lock waits for any other call to the plugin to finish, unless the context
+// is cancelled first
+func (c *rpcClient) lock(ctx context.Context) error {
+	select {
+	case c.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *rpcClient) unlock() {
+	<-c.sem
+}
+
+// temporary returns true if the path is within the temporary directory of
+// the plugin, such that it is safe to remove
+func (c *rpcClient) temporary(path string) bool {
+	c.lock(context.Background())
+	defer c.unlock()
+	if c.tmpdir == "" || !filepath.IsAbs(path) {
+		return false
+	}
+	rel, err := filepath.Rel(c.tmpdir, filepath.Clean(path))
+	if err != nil || rel == "." || rel == ".." {
+		return false
+	}
+	return !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Close stops the plugin process and removes its temporary directory. Calls
+// in progress are stopped, and the plugin is never started again
+func (c *rpcClient) Close() error {
+	c.closeOnce.Do(func() { close(c.closing) })
+	c.lock(context.Background())
+	defer c.unlock()
+	c.kill()
+	if c.tmpdir == "" {
+		return nil
+	}
+	err := os.RemoveAll(c.tmpdir)
+	c.tmpdir = ""
+	return err
+}
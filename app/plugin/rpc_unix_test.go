@@ -0,0 +1,379 @@
+// +build !windows
+
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/provider"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+const helperEnv = "SUPPER_TEST_PLUGIN_HELPER"
+
+// helperExec returns the command which runs the test binary as a plugin
+func helperExec() string {
+	return fmt.Sprintf("%s=1 %s -test.run=TestHelperPlugin", helperEnv, os.Args[0])
+}
+
+// TestHelperPlugin is not a real test, but the plugin process used by the
+// other tests when the test binary is executed as a plugin
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv(helperEnv) != "1" {
+		return
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	out := json.NewEncoder(os.Stdout)
+
+	for in.Scan() {
+		var req struct {
+			ID     int             `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil {
+			os.Exit(2)
+		}
+
+		var result interface{}
+		var rpcErr *rpcError
+
+		switch req.Method {
+		case "search":
+			var m rpcMedia
+			json.Unmarshal(req.Params, &m)
+			if m.Name == "Crash" {
+				os.Exit(3)
+			}
+			if m.Type != "movie" {
+				rpcErr = &rpcError{rpcNotSupported, "only movies"}
+				break
+			}
+			result = []rpcSubtitle{
+				{Link: "a", Language: "en", Release: "Inception.2010.720p.BluRay"},
+				{Link: "b", Language: "da", HearingImpaired: true, Release: "1080p",
+					Media: &rpcMedia{Type: "movie", Name: "Inception", Year: 2010}},
+				{Link: "", Language: "en"},
+			}
+		case "resolve":
+			var p struct{ Link string }
+			json.Unmarshal(req.Params, &p)
+			if p.Link == "bad" {
+				rpcErr = &rpcError{-32000, "unknown link"}
+				break
+			}
+			result = map[string]string{"link": p.Link}
+		case "download":
+			var p struct{ Link string }
+			json.Unmarshal(req.Params, &p)
+			if filepath.IsAbs(p.Link) {
+				result = rpcDownload{Path: p.Link}
+				break
+			}
+			if p.Link == "b" {
+				f, _ := ioutil.TempFile("", "supper")
+				f.WriteString("from file")
+				f.Close()
+				result = rpcDownload{Path: f.Name()}
+				break
+			}
+			result = rpcDownload{Data: base64.StdEncoding.EncodeToString([]byte("from data"))}
+		case "scrape":
+			var m rpcMedia
+			json.Unmarshal(req.Params, &m)
+			m.Name = strings.ToUpper(m.Name)
+			result = m
+		case "pid":
+			result = os.Getpid()
+		case "hang":
+			time.Sleep(time.Hour)
+		case "slow":
+			time.Sleep(300 * time.Millisecond)
+			result = "slow"
+		default:
+			rpcErr = &rpcError{-32601, "method not found"}
+		}
+
+		out.Encode(struct {
+			Version string      `json:"jsonrpc"`
+			ID      int         `json:"id"`
+			Result  interface{} `json:"result,omitempty"`
+			Error   *rpcError   `json:"error,omitempty"`
+		}{rpcVersion, req.ID, result, rpcErr})
+	}
+	os.Exit(0)
+}
+
+type localMedia struct {
+	types.Media
+}
+
+func (localMedia) Name() string       { return "media" }
+func (localMedia) Size() int64        { return 0 }
+func (localMedia) Mode() os.FileMode  { return 0 }
+func (localMedia) ModTime() time.Time { return time.Time{} }
+func (localMedia) IsDir() bool        { return false }
+func (localMedia) Sys() interface{}   { return nil }
+func (localMedia) Path() string       { return "/media/path" }
+
+type testLink string
+
+func (l testLink) Link() string {
+	return string(l)
+}
+
+func TestExternalProvider(t *testing.T) {
+	p := NewProvider("helper", helperExec())
+	defer p.(*Provider).Close()
+
+	assert.Equal(t, "helper", p.Name())
+
+	movie, err := media.NewMovie("Inception.2010.720p")
+	require.NoError(t, err)
+
+	subs, err := p.SearchSubtitles(localMedia{movie})
+	require.NoError(t, err)
+	require.Len(t, subs, 2)
+
+	assert.Equal(t, "a", subs[0].Link())
+	assert.Equal(t, language.English, subs[0].Language())
+	assert.False(t, subs[0].HearingImpaired())
+
+	m, ok := subs[0].ForMedia().TypeMovie()
+	require.True(t, ok)
+	assert.Equal(t, "Inception", m.MovieName())
+	assert.Equal(t, "BluRay", subs[0].ForMedia().Meta().Source().String())
+
+	assert.Equal(t, language.Danish, subs[1].Language())
+	assert.True(t, subs[1].HearingImpaired())
+
+	for link, expected := range map[string]string{"a": "from data", "b": "from file"} {
+		dl, err := p.ResolveSubtitle(testLink(link))
+		require.NoError(t, err)
+
+		r, err := dl.Download()
+		require.NoError(t, err)
+
+		data, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		assert.Equal(t, expected, string(data))
+	}
+
+	tmp, err := ioutil.ReadDir(p.(*Provider).client.tmpdir)
+	require.NoError(t, err)
+	assert.Empty(t, tmp)
+
+	_, err = p.ResolveSubtitle(testLink("bad"))
+	assert.Error(t, err)
+}
+
+func TestExternalProviderKeepsFile(t *testing.T) {
+	p := NewProvider("helper", helperExec()).(*Provider)
+
+	f, err := ioutil.TempFile("", "supper-keep")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("not temporary")
+	f.Close()
+
+	dl, err := p.ResolveSubtitle(testLink(f.Name()))
+	require.NoError(t, err)
+
+	r, err := dl.Download()
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "not temporary", string(data))
+
+	// Only files in the temporary directory of the plugin are removed
+	assert.FileExists(t, f.Name())
+
+	tmpdir := p.client.tmpdir
+	require.NoError(t, p.Close())
+	_, err = os.Stat(tmpdir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestExternalProviderTimeout(t *testing.T) {
+	p := NewProvider("helper", helperExec()).(*Provider)
+	defer p.Close()
+
+	timeout := rpcTimeout
+	rpcTimeout = 500 * time.Millisecond
+	defer func() { rpcTimeout = timeout }()
+
+	var pid int
+	require.NoError(t, p.client.Call("pid", nil, &pid))
+
+	err := p.client.Call("hang", nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no response")
+
+	var restarted int
+	require.NoError(t, p.client.Call("pid", nil, &restarted))
+	assert.NotEqual(t, pid, restarted)
+}
+
+// exited returns true if the process has exited. Processes which have exited,
+// but are yet to be reaped by their parent, have exited as well
+func exited(pid int) bool {
+	if syscall.Kill(pid, 0) == syscall.ESRCH {
+		return true
+	}
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
+
+func TestExternalProviderClose(t *testing.T) {
+	p := NewProvider("helper", helperExec()).(*Provider)
+
+	var pid int
+	require.NoError(t, p.client.Call("pid", nil, &pid))
+
+	// Closing stops calls in progress
+	busy := make(chan error)
+	go func() { busy <- p.client.Call("hang", nil, nil) }()
+	time.Sleep(50 * time.Millisecond)
+
+	require.NoError(t, p.Close())
+
+	select {
+	case err := <-busy:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("call was not stopped by close")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !exited(pid) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, exited(pid), "plugin process is still running")
+
+	// The plugin is not started again once closed
+	err := p.client.Call("pid", nil, &pid)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "closed")
+	assert.Nil(t, p.client.restarts)
+	assert.NoError(t, p.Close())
+}
+
+func TestExternalProviderNotSupported(t *testing.T) {
+	p := NewProvider("helper", helperExec())
+	defer p.(*Provider).Close()
+
+	episode, err := media.NewEpisode("Game.of.Thrones.S02E05")
+	require.NoError(t, err)
+
+	_, err = p.SearchSubtitles(localMedia{episode})
+	require.Error(t, err)
+	assert.True(t, provider.IsErrMediaNotSupported(err))
+}
+
+func TestExternalProviderRestart(t *testing.T) {
+	p := NewProvider("helper", helperExec()).(*Provider)
+	defer p.Close()
+
+	var pid int
+	require.NoError(t, p.client.Call("pid", nil, &pid))
+
+	crash, err := media.NewMovie("Crash.2004")
+	require.NoError(t, err)
+
+	_, err = p.SearchSubtitles(localMedia{crash})
+	require.Error(t, err)
+
+	var restarted int
+	require.NoError(t, p.client.Call("pid", nil, &restarted))
+	assert.NotEqual(t, pid, restarted)
+
+	for i := 0; i < rpcMaxRestarts; i++ {
+		p.SearchSubtitles(localMedia{crash})
+	}
+
+	err = p.client.Call("pid", nil, &restarted)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "too many times")
+}
+
+func TestExternalProviderCancel(t *testing.T) {
+	p := NewProvider("helper", helperExec()).(*Provider)
+	defer p.Close()
+
+	var _ types.ContextProvider = p
+
+	var pid int
+	require.NoError(t, p.client.Call("pid", nil, &pid))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := p.client.CallContext(ctx, "slow", nil, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 300*time.Millisecond)
+
+	// The response of the cancelled call is discarded without a restart
+	var same int
+	require.NoError(t, p.client.Call("pid", nil, &same))
+	assert.Equal(t, pid, same)
+
+	// Calls waiting for another call stop when cancelled
+	busy := make(chan error)
+	go func() { busy <- p.client.Call("slow", nil, nil) }()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	movie, err := media.NewMovie("Inception.2010.720p")
+	require.NoError(t, err)
+
+	start = time.Now()
+	_, err = p.SearchSubtitlesContext(ctx, localMedia{movie})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 250*time.Millisecond)
+	assert.NoError(t, <-busy)
+}
+
+func TestExternalScraper(t *testing.T) {
+	s := NewScraper("helper", helperExec())
+	defer s.(*Scraper).Close()
+
+	var _ types.ContextScraper = s.(*Scraper)
+
+	movie, err := media.NewMovie("Inception.2010.720p")
+	require.NoError(t, err)
+
+	scraped, err := s.Scrape(movie)
+	require.NoError(t, err)
+
+	m, ok := scraped.TypeMovie()
+	require.True(t, ok)
+	assert.Equal(t, "INCEPTION", m.MovieName())
+	assert.Equal(t, 2010, m.Year())
+}
@@ -0,0 +1,221 @@
+package wanted
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// Item is a video which is missing subtitles in a language
+type Item struct {
+	Path     string    `json:"path"`
+	Lang     string    `json:"lang"`
+	Added    time.Time `json:"added"`
+	Attempts int       `json:"attempts"`
+	Next     time.Time `json:"next"`
+}
+
+// Language returns the language of the missing subtitle
+func (i Item) Language() language.Tag {
+	return language.Make(i.Lang)
+}
+
+type key struct {
+	path string
+	lang string
+}
+
+// List keeps track of subtitles which are missing, and when to search for
+// them again. Searches are retried on a decaying schedule, until the subtitle
+// is found or the item has been wanted for longer than the maximum age. The
+// list is persisted to a file, unless no file is given. A list is safe for
+// concurrent use
+type List struct {
+	mu       sync.Mutex
+	path     string
+	schedule []time.Duration
+	maxAge   time.Duration
+	items    map[key]*Item
+}
+
+// Open reads the list from the file at the path. An empty list is returned if
+// the file does not exist. The schedule is the delay before each retry, where
+// the last delay is used for any further retries
+func Open(path string, schedule []time.Duration, maxAge time.Duration) (*List, error) {
+	l := &List{
+		path:     path,
+		schedule: schedule,
+		maxAge:   maxAge,
+		items:    make(map[key]*Item),
+	}
+
+	if path == "" {
+		return l, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*Item
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	for _, i := range items {
+		l.items[key{i.Path, i.Lang}] = i
+	}
+
+	return l, nil
+}
+
+// Want records that the video at the path is missing a subtitle in the
+// language. If the subtitle is already wanted the attempt is counted, and the
+// next search is scheduled further into the future
+func (l *List) Want(path string, lang language.Tag) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	k := key{abs, lang.String()}
+
+	item, ok := l.items[k]
+	if !ok {
+		item = &Item{
+			Path:  abs,
+			Lang:  lang.String(),
+			Added: now,
+		}
+		l.items[k] = item
+	} else {
+		item.Attempts++
+	}
+	item.Next = now.Add(l.delay(item.Attempts))
+
+	return l.save()
+}
+
+// Found removes the subtitle in the language for the video at the path from
+// the list
+func (l *List) Found(path string, lang language.Tag) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	k := key{abs, lang.String()}
+	if _, ok := l.items[k]; !ok {
+		return nil
+	}
+	delete(l.items, k)
+
+	return l.save()
+}
+
+// Due returns the items which should be searched for again. Items which have
+// been wanted for longer than the maximum age are removed
+func (l *List) Due(now time.Time) ([]Item, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var due []Item
+	var expired int
+	for k, i := range l.items {
+		if l.maxAge > 0 && now.Sub(i.Added) > l.maxAge {
+			delete(l.items, k)
+			expired++
+			continue
+		}
+		if !i.Next.After(now) {
+			due = append(due, *i)
+		}
+	}
+	sortItems(due)
+
+	if expired > 0 {
+		return due, l.save()
+	}
+	return due, nil
+}
+
+// Items returns every wanted item, with the items to search for first at the
+// beginning
+func (l *List) Items() []Item {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	items := make([]Item, 0, len(l.items))
+	for _, i := range l.items {
+		items = append(items, *i)
+	}
+	sortItems(items)
+	return items
+}
+
+// delay returns the duration to wait before the next search, after the given
+// number of failed attempts
+func (l *List) delay(attempts int) time.Duration {
+	if len(l.schedule) == 0 {
+		return 0
+	}
+	if attempts >= len(l.schedule) {
+		return l.schedule[len(l.schedule)-1]
+	}
+	return l.schedule[attempts]
+}
+
+// save writes the list to disk. The list must be locked
+func (l *List) save() error {
+	if l.path == "" {
+		return nil
+	}
+
+	items := make([]*Item, 0, len(l.items))
+	for _, i := range l.items {
+		items = append(items, i)
+	}
+
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp := l.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
+
+func sortItems(items []Item) {
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Next.Equal(items[j].Next) {
+			return items[i].Next.Before(items[j].Next)
+		}
+		if items[i].Path != items[j].Path {
+			return items[i].Path < items[j].Path
+		}
+		return items[i].Lang < items[j].Lang
+	})
+}
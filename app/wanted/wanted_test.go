@@ -0,0 +1,92 @@
+package wanted
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+var testSchedule = []time.Duration{time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+func TestWantedSchedule(t *testing.T) {
+	l, err := Open("", testSchedule, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, l.Want("/media/movie.mkv", language.English))
+	require.NoError(t, l.Want("/media/movie.mkv", language.German))
+
+	items := l.Items()
+	require.Len(t, items, 2)
+	assert.Equal(t, "en", items[0].Lang)
+	assert.Equal(t, language.English, items[0].Language())
+	assert.Equal(t, 0, items[0].Attempts)
+
+	due, err := l.Due(time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	due, err = l.Due(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, due, 2)
+
+	// Each failed attempt waits longer, until the end of the schedule
+	for _, d := range []time.Duration{6 * time.Hour, 24 * time.Hour, 24 * time.Hour} {
+		require.NoError(t, l.Want("/media/movie.mkv", language.English))
+		items = l.Items()
+		require.Len(t, items, 2)
+		assert.Equal(t, "en", items[1].Lang)
+		assert.WithinDuration(t, time.Now().Add(d), items[1].Next, time.Minute)
+	}
+	assert.Equal(t, 3, items[1].Attempts)
+
+	require.NoError(t, l.Found("/media/movie.mkv", language.English))
+	require.NoError(t, l.Found("/media/movie.mkv", language.Spanish))
+
+	items = l.Items()
+	require.Len(t, items, 1)
+	assert.Equal(t, "de", items[0].Lang)
+}
+
+func TestWantedMaxAge(t *testing.T) {
+	l, err := Open("", testSchedule, 48*time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, l.Want("/media/movie.mkv", language.English))
+
+	due, err := l.Due(time.Now().Add(47 * time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, due, 1)
+
+	due, err = l.Due(time.Now().Add(49 * time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+	assert.Empty(t, l.Items())
+}
+
+func TestWantedPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "wanted", "wanted.json")
+
+	l, err := Open(path, testSchedule, 0)
+	require.NoError(t, err)
+	require.NoError(t, l.Want("/media/movie.mkv", language.English))
+	require.NoError(t, l.Want("/media/show.mkv", language.German))
+	require.NoError(t, l.Found("/media/show.mkv", language.German))
+
+	l, err = Open(path, testSchedule, 0)
+	require.NoError(t, err)
+
+	items := l.Items()
+	require.Len(t, items, 1)
+	assert.Equal(t, "/media/movie.mkv", items[0].Path)
+	assert.False(t, items[0].Next.IsZero())
+}
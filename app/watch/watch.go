@@ -0,0 +1,179 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/fsnotify/fsnotify"
+)
+
+// maxInterval is the longest duration between checking whether files have
+// become stable
+const maxInterval = time.Second
+
+// pending is a file which is still being written
+type pending struct {
+	size    int64
+	modtime time.Time
+	since   time.Time
+}
+
+// Watch watches the directories and their subdirectories for new files. The
+// function is called for each new file once its size and modification time
+// has not changed for the stable duration, such that files which are still
+// being downloaded or copied are not processed. The function is called from
+// the watching goroutine and should return quickly. Watching stops when the
+// context is cancelled, and the error of the context is returned
+func Watch(ctx context.Context, dirs []string, stable time.Duration, fn func(string)) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fsw.Close()
+
+	w := &watcher{
+		fsw:    fsw,
+		stable: stable,
+		files:  make(map[string]*pending),
+	}
+
+	for _, dir := range dirs {
+		if err := w.add(dir, false); err != nil {
+			return err
+		}
+	}
+
+	interval := stable / 4
+	if interval > maxInterval || interval <= 0 {
+		interval = maxInterval
+	}
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			w.handle(e)
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			log.WithError(err).Warn("Watch error")
+		case now := <-tick.C:
+			for _, path := range w.settled(now) {
+				fn(path)
+			}
+		}
+	}
+}
+
+type watcher struct {
+	fsw    *fsnotify.Watcher
+	stable time.Duration
+	files  map[string]*pending
+}
+
+// add watches the directory and its subdirectories. Files in the directories
+// are tracked as new files if track is true, i.e. when a directory has been
+// moved into a watched directory
+func (w *watcher) add(root string, track bool) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && hidden(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return w.fsw.Add(path)
+		}
+		if track {
+			w.track(path)
+		}
+		return nil
+	})
+}
+
+func (w *watcher) handle(e fsnotify.Event) {
+	if hidden(e.Name) {
+		return
+	}
+
+	if e.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		delete(w.files, e.Name)
+		return
+	}
+
+	if e.Op&fsnotify.Create != 0 {
+		info, err := os.Stat(e.Name)
+		if err != nil {
+			return
+		}
+		if info.IsDir() {
+			if err := w.add(e.Name, true); err != nil {
+				log.WithError(err).WithField("path", e.Name).Warn("Could not watch directory")
+			}
+			return
+		}
+	}
+
+	if e.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+		w.track(e.Name)
+	}
+}
+
+// track starts waiting for the file to become stable, or restarts the wait if
+// the file is already tracked
+func (w *watcher) track(path string) {
+	if p, ok := w.files[path]; ok {
+		p.since = time.Now()
+		return
+	}
+	w.files[path] = &pending{
+		size:  -1,
+		since: time.Now(),
+	}
+}
+
+// settled returns the tracked files which have not changed for the stable
+// duration, and stops tracking them
+func (w *watcher) settled(now time.Time) []string {
+	var settled []string
+	for path, p := range w.files {
+		info, err := os.Stat(path)
+		if err != nil {
+			delete(w.files, path)
+			continue
+		}
+		if info.Size() != p.size || !info.ModTime().Equal(p.modtime) {
+			p.size = info.Size()
+			p.modtime = info.ModTime()
+			p.since = now
+			continue
+		}
+		if now.Sub(p.since) >= w.stable {
+			delete(w.files, path)
+			settled = append(settled, path)
+		}
+	}
+	sort.Strings(settled)
+	return settled
+}
+
+func hidden(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".")
+}
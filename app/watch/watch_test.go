@@ -0,0 +1,116 @@
+package watch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStable = 200 * time.Millisecond
+
+// startWatch watches the directory and returns a channel of the stable files
+func startWatch(t *testing.T, dir string) (<-chan string, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	files := make(chan string, 10)
+
+	go Watch(ctx, []string{dir}, testStable, func(path string) {
+		files <- path
+	})
+
+	// Give the watcher time to register the directories
+	time.Sleep(50 * time.Millisecond)
+
+	return files, cancel
+}
+
+func expectFile(t *testing.T, files <-chan string) string {
+	select {
+	case path := <-files:
+		return path
+	case <-time.After(5 * time.Second):
+		t.Fatal("no stable file detected")
+	}
+	return ""
+}
+
+func TestWatchStable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files, cancel := startWatch(t, dir)
+	defer cancel()
+
+	path := filepath.Join(dir, "Inception.2010.720p.mkv")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+
+	// Keep writing to the file, which must not be reported until it is stable
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err := f.Write([]byte("data"))
+		require.NoError(t, err)
+		time.Sleep(testStable / 2)
+	}
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, path, expectFile(t, files))
+	assert.True(t, time.Since(start) > 5*testStable/2)
+
+	select {
+	case path := <-files:
+		t.Fatalf("file %v reported twice", path)
+	case <-time.After(2 * testStable):
+	}
+}
+
+func TestWatchDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	src, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(src)
+
+	files, cancel := startWatch(t, dir)
+	defer cancel()
+
+	// A directory moved into the watched directory is processed as a whole
+	movie := filepath.Join(src, "Inception (2010)")
+	require.NoError(t, os.Mkdir(movie, os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(movie, "Inception.2010.720p.mkv"), []byte("data"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(movie, ".hidden.mkv"), []byte("data"), 0644))
+
+	dest := filepath.Join(dir, "Inception (2010)")
+	require.NoError(t, os.Rename(movie, dest))
+
+	assert.Equal(t, filepath.Join(dest, "Inception.2010.720p.mkv"), expectFile(t, files))
+
+	// New files in the new directory are watched as well
+	episode := filepath.Join(dest, "Game.of.Thrones.S01E01.mkv")
+	require.NoError(t, ioutil.WriteFile(episode, []byte("data"), 0644))
+
+	assert.Equal(t, episode, expectFile(t, files))
+}
+
+func TestWatchCancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = Watch(ctx, []string{dir}, testStable, func(string) {})
+	assert.Equal(t, context.Canceled, err)
+
+	err = Watch(context.Background(), []string{filepath.Join(dir, "missing")}, testStable, func(string) {})
+	assert.Error(t, err)
+}
@@ -0,0 +1,284 @@
+// Package charset detects the character encoding of subtitles, and converts
+// subtitles to and from UTF-8
+package charset
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	utf "golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/language"
+)
+
+// UTF8 is the encoding subtitles are normalized to by default
+var UTF8 = utf.UTF8
+
+// charsets are legacy encodings used for texts in a script
+type charsets struct {
+	encodings []encoding.Encoding
+	scripts   []*unicode.RangeTable
+}
+
+// and returns the encodings and scripts of both charsets
+func (c charsets) and(o charsets) charsets {
+	return charsets{
+		encodings: append(append([]encoding.Encoding{}, c.encodings...), o.encodings...),
+		scripts:   append(append([]*unicode.RangeTable{}, c.scripts...), o.scripts...),
+	}
+}
+
+var (
+	western = charsets{
+		[]encoding.Encoding{charmap.Windows1252, charmap.ISO8859_15},
+		[]*unicode.RangeTable{unicode.Latin},
+	}
+	central = charsets{
+		[]encoding.Encoding{charmap.Windows1250, charmap.ISO8859_2},
+		[]*unicode.RangeTable{unicode.Latin},
+	}
+	romanian = charsets{
+		[]encoding.Encoding{charmap.Windows1250, charmap.ISO8859_16, charmap.ISO8859_2},
+		[]*unicode.RangeTable{unicode.Latin},
+	}
+	cyrillic = charsets{
+		[]encoding.Encoding{charmap.Windows1251, charmap.KOI8R, charmap.KOI8U, charmap.ISO8859_5},
+		[]*unicode.RangeTable{unicode.Cyrillic},
+	}
+	baltic = charsets{
+		[]encoding.Encoding{charmap.Windows1257, charmap.ISO8859_13, charmap.ISO8859_4},
+		[]*unicode.RangeTable{unicode.Latin},
+	}
+	turkish = charsets{
+		[]encoding.Encoding{charmap.Windows1254, charmap.ISO8859_9},
+		[]*unicode.RangeTable{unicode.Latin},
+	}
+	greek = charsets{
+		[]encoding.Encoding{charmap.Windows1253, charmap.ISO8859_7},
+		[]*unicode.RangeTable{unicode.Greek},
+	}
+	hebrew = charsets{
+		[]encoding.Encoding{charmap.Windows1255, charmap.ISO8859_8},
+		[]*unicode.RangeTable{unicode.Hebrew},
+	}
+	arabic = charsets{
+		[]encoding.Encoding{charmap.Windows1256, charmap.ISO8859_6},
+		[]*unicode.RangeTable{unicode.Arabic},
+	}
+	vietnamese = charsets{
+		[]encoding.Encoding{charmap.Windows1258},
+		[]*unicode.RangeTable{unicode.Latin},
+	}
+	thai = charsets{
+		[]encoding.Encoding{charmap.Windows874},
+		[]*unicode.RangeTable{unicode.Thai},
+	}
+	chinese = charsets{
+		[]encoding.Encoding{simplifiedchinese.GB18030, traditionalchinese.Big5},
+		[]*unicode.RangeTable{unicode.Han},
+	}
+	japan = charsets{
+		[]encoding.Encoding{japanese.ShiftJIS, japanese.EUCJP},
+		[]*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana},
+	}
+	korea = charsets{
+		[]encoding.Encoding{korean.EUCKR},
+		[]*unicode.RangeTable{unicode.Hangul, unicode.Han},
+	}
+)
+
+// languages maps the base of a language to the charsets its texts are found
+// in, most common first. Other languages use western charsets
+var languages = map[string]charsets{
+	"sq": central.and(western),
+	"bs": central.and(cyrillic),
+	"hr": central,
+	"cs": central,
+	"hu": central,
+	"pl": central,
+	"sk": central,
+	"sl": central,
+	"ro": romanian,
+	"sr": central.and(cyrillic),
+	"be": cyrillic,
+	"bg": cyrillic,
+	"mk": cyrillic,
+	"ru": cyrillic,
+	"uk": cyrillic,
+	"et": baltic.and(western),
+	"lt": baltic,
+	"lv": baltic,
+	"az": turkish,
+	"tr": turkish,
+	"el": greek,
+	"he": hebrew,
+	"ar": arabic,
+	"fa": arabic,
+	"ur": arabic,
+	"vi": vietnamese,
+	"th": thai,
+	"zh": chinese,
+	"ja": japan,
+	"ko": korea,
+}
+
+// Detect returns the encoding of the text. Texts with a byte order mark, and
+// texts which are valid UTF-8 or look like UTF-16, are detected as such.
+// Otherwise the language of the text decides which legacy encodings are
+// considered, and the encoding which decodes the text most sensibly is chosen
+func Detect(data []byte, lang language.Tag) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return utf.UTF8BOM
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return utf.UTF16(utf.LittleEndian, utf.ExpectBOM)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return utf.UTF16(utf.BigEndian, utf.ExpectBOM)
+	}
+
+	if e, ok := detectUTF16(data); ok {
+		return e
+	}
+
+	if utf8.Valid(data) {
+		return UTF8
+	}
+
+	base, _ := lang.Base()
+	c, ok := languages[base.String()]
+	if !ok {
+		c = western
+	}
+
+	best, score := c.encodings[0], 0
+	for i, e := range c.encodings {
+		text, err := e.NewDecoder().Bytes(data)
+		if err != nil {
+			continue
+		}
+		if s := plausibility(text, c.scripts); i == 0 || s > score {
+			best, score = e, s
+		}
+	}
+	return best
+}
+
+// detectUTF16 detects UTF-16 without a byte order mark, by the zero bytes of
+// the ASCII characters (e.g. timestamps) found in any subtitle
+func detectUTF16(data []byte) (encoding.Encoding, bool) {
+	if len(data) < 2 || len(data)%2 != 0 {
+		return nil, false
+	}
+
+	var even, odd int
+	for i := 0; i < len(data); i += 2 {
+		if data[i] == 0 {
+			even++
+		}
+		if data[i+1] == 0 {
+			odd++
+		}
+	}
+
+	pairs := len(data) / 2
+	switch {
+	case odd > pairs/3 && even < pairs/10:
+		return utf.UTF16(utf.LittleEndian, utf.IgnoreBOM), true
+	case even > pairs/3 && odd < pairs/10:
+		return utf.UTF16(utf.BigEndian, utf.IgnoreBOM), true
+	}
+	return nil, false
+}
+
+// plausibility returns how likely the decoded text is to be correct. Letters
+// of the scripts of the language count in favour, while control characters,
+// letters of other scripts, symbols inside words and words mixing scripts or
+// case count against. ASCII is the same in every candidate and is ignored
+func plausibility(text []byte, scripts []*unicode.RangeTable) int {
+	var score int
+	var prev rune
+	for _, r := range string(text) {
+		switch {
+		case r == utf8.RuneError || unicode.IsControl(r) && !unicode.IsSpace(r):
+			score -= 10
+		case r < utf8.RuneSelf:
+		case unicode.IsLetter(r):
+			if unicode.In(r, scripts...) {
+				score++
+			} else {
+				score -= 2
+			}
+			if unicode.IsLetter(prev) && unicode.Is(unicode.Latin, prev) != unicode.Is(unicode.Latin, r) {
+				score -= 3
+			}
+			if unicode.IsLower(prev) && unicode.IsUpper(r) {
+				score -= 2
+			}
+		default:
+			score--
+			if unicode.IsLetter(prev) {
+				score -= 2
+			}
+		}
+		prev = r
+	}
+	return score
+}
+
+// ToUTF8 detects the encoding of the text and decodes the text into UTF-8,
+// without a byte order mark. The detected encoding is returned as well
+func ToUTF8(data []byte, lang language.Tag) ([]byte, encoding.Encoding, error) {
+	e := Detect(data, lang)
+	text, err := e.NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return text, e, nil
+}
+
+// FromUTF8 encodes the UTF-8 text into the encoding. Characters which can not
+// be represented in the encoding are replaced
+func FromUTF8(text []byte, e encoding.Encoding) ([]byte, error) {
+	return encoding.ReplaceUnsupported(e.NewEncoder()).Bytes(text)
+}
+
+// Lookup returns the encoding with the name (e.g. utf-8, windows-1251 or
+// iso-8859-2). UTF-16 is written with a byte order mark
+func Lookup(name string) (encoding.Encoding, error) {
+	switch strings.ToLower(name) {
+	case "utf-16", "utf-16le":
+		return utf.UTF16(utf.LittleEndian, utf.UseBOM), nil
+	case "utf-16be":
+		return utf.UTF16(utf.BigEndian, utf.UseBOM), nil
+	}
+	e, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %v", name)
+	}
+	return e, nil
+}
+
+// Name returns the name of the encoding
+func Name(e encoding.Encoding) string {
+	switch e {
+	case utf.UTF8BOM:
+		return "utf-8 (bom)"
+	case utf.UTF16(utf.LittleEndian, utf.ExpectBOM), utf.UTF16(utf.LittleEndian, utf.IgnoreBOM), utf.UTF16(utf.LittleEndian, utf.UseBOM):
+		return "utf-16le"
+	case utf.UTF16(utf.BigEndian, utf.ExpectBOM), utf.UTF16(utf.BigEndian, utf.IgnoreBOM), utf.UTF16(utf.BigEndian, utf.UseBOM):
+		return "utf-16be"
+	}
+	if name, err := htmlindex.Name(e); err == nil {
+		return name
+	}
+	return fmt.Sprint(e)
+}
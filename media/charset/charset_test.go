@@ -0,0 +1,84 @@
+package charset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	utf "golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/language"
+)
+
+const timestamp = "1\r\n00:00:01,000 --> 00:00:02,500\r\n"
+
+var samples = []struct {
+	lang language.Tag
+	text string
+	enc  []encoding.Encoding
+}{
+	{language.Russian, "Привет, как дела? Всё хорошо, спасибо.", []encoding.Encoding{charmap.Windows1251, charmap.KOI8R, charmap.ISO8859_5}},
+	{language.Ukrainian, "Доброго ранку! Їжак їсть яблуко.", []encoding.Encoding{charmap.Windows1251, charmap.KOI8U}},
+	{language.Czech, "Příliš žluťoučký kůň úpěl ďábelské ódy.", []encoding.Encoding{charmap.Windows1250, charmap.ISO8859_2}},
+	{language.Polish, "Zażółć gęślą jaźń. Śpiewał źrebak.", []encoding.Encoding{charmap.Windows1250, charmap.ISO8859_2}},
+	{language.Arabic, "مرحبا، كيف حالك؟ أنا بخير، شكرا.", []encoding.Encoding{charmap.Windows1256, charmap.ISO8859_6}},
+	{language.Greek, "Καλημέρα, τι κάνεις; Είμαι καλά. Άνοιξη!", []encoding.Encoding{charmap.Windows1253, charmap.ISO8859_7}},
+	{language.Turkish, "Günaydın, nasılsın? İyiyim, teşekkürler.", []encoding.Encoding{charmap.Windows1254}},
+	{language.German, "Schöne Grüße aus Köln, süß!", []encoding.Encoding{charmap.Windows1252}},
+}
+
+func TestDetect(t *testing.T) {
+	for _, s := range samples {
+		for _, e := range s.enc {
+			data, err := e.NewEncoder().Bytes([]byte(timestamp + s.text))
+			require.NoError(t, err)
+			assert.Equal(t, Name(e), Name(Detect(data, s.lang)), "%v in %v", s.lang, Name(e))
+
+			text, detected, err := ToUTF8(data, s.lang)
+			require.NoError(t, err)
+			assert.Equal(t, Name(e), Name(detected))
+			assert.Equal(t, timestamp+s.text, string(text))
+		}
+	}
+}
+
+func TestDetectUnicode(t *testing.T) {
+	text := []byte(timestamp + "Привет, как дела?")
+
+	assert.Equal(t, UTF8, Detect(text, language.Russian))
+	assert.Equal(t, utf.UTF8BOM, Detect(append([]byte{0xEF, 0xBB, 0xBF}, text...), language.Russian))
+
+	for _, e := range []encoding.Encoding{
+		utf.UTF16(utf.LittleEndian, utf.UseBOM),
+		utf.UTF16(utf.BigEndian, utf.UseBOM),
+		utf.UTF16(utf.LittleEndian, utf.IgnoreBOM),
+		utf.UTF16(utf.BigEndian, utf.IgnoreBOM),
+	} {
+		data, err := e.NewEncoder().Bytes(text)
+		require.NoError(t, err)
+
+		decoded, detected, err := ToUTF8(data, language.Und)
+		require.NoError(t, err)
+		assert.Equal(t, string(text), string(decoded))
+		assert.Equal(t, Name(e), Name(detected))
+	}
+}
+
+func TestFromUTF8(t *testing.T) {
+	e, err := Lookup("windows-1251")
+	require.NoError(t, err)
+
+	data, err := FromUTF8([]byte("Привет ★"), e)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xCF, 0xF0, 0xE8, 0xE2, 0xE5, 0xF2, ' ', 0x1A}, data)
+
+	e, err = Lookup("UTF-16")
+	require.NoError(t, err)
+	data, err = FromUTF8([]byte("A"), e)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xFF, 0xFE, 'A', 0}, data)
+
+	_, err = Lookup("klingon")
+	assert.Error(t, err)
+}
@@ -0,0 +1,204 @@
+package cleanup
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/tympanix/supper/media/subformat"
+)
+
+// Rules are the parts of subtitles which are removed. Cues matching any of
+// the patterns (regular expressions) or containing any of the phrases are
+// removed in addition to the built-in ads and credits
+type Rules struct {
+	Ads             bool
+	HearingImpaired bool
+	Patterns        []string
+	Phrases         []string
+}
+
+// Result is the number of cues removed as ads or credits, and the number of
+// cues which hearing impaired annotations were stripped from
+type Result struct {
+	Ads         int
+	Annotations int
+}
+
+// Changed returns true if any cue was removed or changed
+func (r Result) Changed() bool {
+	return r.Ads > 0 || r.Annotations > 0
+}
+
+// adPatterns match cues which advertise subtitle sites or credit the people
+// who made the subtitle
+var adPatterns = []string{
+	`(?i)\bwww\.\S+`,
+	`(?i)https?://`,
+	`(?i)^\W*(english\s+)?(subtitles?|subs|captions?|captioning|translat(ed|ion)|transcript(ion)?|(re-?)?sync(ed|hronized)?|correct(ed|ions)|encoded|ripped|proofread(ing)?)((\s+(and|&)\s+|\s*,\s*)\w+)*\s*(\bby\b|:)`,
+}
+
+// adPhrases are phrases, in lower case, of cues which advertise subtitle
+// sites or their sponsors
+var adPhrases = []string{
+	"advertise your product or brand here",
+	"support us and become vip member",
+	"please rate this subtitle",
+	"help other users to choose the best subtitles",
+	"subtitles downloaded from",
+	"captioning sponsored by",
+	"captioning made possible by",
+	"opensubtitles",
+	"addic7ed",
+	"subscene",
+	"podnapisi",
+}
+
+// annotationRegexp matches sound descriptions, e.g. [MUSIC] or (laughs),
+// which may span several lines
+var annotationRegexp = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+
+// speakerRegexp matches speaker labels in upper case at the beginning of a
+// line, e.g. "JOHN:" or "- MAN #2:", keeping formatting tags and dashes
+var speakerRegexp = regexp.MustCompile(`^((?:<[^>]+>|\{[^}]*\})*\s*(?:-\s*)?)[A-Z][A-Z0-9'.#\- ]*[A-Z0-9]:\s*`)
+
+// tagRegexp matches formatting tags of the subtitle formats
+var tagRegexp = regexp.MustCompile(`<[^>]+>|\{[^}]*\}`)
+
+// dashRegexp matches the dash of a line of dialogue, after any tags
+var dashRegexp = regexp.MustCompile(`^((?:<[^>]+>|\{[^}]*\})*)\s*-\s*`)
+
+// spaceRegexp matches runs of spaces left by removed annotations
+var spaceRegexp = regexp.MustCompile(`[ \t]{2,}`)
+
+// Cleaner removes ads, credits and hearing impaired annotations from cues
+type Cleaner struct {
+	patterns []*regexp.Regexp
+	phrases  []string
+	hi       bool
+}
+
+// New returns a cleaner using the rules. An error is returned if any of the
+// patterns is not a valid regular expression
+func New(r Rules) (*Cleaner, error) {
+	c := &Cleaner{hi: r.HearingImpaired}
+
+	patterns := r.Patterns
+	if r.Ads {
+		patterns = append(append([]string(nil), adPatterns...), patterns...)
+		c.phrases = append(c.phrases, adPhrases...)
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		c.patterns = append(c.patterns, re)
+	}
+	for _, p := range r.Phrases {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			c.phrases = append(c.phrases, p)
+		}
+	}
+
+	return c, nil
+}
+
+// Clean returns the cues without ads, credits and, if enabled, hearing
+// impaired annotations. Cues without any text left are removed
+func (c *Cleaner) Clean(cues []subformat.Cue) ([]subformat.Cue, Result) {
+	var res Result
+	cleaned := make([]subformat.Cue, 0, len(cues))
+
+	for _, cue := range cues {
+		lines, r := c.CleanCue(cue)
+		res.Ads += r.Ads
+		res.Annotations += r.Annotations
+		if len(lines) == 0 {
+			continue
+		}
+		cue.Lines = lines
+		cleaned = append(cleaned, cue)
+	}
+
+	return cleaned, res
+}
+
+// CleanCue returns the lines of the cue without hearing impaired annotations,
+// if enabled, or no lines if the cue is an ad or has no text left
+func (c *Cleaner) CleanCue(cue subformat.Cue) ([]string, Result) {
+	if c.isAd(cue) {
+		return nil, Result{Ads: 1}
+	}
+	if !c.hi {
+		return cue.Lines, Result{}
+	}
+	lines, changed := stripAnnotations(cue.Lines)
+	if !changed {
+		return cue.Lines, Result{}
+	}
+	return lines, Result{Annotations: 1}
+}
+
+// isAd returns true if the text of the cue matches any pattern or contains
+// any phrase
+func (c *Cleaner) isAd(cue subformat.Cue) bool {
+	text := plainText(strings.Join(cue.Lines, "\n"))
+	for _, line := range strings.Split(text, "\n") {
+		for _, re := range c.patterns {
+			if re.MatchString(strings.TrimSpace(line)) {
+				return true
+			}
+		}
+	}
+	lower := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	for _, p := range c.phrases {
+		if strings.Contains(lower, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripAnnotations removes sound descriptions and speaker labels from the
+// lines. Lines without any text left are removed, and the dash is removed
+// from a single line of dialogue left over
+func stripAnnotations(lines []string) ([]string, bool) {
+	text := annotationRegexp.ReplaceAllString(strings.Join(lines, "\n"), "")
+
+	var stripped []string
+	for _, line := range strings.Split(text, "\n") {
+		line = speakerRegexp.ReplaceAllString(line, "$1")
+		line = strings.TrimSpace(spaceRegexp.ReplaceAllString(line, " "))
+		if !hasText(line) {
+			continue
+		}
+		stripped = append(stripped, line)
+	}
+
+	changed := len(stripped) != len(lines)
+	for i := 0; !changed && i < len(lines); i++ {
+		changed = stripped[i] != strings.TrimSpace(lines[i])
+	}
+	if !changed {
+		return lines, false
+	}
+
+	if len(stripped) == 1 && len(lines) > 1 {
+		stripped[0] = dashRegexp.ReplaceAllString(stripped[0], "$1")
+	}
+	return stripped, true
+}
+
+// hasText returns true if the line contains any letters or digits outside of
+// formatting tags
+func hasText(line string) bool {
+	return strings.IndexFunc(plainText(line), func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) >= 0
+}
+
+// plainText returns the text without formatting tags
+func plainText(text string) string {
+	return tagRegexp.ReplaceAllString(text, "")
+}
@@ -0,0 +1,108 @@
+package cleanup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/media/subformat"
+)
+
+func cues(lines ...[]string) []subformat.Cue {
+	var c []subformat.Cue
+	for i, l := range lines {
+		start := time.Duration(i) * time.Second
+		c = append(c, subformat.Cue{Start: start, End: start + time.Second, Lines: l})
+	}
+	return c
+}
+
+func texts(c []subformat.Cue) [][]string {
+	var t [][]string
+	for _, cue := range c {
+		t = append(t, cue.Lines)
+	}
+	return t
+}
+
+func TestCleanAds(t *testing.T) {
+	c, err := New(Rules{Ads: true, Phrases: []string{" Brought To You By "}})
+	require.NoError(t, err)
+
+	input := cues(
+		[]string{"Subtitles by XYZ"},
+		[]string{"Sync and corrections by n17t01", "<font color=\"#00ff00\">www.addic7ed.com</font>"},
+		[]string{"Where are you going?"},
+		[]string{"Advertise your product or brand here", "contact today"},
+		[]string{"I was translated by nobody."},
+		[]string{"brought to you", "by the letter A"},
+		[]string{"- Subtitles: team", "- Ok."},
+	)
+
+	cleaned, res := c.Clean(input)
+	assert.Equal(t, [][]string{
+		{"Where are you going?"},
+		{"I was translated by nobody."},
+	}, texts(cleaned))
+	assert.Equal(t, Result{Ads: 5}, res)
+	assert.Equal(t, 2*time.Second, cleaned[0].Start)
+}
+
+func TestCleanPatterns(t *testing.T) {
+	c, err := New(Rules{Patterns: []string{`(?i)^resync`}})
+	require.NoError(t, err)
+
+	cleaned, res := c.Clean(cues(
+		[]string{"Resync for WEB-DL"},
+		[]string{"Subtitles by XYZ"},
+	))
+	assert.Equal(t, [][]string{{"Subtitles by XYZ"}}, texts(cleaned))
+	assert.Equal(t, 1, res.Ads)
+
+	_, err = New(Rules{Patterns: []string{"("}})
+	assert.Error(t, err)
+}
+
+func TestCleanHearingImpaired(t *testing.T) {
+	c, err := New(Rules{HearingImpaired: true})
+	require.NoError(t, err)
+
+	input := cues(
+		[]string{"[MUSIC PLAYING]"},
+		[]string{"<i>(laughs)</i>"},
+		[]string{"- [sighs]", "- I know."},
+		[]string{"JOHN: Come here.", "(door slams)"},
+		[]string{"- MR. SMITH: Hello.", "- MAN #2: Hi there."},
+		[]string{"I said (quietly) no."},
+		[]string{"(whispering) Don't", "make a sound."},
+		[]string{"♪ ♪"},
+		[]string{"♪ La la la ♪"},
+		[]string{"I: am fine. OK?"},
+		[]string{"Subtitles by XYZ"},
+	)
+
+	cleaned, res := c.Clean(input)
+	assert.Equal(t, [][]string{
+		{"I know."},
+		{"Come here."},
+		{"- Hello.", "- Hi there."},
+		{"I said no."},
+		{"Don't", "make a sound."},
+		{"♪ La la la ♪"},
+		{"I: am fine. OK?"},
+		{"Subtitles by XYZ"},
+	}, texts(cleaned))
+	assert.Equal(t, Result{Annotations: 8}, res)
+	assert.True(t, res.Changed())
+}
+
+func TestCleanUnchanged(t *testing.T) {
+	c, err := New(Rules{Ads: true, HearingImpaired: true})
+	require.NoError(t, err)
+
+	input := cues([]string{"Hello there."}, []string{"- Yes.", "- No."})
+	cleaned, res := c.Clean(input)
+	assert.Equal(t, input, cleaned)
+	assert.False(t, res.Changed())
+}
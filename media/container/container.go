@@ -0,0 +1,386 @@
+// Package container reads information about videos from the headers of their
+// container formats (Matroska/WebM, MP4/QuickTime and AVI), without decoding
+// any of the streams
+package container
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+)
+
+// ErrUnknownContainer is returned for files in an unsupported container format
+var ErrUnknownContainer = errors.New("unknown container format")
+
+// errNoDuration is returned when the header of a container has no duration
+var errNoDuration = errors.New("no duration found in container")
+
+// Duration returns the duration of the video at the path, as given by the
+// header of its container
+func Duration(path string) (time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return ReadDuration(f)
+}
+
+// ReadDuration returns the duration of the video, as given by the header of
+// its container
+func ReadDuration(r io.ReadSeeker) (time.Duration, error) {
+	var magic [12]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return 0, ErrUnknownContainer
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case bytes.Equal(magic[:4], []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return matroskaDuration(r)
+	case bytes.Equal(magic[:4], []byte("RIFF")) && bytes.Equal(magic[8:12], []byte("AVI ")):
+		return aviDuration(r)
+	case isBox(magic[4:8]):
+		return mp4Duration(r)
+	}
+	return 0, ErrUnknownContainer
+}
+
+// Matroska element IDs, including their length markers
+const (
+	mkvSegment       = 0x18538067
+	mkvInfo          = 0x1549A966
+	mkvTimecodeScale = 0x2AD7B1
+	mkvDuration      = 0x4489
+	mkvCluster       = 0x1F43B675
+)
+
+// matroskaDuration reads the duration from the segment information of a
+// Matroska (or WebM) file
+func matroskaDuration(r io.ReadSeeker) (time.Duration, error) {
+	// Skip the EBML header
+	if _, size, err := readElement(r); err != nil {
+		return 0, err
+	} else if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+		return 0, err
+	}
+
+	id, _, err := readElement(r)
+	if err != nil {
+		return 0, err
+	}
+	if id != mkvSegment {
+		return 0, errors.New("no segment found in matroska file")
+	}
+
+	for {
+		id, size, err := readElement(r)
+		if err != nil {
+			return 0, err
+		}
+		if id == mkvCluster || size < 0 {
+			return 0, errNoDuration
+		}
+		if id == mkvInfo {
+			return matroskaInfo(io.LimitReader(r, size))
+		}
+		if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// matroskaInfo reads the duration from the elements of the segment information
+func matroskaInfo(r io.Reader) (time.Duration, error) {
+	scale := uint64(time.Millisecond)
+	duration := -1.0
+
+	for {
+		id, size, err := readElement(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if size < 0 {
+			return 0, errNoDuration
+		}
+		if size > 8 {
+			if _, err := io.CopyN(ioutil.Discard, r, size); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return 0, err
+		}
+
+		switch id {
+		case mkvTimecodeScale:
+			scale = readUint(data)
+		case mkvDuration:
+			switch size {
+			case 4:
+				duration = float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+			case 8:
+				duration = math.Float64frombits(binary.BigEndian.Uint64(data))
+			}
+		}
+	}
+
+	if duration < 0 {
+		return 0, errNoDuration
+	}
+	return time.Duration(duration * float64(scale)), nil
+}
+
+// readElement reads the ID and size of the next EBML element. The size is
+// negative if it is unknown
+func readElement(r io.Reader) (uint64, int64, error) {
+	id, _, err := readVint(r, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, unknown, err := readVint(r, false)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	if unknown {
+		return id, -1, nil
+	}
+	return id, int64(size), nil
+}
+
+// readVint reads a variable length EBML integer. Element IDs keep their length
+// marker. Sizes with every bit set are unknown
+func readVint(r io.Reader, marker bool) (uint64, bool, error) {
+	var first [1]byte
+	if _, err := io.ReadFull(r, first[:]); err != nil {
+		return 0, false, err
+	}
+
+	length := 1
+	for mask := byte(0x80); first[0]&mask == 0; mask >>= 1 {
+		if mask == 1 {
+			return 0, false, errors.New("invalid ebml integer")
+		}
+		length++
+	}
+
+	value := uint64(first[0])
+	if !marker {
+		value &= uint64(0xFF >> uint(length))
+	}
+	all := value == uint64(0xFF>>uint(length))
+
+	rest := make([]byte, length-1)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return 0, false, io.ErrUnexpectedEOF
+	}
+	for _, b := range rest {
+		value = value<<8 | uint64(b)
+		all = all && b == 0xFF
+	}
+	return value, !marker && all, nil
+}
+
+func readUint(data []byte) uint64 {
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+// isBox reports whether the type is a top level box which may begin an MP4 or
+// QuickTime file
+func isBox(t []byte) bool {
+	for _, box := range []string{"ftyp", "moov", "mdat", "free", "skip", "wide", "pnot"} {
+		if string(t) == box {
+			return true
+		}
+	}
+	return false
+}
+
+// mp4Duration reads the duration from the movie header box of an MP4 or
+// QuickTime file
+func mp4Duration(r io.ReadSeeker) (time.Duration, error) {
+	end, err := findBox(r, "moov", -1)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := findBox(r, "mvhd", end); err != nil {
+		return 0, err
+	}
+
+	var version [4]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return 0, err
+	}
+
+	var timescale uint32
+	var duration uint64
+	if version[0] == 1 {
+		var h struct {
+			Created, Modified uint64
+			Timescale         uint32
+			Duration          uint64
+		}
+		if err := binary.Read(r, binary.BigEndian, &h); err != nil {
+			return 0, err
+		}
+		timescale, duration = h.Timescale, h.Duration
+	} else {
+		var h struct {
+			Created, Modified, Timescale, Duration uint32
+		}
+		if err := binary.Read(r, binary.BigEndian, &h); err != nil {
+			return 0, err
+		}
+		timescale, duration = h.Timescale, uint64(h.Duration)
+	}
+
+	if timescale == 0 {
+		return 0, errNoDuration
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), nil
+}
+
+// findBox skips boxes until the box of the type, and positions the reader at
+// its content. Boxes are searched for until the end offset, or the end of the
+// file if the offset is negative. The end offset of the box is returned, which
+// is negative if the box extends to the end of the file
+func findBox(r io.ReadSeeker, typ string, end int64) (int64, error) {
+	for {
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+		if end >= 0 && pos >= end {
+			return 0, errNoDuration
+		}
+
+		var h struct {
+			Size uint32
+			Type [4]byte
+		}
+		if err := binary.Read(r, binary.BigEndian, &h); err != nil {
+			if err == io.EOF {
+				return 0, errNoDuration
+			}
+			return 0, err
+		}
+
+		size, header := int64(h.Size), int64(8)
+		if h.Size == 1 {
+			var large uint64
+			if err := binary.Read(r, binary.BigEndian, &large); err != nil {
+				return 0, err
+			}
+			size, header = int64(large), 16
+		}
+
+		if h.Size != 0 && size < header {
+			return 0, errors.New("invalid box size")
+		}
+		if string(h.Type[:]) == typ {
+			if h.Size == 0 {
+				return -1, nil
+			}
+			return pos + size, nil
+		}
+		if h.Size == 0 {
+			return 0, errNoDuration
+		}
+		if _, err := r.Seek(pos+size, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// aviDuration reads the duration from the main header of an AVI file. The
+// main header of OpenDML files larger than 1 GB only counts the frames of the
+// first part of the file, such that the total number of frames is read from
+// the extended header when present
+func aviDuration(r io.ReadSeeker) (time.Duration, error) {
+	var h struct {
+		Riff     [4]byte
+		Size     uint32
+		Avi      [4]byte
+		List     [4]byte
+		ListSize uint32
+		Hdrl     [4]byte
+		Avih     [4]byte
+		AvihSize uint32
+
+		MicroSecPerFrame   uint32
+		MaxBytesPerSec     uint32
+		PaddingGranularity uint32
+		Flags              uint32
+		TotalFrames        uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return 0, err
+	}
+	if string(h.Hdrl[:]) != "hdrl" || string(h.Avih[:]) != "avih" {
+		return 0, errNoDuration
+	}
+
+	frames := h.TotalFrames
+	if total, ok := aviTotalFrames(r, 32+int64(h.AvihSize)+int64(h.AvihSize%2), 20+int64(h.ListSize)); ok {
+		frames = total
+	}
+	return time.Duration(h.MicroSecPerFrame) * time.Duration(frames) * time.Microsecond, nil
+}
+
+// aviTotalFrames returns the total number of frames from the extended header
+// (dmlh) of an OpenDML AVI file, searching the chunks from the position until
+// the end
+func aviTotalFrames(r io.ReadSeeker, pos, end int64) (uint32, bool) {
+	for pos+8 <= end {
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			return 0, false
+		}
+		var c struct {
+			ID   [4]byte
+			Size uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &c); err != nil {
+			return 0, false
+		}
+
+		switch string(c.ID[:]) {
+		case "LIST":
+			var typ [4]byte
+			if err := binary.Read(r, binary.LittleEndian, &typ); err != nil {
+				return 0, false
+			}
+			if string(typ[:]) == "odml" {
+				return aviTotalFrames(r, pos+12, pos+8+int64(c.Size))
+			}
+		case "dmlh":
+			var frames uint32
+			if err := binary.Read(r, binary.LittleEndian, &frames); err != nil {
+				return 0, false
+			}
+			return frames, frames > 0
+		}
+		pos += 8 + int64(c.Size) + int64(c.Size%2)
+	}
+	return 0, false
+}
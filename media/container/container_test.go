@@ -0,0 +1,47 @@
+package container
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"test/sample.mkv": time.Hour + 30*time.Minute + 500*time.Millisecond,
+		"test/sample.mp4": 45 * time.Minute,
+		"test/sample.avi": 5401019168 * time.Microsecond,
+
+		// The main header only counts the frames of the first part of the file
+		"test/sample.odml.avi": 5401019168 * time.Microsecond,
+	}
+
+	for path, expected := range tests {
+		d, err := Duration(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, expected, d, path)
+	}
+}
+
+func TestDurationInvalid(t *testing.T) {
+	_, err := ReadDuration(bytes.NewReader([]byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n")))
+	assert.Equal(t, ErrUnknownContainer, err)
+
+	_, err = ReadDuration(bytes.NewReader(nil))
+	assert.Equal(t, ErrUnknownContainer, err)
+
+	for _, path := range []string{"test/sample.mkv", "test/sample.mp4", "test/sample.avi"} {
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+
+		_, err = ReadDuration(bytes.NewReader(data[:len(data)/2]))
+		assert.Error(t, err, path)
+	}
+
+	_, err = Duration("test/missing.mkv")
+	assert.Error(t, err)
+}
@@ -0,0 +1,280 @@
+// Package langid identifies the language of text, such as the dialogue of a
+// subtitle, by comparing the character trigrams of the text with built-in
+// profiles of languages. Languages written in a script of their own are
+// identified by the script alone
+package langid
+
+import (
+	"math"
+	"unicode"
+
+	"golang.org/x/text/language"
+)
+
+// minLetters is the fewest letters needed to identify the language of a text
+const minLetters = 100
+
+// maxLetters is the most letters of a text which are looked at
+const maxLetters = 20000
+
+// margin is how much better the identified language must match a text than
+// the expected language, for the text not to be in the expected language
+const margin = 1.1
+
+// scripts are the scripts told apart when identifying a language
+var scripts = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"Latin", unicode.Latin},
+	{"Cyrillic", unicode.Cyrillic},
+	{"Arabic", unicode.Arabic},
+	{"Greek", unicode.Greek},
+	{"Hebrew", unicode.Hebrew},
+	{"Han", unicode.Han},
+	{"Kana", unicode.Hiragana},
+	{"Kana", unicode.Katakana},
+	{"Hangul", unicode.Hangul},
+	{"Thai", unicode.Thai},
+	{"Georgian", unicode.Georgian},
+	{"Armenian", unicode.Armenian},
+	{"Devanagari", unicode.Devanagari},
+	{"Bengali", unicode.Bengali},
+	{"Tamil", unicode.Tamil},
+	{"Telugu", unicode.Telugu},
+	{"Malayalam", unicode.Malayalam},
+}
+
+// scriptCodes maps ISO 15924 script codes to the scripts told apart
+var scriptCodes = map[string]string{
+	"Latn": "Latin",
+	"Cyrl": "Cyrillic",
+	"Arab": "Arabic",
+	"Grek": "Greek",
+	"Hebr": "Hebrew",
+	"Hans": "Han",
+	"Hant": "Han",
+	"Jpan": "Kana",
+	"Kore": "Hangul",
+	"Thai": "Thai",
+	"Geor": "Georgian",
+	"Armn": "Armenian",
+	"Deva": "Devanagari",
+	"Beng": "Bengali",
+	"Taml": "Tamil",
+	"Telu": "Telugu",
+	"Mlym": "Malayalam",
+}
+
+// alternateScripts are scripts languages are commonly written in, besides
+// their default script
+var alternateScripts = map[string]string{
+	"sr": "Latin",
+	"bs": "Cyrillic",
+}
+
+// unique maps scripts to the only language of the script told apart
+var unique = map[string]string{
+	"Greek":      "el",
+	"Hebrew":     "he",
+	"Han":        "zh",
+	"Kana":       "ja",
+	"Hangul":     "ko",
+	"Thai":       "th",
+	"Georgian":   "ka",
+	"Armenian":   "hy",
+	"Devanagari": "hi",
+	"Bengali":    "bn",
+	"Tamil":      "ta",
+	"Telugu":     "te",
+	"Malayalam":  "ml",
+}
+
+// alike are groups of languages which are too similar to be told apart
+var alike = [][]string{
+	{"da", "no", "nb", "nn"},
+	{"hr", "sr", "bs"},
+	{"id", "ms"},
+	{"cs", "sk"},
+}
+
+// profile is the trigram frequencies of a text
+type profile struct {
+	script   string
+	letters  int
+	trigrams map[string]float64
+	norm     float64
+}
+
+// profiles holds the profile of each language with a sample
+var profiles = make(map[string]profile)
+
+func init() {
+	for lang, sample := range samples {
+		profiles[lang] = newProfile(sample)
+	}
+}
+
+// newProfile counts the trigrams of the words of the text, ignoring case,
+// punctuation and markup (e.g. <i> and {\an8}), and finds the script most of
+// the letters are written in
+func newProfile(text string) profile {
+	p := profile{trigrams: make(map[string]float64)}
+
+	counts := make(map[string]int)
+	runes := []rune{' '}
+	var end rune
+	for _, r := range text {
+		if p.letters >= maxLetters {
+			break
+		}
+		switch {
+		case end != 0:
+			if r == end {
+				end = 0
+			}
+			continue
+		case r == '<':
+			end = '>'
+			continue
+		case r == '{':
+			end = '}'
+			continue
+		}
+
+		if !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r) {
+			if runes[len(runes)-1] != ' ' {
+				runes = append(runes, ' ')
+			}
+			continue
+		}
+
+		runes = append(runes, unicode.ToLower(r))
+		p.letters++
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.name]++
+				break
+			}
+		}
+	}
+	runes = append(runes, ' ')
+
+	// Japanese mixes kana with Han, and is told apart by a share of kana
+	if counts["Kana"] > p.letters/10 {
+		p.script = "Kana"
+	} else {
+		var most int
+		for name, n := range counts {
+			if n > most || n == most && name < p.script {
+				p.script, most = name, n
+			}
+		}
+	}
+
+	for i := 0; i+3 <= len(runes); i++ {
+		p.trigrams[string(runes[i:i+3])]++
+	}
+	for _, n := range p.trigrams {
+		p.norm += n * n
+	}
+	p.norm = math.Sqrt(p.norm)
+
+	return p
+}
+
+// similarity returns the cosine similarity of the trigrams of the profiles
+func (p profile) similarity(o profile) float64 {
+	if p.norm == 0 || o.norm == 0 {
+		return 0
+	}
+	var dot float64
+	for t, n := range p.trigrams {
+		dot += n * o.trigrams[t]
+	}
+	return dot / (p.norm * o.norm)
+}
+
+// identify returns the base of the language of the text profile and how well
+// the language matches, or false if the text is too short
+func (p profile) identify() (string, float64, bool) {
+	if p.letters < minLetters {
+		return "", 0, false
+	}
+	if lang, ok := unique[p.script]; ok {
+		return lang, 1, true
+	}
+
+	var best string
+	var score float64
+	for lang, o := range profiles {
+		if o.script != p.script {
+			continue
+		}
+		if s := p.similarity(o); s > score || s == score && lang < best {
+			best, score = lang, s
+		}
+	}
+	return best, score, best != ""
+}
+
+// Identify returns the language of the text. False is returned if the text is
+// too short, or in a script of no known language
+func Identify(text string) (language.Tag, bool) {
+	lang, _, ok := newProfile(text).identify()
+	if !ok {
+		return language.Und, false
+	}
+	return language.Make(lang), true
+}
+
+// Matches reports whether the text may be in the language, and returns the
+// language the text is identified as. Texts which are too short to identify,
+// and languages too similar to the identified language, always match. When
+// the language has no profile only the script of the text is checked
+func Matches(text string, lang language.Tag) (language.Tag, bool) {
+	p := newProfile(text)
+	found, score, ok := p.identify()
+	if !ok {
+		return language.Und, true
+	}
+
+	detected := language.Make(found)
+	base, _ := lang.Base()
+	expected := base.String()
+
+	if similar(expected, found) {
+		return detected, true
+	}
+
+	if o, ok := profiles[expected]; ok && o.script == p.script {
+		return detected, score < margin*p.similarity(o)
+	}
+
+	script, _ := lang.Script()
+	name, ok := scriptCodes[script.String()]
+	if !ok {
+		return detected, true
+	}
+	return detected, name == p.script || alternateScripts[expected] == p.script
+}
+
+// similar reports whether the languages are the same or too similar to be
+// told apart
+func similar(a, b string) bool {
+	if a == b {
+		return true
+	}
+	for _, group := range alike {
+		var found int
+		for _, l := range group {
+			if l == a || l == b {
+				found++
+			}
+		}
+		if found == 2 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package langid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+// texts are dialogue in each language, unrelated to the samples of profiles
+var texts = map[string]string{
+	"en": `The train leaves at seven, so we need to get up early. Did you pack
+		the sandwiches? I thought you were going to do that. My mother always says
+		that breakfast is the most important meal of the day. Can you believe this
+		weather? It has been raining for three days without stopping.`,
+	"id": `Keretanya berangkat jam tujuh, jadi kita harus bangun pagi. Apakah
+		kau sudah membawa rotinya? Kupikir kau yang akan melakukannya. Ibuku selalu
+		bilang sarapan adalah makanan yang paling penting. Kau percaya cuaca ini?
+		Sudah hujan selama tiga hari tanpa berhenti.`,
+	"de": `Der Zug fährt um sieben, also müssen wir früh aufstehen. Hast du die
+		Brote eingepackt? Ich dachte, das wolltest du machen. Meine Mutter sagt
+		immer, dass das Frühstück die wichtigste Mahlzeit des Tages ist. Kannst du
+		dieses Wetter glauben? Es regnet seit drei Tagen ohne Pause.`,
+	"nl": `De trein vertrekt om zeven uur, dus we moeten vroeg opstaan. Heb jij
+		de boterhammen ingepakt? Ik dacht dat jij dat zou doen. Mijn moeder zegt
+		altijd dat het ontbijt de belangrijkste maaltijd van de dag is. Kun je dit
+		weer geloven? Het regent al drie dagen zonder op te houden.`,
+	"es": `El tren sale a las siete, así que tenemos que levantarnos temprano.
+		¿Has guardado los bocadillos? Pensé que ibas a hacerlo tú. Mi madre siempre
+		dice que el desayuno es la comida más importante del día. ¿Te puedes creer
+		este tiempo? Lleva tres días lloviendo sin parar.`,
+	"pt": `O trem sai às sete, então precisamos acordar cedo. Você guardou os
+		sanduíches? Achei que você ia fazer isso. Minha mãe sempre diz que o café
+		da manhã é a refeição mais importante do dia. Dá para acreditar nesse tempo?
+		Está chovendo há três dias sem parar.`,
+	"it": `Il treno parte alle sette, quindi dobbiamo alzarci presto. Hai messo
+		in valigia i panini? Pensavo che lo facessi tu. Mia madre dice sempre che
+		la colazione è il pasto più importante della giornata. Ci credi a questo
+		tempo? Piove da tre giorni senza sosta.`,
+	"fr": `Le train part à sept heures, alors il faut se lever tôt. Tu as
+		emballé les sandwichs? Je pensais que tu allais le faire. Ma mère dit
+		toujours que le petit déjeuner est le repas le plus important de la
+		journée. Tu peux croire ce temps? Il pleut depuis trois jours sans arrêt.`,
+	"sv": `Tåget går klockan sju, så vi måste gå upp tidigt. Har du packat
+		smörgåsarna? Jag trodde att du skulle göra det. Min mamma säger alltid att
+		frukosten är dagens viktigaste måltid. Kan du fatta det här vädret? Det har
+		regnat i tre dagar utan att sluta.`,
+	"da": `Toget kører klokken syv, så vi skal tidligt op. Har du pakket
+		madpakkerne? Jeg troede, at du ville gøre det. Min mor siger altid, at
+		morgenmaden er dagens vigtigste måltid. Kan du tro det her vejr? Det har
+		regnet i tre dage uden at stoppe.`,
+	"pl": `Pociąg odjeżdża o siódmej, więc musimy wcześnie wstać. Spakowałeś
+		kanapki? Myślałam, że ty to zrobisz. Moja mama zawsze mówi, że śniadanie
+		jest najważniejszym posiłkiem dnia. Wierzysz w tę pogodę? Pada już od
+		trzech dni bez przerwy.`,
+	"cs": `Vlak odjíždí v sedm, takže musíme brzy vstávat. Zabalil jsi
+		svačinu? Myslela jsem, že to uděláš ty. Moje máma vždycky říká, že snídaně
+		je nejdůležitější jídlo dne. Věříš tomu počasí? Prší už tři dny bez
+		přestání.`,
+	"tr": `Tren yedide kalkıyor, o yüzden erken kalkmamız gerek. Sandviçleri
+		hazırladın mı? Ben senin yapacağını sanıyordum. Annem her zaman kahvaltının
+		günün en önemli öğünü olduğunu söyler. Bu havaya inanabiliyor musun? Üç
+		gündür hiç durmadan yağmur yağıyor.`,
+	"ru": `Поезд отходит в семь, так что нам нужно рано встать. Ты упаковал
+		бутерброды? Я думала, что это сделаешь ты. Моя мама всегда говорит, что
+		завтрак самая важная еда дня. Ты можешь поверить в эту погоду? Дождь идёт
+		уже три дня без остановки.`,
+	"uk": `Потяг вирушає о сьомій, тож нам треба рано встати. Ти спакував
+		бутерброди? Я думала, що це зробиш ти. Моя мама завжди каже, що сніданок
+		найважливіша їжа дня. Ти можеш повірити в цю погоду? Дощ іде вже три дні
+		без зупинки.`,
+	"ar": `القطار يغادر في السابعة، لذلك علينا أن نستيقظ مبكراً. هل وضعت
+		الشطائر في الحقيبة؟ ظننت أنك ستفعل ذلك. أمي تقول دائماً إن الفطور هو أهم
+		وجبة في اليوم. هل تصدق هذا الطقس؟ إنها تمطر منذ ثلاثة أيام دون توقف.`,
+	"fa": `قطار ساعت هفت حرکت می‌کنه، پس باید زود بیدار بشیم. ساندویچ‌ها رو
+		جمع کردی؟ فکر کردم خودت این کار رو می‌کنی. مادرم همیشه میگه صبحانه
+		مهم‌ترین وعده‌ی غذایی روزه. می‌تونی این هوا رو باور کنی؟ سه روزه که
+		بدون وقفه بارون میاد.`,
+	"el": `Το τρένο φεύγει στις επτά, οπότε πρέπει να ξυπνήσουμε νωρίς. Έβαλες
+		τα σάντουιτς στην τσάντα; Νόμιζα ότι θα το έκανες εσύ. Η μητέρα μου λέει
+		πάντα ότι το πρωινό είναι το πιο σημαντικό γεύμα της ημέρας. Πιστεύεις
+		αυτόν τον καιρό; Βρέχει τρεις μέρες χωρίς σταματημό.`,
+}
+
+func TestIdentify(t *testing.T) {
+	for lang, text := range texts {
+		tag, ok := Identify(text)
+		if assert.True(t, ok, lang) {
+			base, _ := tag.Base()
+			assert.True(t, similar(lang, base.String()), "%v identified as %v", lang, tag)
+		}
+	}
+
+	_, ok := Identify("Hello there")
+	assert.False(t, ok)
+}
+
+func TestIdentifyMarkup(t *testing.T) {
+	text := strings.Replace(texts["id"], " ", " <i>{\\an8}", -1)
+	tag, ok := Identify(text)
+	assert.True(t, ok)
+	assert.Equal(t, language.Indonesian, tag)
+}
+
+func TestMatches(t *testing.T) {
+	for lang, text := range texts {
+		_, ok := Matches(text, language.Make(lang))
+		assert.True(t, ok, lang)
+	}
+
+	tag, ok := Matches(texts["id"], language.English)
+	assert.False(t, ok)
+	assert.Equal(t, language.Indonesian, tag)
+
+	_, ok = Matches(texts["ru"], language.English)
+	assert.False(t, ok)
+
+	_, ok = Matches(texts["en"], language.Greek)
+	assert.False(t, ok)
+
+	// Similar languages are not told apart
+	_, ok = Matches(texts["da"], language.Norwegian)
+	assert.True(t, ok)
+
+	// Languages without a profile are checked by their script only
+	_, ok = Matches(texts["en"], language.Estonian)
+	assert.True(t, ok)
+	_, ok = Matches(texts["ru"], language.Estonian)
+	assert.False(t, ok)
+	_, ok = Matches(texts["ru"], language.Serbian)
+	assert.True(t, ok)
+
+	_, ok = Matches("Too short", language.Greek)
+	assert.True(t, ok)
+}
@@ -0,0 +1,245 @@
+package langid
+
+// samples are conversational texts, similar to the dialogue of subtitles,
+// from which the trigram profile of each language is built
+var samples = map[string]string{
+	"en": `What are you doing here? I told you to stay in the car. I know, but I
+		couldn't just sit there and wait. Where is everybody? They left an hour ago.
+		We should go now, before they come back. Listen to me, you have to trust me
+		on this one. I don't think that's a good idea. Why not? Because the last time
+		you said that, we almost got killed. Come on, it wasn't that bad. Are you
+		kidding me? Just get in the car. Thank you for everything. I'm sorry about
+		your father, he was a good man. Nobody knows what happened that night. Would
+		you like something to drink? Yes, please. How long have you been working
+		here? Let's talk about it tomorrow. It's getting late and I'm really tired.
+		Have you seen my keys? They were on the table this morning. Don't worry,
+		everything is going to be fine. We'll find them. I love you. I love you too.`,
+	"de": `Was machst du denn hier? Ich habe dir doch gesagt, dass du im Auto
+		bleiben sollst. Ich weiß, aber ich konnte nicht einfach dasitzen und warten.
+		Wo sind denn alle? Sie sind vor einer Stunde gegangen. Wir sollten jetzt
+		gehen, bevor sie zurückkommen. Hör mir zu, du musst mir vertrauen. Ich glaube
+		nicht, dass das eine gute Idee ist. Warum nicht? Weil wir beim letzten Mal
+		fast umgebracht worden wären. Komm schon, so schlimm war es nicht. Willst du
+		mich verarschen? Steig einfach ins Auto. Danke für alles. Das mit deinem Vater
+		tut mir leid, er war ein guter Mann. Niemand weiß, was in dieser Nacht
+		passiert ist. Möchtest du etwas trinken? Ja, bitte. Wie lange arbeitest du
+		schon hier? Lass uns morgen darüber reden. Es ist spät und ich bin wirklich
+		müde. Hast du meine Schlüssel gesehen? Keine Sorge, alles wird gut.`,
+	"fr": `Qu'est-ce que tu fais ici? Je t'avais dit de rester dans la voiture. Je
+		sais, mais je ne pouvais pas rester assis à attendre. Où sont les autres? Ils
+		sont partis il y a une heure. On devrait y aller maintenant, avant qu'ils
+		reviennent. Écoute-moi, tu dois me faire confiance. Je ne pense pas que ce
+		soit une bonne idée. Pourquoi pas? Parce que la dernière fois que tu as dit
+		ça, on a failli se faire tuer. Allez, ce n'était pas si terrible. Tu te fous
+		de moi? Monte dans la voiture. Merci pour tout. Je suis désolé pour ton père,
+		c'était un homme bien. Personne ne sait ce qui s'est passé cette nuit-là. Tu
+		veux boire quelque chose? Oui, s'il te plaît. Depuis combien de temps tu
+		travailles ici? On en parlera demain. Il est tard et je suis vraiment
+		fatigué. Tu as vu mes clés? Ne t'inquiète pas, tout va bien se passer.`,
+	"es": `¿Qué estás haciendo aquí? Te dije que te quedaras en el coche. Lo sé,
+		pero no podía quedarme sentado esperando. ¿Dónde están todos? Se fueron hace
+		una hora. Deberíamos irnos ahora, antes de que vuelvan. Escúchame, tienes que
+		confiar en mí. No creo que sea una buena idea. ¿Por qué no? Porque la última
+		vez que dijiste eso, casi nos matan. Vamos, no fue para tanto. ¿Me estás
+		tomando el pelo? Sube al coche. Gracias por todo. Siento lo de tu padre, era
+		un buen hombre. Nadie sabe lo que pasó esa noche. ¿Quieres algo de beber? Sí,
+		por favor. ¿Cuánto tiempo llevas trabajando aquí? Hablemos de eso mañana. Es
+		tarde y estoy muy cansado. ¿Has visto mis llaves? Estaban en la mesa esta
+		mañana. No te preocupes, todo va a salir bien. Las encontraremos. Te quiero.`,
+	"it": `Che cosa ci fai qui? Ti avevo detto di restare in macchina. Lo so, ma
+		non potevo stare seduto ad aspettare. Dove sono tutti? Se ne sono andati
+		un'ora fa. Dovremmo andare adesso, prima che tornino. Ascoltami, devi fidarti
+		di me. Non credo che sia una buona idea. Perché no? Perché l'ultima volta che
+		l'hai detto, ci hanno quasi ammazzati. Dai, non è stato così terribile. Mi
+		prendi in giro? Sali in macchina. Grazie di tutto. Mi dispiace per tuo padre,
+		era un brav'uomo. Nessuno sa cosa sia successo quella notte. Vuoi qualcosa da
+		bere? Sì, grazie. Da quanto tempo lavori qui? Ne parliamo domani. È tardi e
+		sono davvero stanco. Hai visto le mie chiavi? Erano sul tavolo stamattina.
+		Non preoccuparti, andrà tutto bene. Le troveremo. Ti voglio bene.`,
+	"pt": `O que você está fazendo aqui? Eu disse para você ficar no carro. Eu sei,
+		mas não podia ficar sentado esperando. Onde estão todos? Eles foram embora
+		há uma hora. Devíamos ir agora, antes que eles voltem. Escute, você precisa
+		confiar em mim. Não acho que seja uma boa ideia. Por que não? Porque da
+		última vez que você disse isso, quase fomos mortos. Vamos, não foi tão ruim
+		assim. Você está brincando comigo? Entre no carro. Obrigado por tudo. Sinto
+		muito pelo seu pai, ele era um bom homem. Ninguém sabe o que aconteceu
+		naquela noite. Quer alguma coisa para beber? Sim, por favor. Há quanto tempo
+		você trabalha aqui? Vamos falar sobre isso amanhã. Está tarde e estou muito
+		cansado. Você viu as minhas chaves? Não se preocupe, vai dar tudo certo.`,
+	"nl": `Wat doe jij hier? Ik zei toch dat je in de auto moest blijven. Ik weet
+		het, maar ik kon daar niet gewoon blijven zitten wachten. Waar is iedereen?
+		Ze zijn een uur geleden vertrokken. We moeten nu gaan, voordat ze terugkomen.
+		Luister naar me, je moet me vertrouwen. Ik denk niet dat dat een goed idee
+		is. Waarom niet? Omdat we de vorige keer dat je dat zei bijna vermoord werden.
+		Kom op, zo erg was het niet. Maak je een grapje? Stap gewoon in de auto.
+		Bedankt voor alles. Het spijt me van je vader, hij was een goede man.
+		Niemand weet wat er die nacht is gebeurd. Wil je iets drinken? Ja, graag.
+		Hoe lang werk je hier al? Laten we het er morgen over hebben. Het is laat en
+		ik ben echt moe. Heb je mijn sleutels gezien? Maak je geen zorgen, het komt
+		allemaal goed. We vinden ze wel. Ik hou van je.`,
+	"da": `Hvad laver du her? Jeg sagde jo, at du skulle blive i bilen. Det ved
+		jeg godt, men jeg kunne ikke bare sidde der og vente. Hvor er de andre? De
+		gik for en time siden. Vi skal gå nu, før de kommer tilbage. Hør på mig, du
+		bliver nødt til at stole på mig. Jeg tror ikke, det er en god idé. Hvorfor
+		ikke? Fordi sidste gang du sagde det, blev vi næsten slået ihjel. Kom nu,
+		det var ikke så slemt. Laver du sjov med mig? Sæt dig ind i bilen. Tak for
+		alt. Jeg er ked af det med din far, han var en god mand. Ingen ved, hvad
+		der skete den nat. Vil du have noget at drikke? Ja tak. Hvor længe har du
+		arbejdet her? Lad os snakke om det i morgen. Det er sent, og jeg er virkelig
+		træt. Har du set mine nøgler? Bare rolig, det skal nok gå alt sammen.`,
+	"no": `Hva gjør du her? Jeg sa jo at du skulle bli i bilen. Jeg vet det, men
+		jeg kunne ikke bare sitte der og vente. Hvor er alle sammen? De dro for en
+		time siden. Vi må dra nå, før de kommer tilbake. Hør på meg, du må stole på
+		meg. Jeg tror ikke det er en god idé. Hvorfor ikke? Fordi sist gang du sa
+		det, ble vi nesten drept. Kom igjen, det var ikke så ille. Tuller du med
+		meg? Sett deg inn i bilen. Takk for alt. Jeg er lei meg for det med faren
+		din, han var en god mann. Ingen vet hva som skjedde den natten. Vil du ha
+		noe å drikke? Ja takk. Hvor lenge har du jobbet her? La oss snakke om det i
+		morgen. Det er sent, og jeg er veldig sliten. Har du sett nøklene mine? Ikke
+		vær redd, alt kommer til å gå bra. Vi finner dem.`,
+	"sv": `Vad gör du här? Jag sa ju att du skulle stanna i bilen. Jag vet, men
+		jag kunde inte bara sitta där och vänta. Var är alla? De gick för en timme
+		sedan. Vi borde åka nu, innan de kommer tillbaka. Lyssna på mig, du måste
+		lita på mig. Jag tror inte att det är en bra idé. Varför inte? För att
+		förra gången du sa så blev vi nästan dödade. Kom igen, det var inte så
+		farligt. Skämtar du med mig? Sätt dig i bilen. Tack för allt. Jag är ledsen
+		för din pappa, han var en bra man. Ingen vet vad som hände den natten. Vill
+		du ha något att dricka? Ja tack. Hur länge har du jobbat här? Vi pratar om
+		det i morgon. Det är sent och jag är jättetrött. Har du sett mina nycklar?
+		Oroa dig inte, allt kommer att ordna sig. Vi hittar dem.`,
+	"fi": `Mitä sinä täällä teet? Minähän käskin sinun jäädä autoon. Tiedän,
+		mutta en voinut vain istua siellä odottamassa. Missä kaikki ovat? He
+		lähtivät tunti sitten. Meidän pitäisi mennä nyt, ennen kuin he tulevat
+		takaisin. Kuuntele minua, sinun täytyy luottaa minuun. En usko, että se on
+		hyvä idea. Miksi ei? Koska viime kerralla kun sanoit noin, meidät melkein
+		tapettiin. Älä viitsi, ei se niin paha ollut. Pilailetko sinä? Mene vain
+		autoon. Kiitos kaikesta. Olen pahoillani isästäsi, hän oli hyvä mies. Kukaan
+		ei tiedä, mitä sinä yönä tapahtui. Haluatko jotain juotavaa? Kyllä, kiitos.
+		Kauanko olet työskennellyt täällä? Puhutaan siitä huomenna. On myöhä ja olen
+		todella väsynyt. Oletko nähnyt avaimiani? Älä huoli, kaikki järjestyy.`,
+	"pl": `Co ty tu robisz? Mówiłem ci, żebyś została w samochodzie. Wiem, ale nie
+		mogłam tak po prostu siedzieć i czekać. Gdzie są wszyscy? Wyszli godzinę
+		temu. Powinniśmy już iść, zanim wrócą. Posłuchaj mnie, musisz mi zaufać. Nie
+		sądzę, żeby to był dobry pomysł. Dlaczego nie? Bo ostatnim razem, kiedy to
+		powiedziałeś, prawie nas zabili. Daj spokój, nie było aż tak źle. Żartujesz
+		sobie? Po prostu wsiadaj do samochodu. Dziękuję za wszystko. Przykro mi z
+		powodu twojego ojca, był dobrym człowiekiem. Nikt nie wie, co się wydarzyło
+		tamtej nocy. Chcesz się czegoś napić? Tak, poproszę. Jak długo tu pracujesz?
+		Porozmawiajmy o tym jutro. Jest późno i jestem bardzo zmęczony. Widziałeś
+		moje klucze? Nie martw się, wszystko będzie dobrze. Znajdziemy je.`,
+	"cs": `Co tady děláš? Říkal jsem ti, ať zůstaneš v autě. Já vím, ale nemohla
+		jsem tam jen tak sedět a čekat. Kde jsou všichni? Odešli před hodinou. Měli
+		bychom jít hned, než se vrátí. Poslouchej mě, musíš mi věřit. Nemyslím si,
+		že je to dobrý nápad. Proč ne? Protože když jsi to řekl naposledy, málem nás
+		zabili. No tak, nebylo to tak hrozné. Děláš si ze mě legraci? Prostě nastup
+		do auta. Děkuju za všechno. Je mi líto tvého otce, byl to dobrý člověk. Nikdo
+		neví, co se té noci stalo. Dáš si něco k pití? Ano, prosím. Jak dlouho tady
+		pracuješ? Promluvíme si o tom zítra. Je pozdě a jsem opravdu unavený. Neviděl
+		jsi moje klíče? Neboj se, všechno bude v pořádku. Najdeme je.`,
+	"hr": `Što radiš ovdje? Rekao sam ti da ostaneš u autu. Znam, ali nisam mogla
+		samo sjediti i čekati. Gdje su svi? Otišli su prije sat vremena. Trebali
+		bismo ići sada, prije nego što se vrate. Slušaj me, moraš mi vjerovati. Ne
+		mislim da je to dobra ideja. Zašto ne? Zato što su nas zadnji put kad si to
+		rekao skoro ubili. Ma daj, nije bilo tako strašno. Šališ se sa mnom? Samo
+		uđi u auto. Hvala ti na svemu. Žao mi je zbog tvog oca, bio je dobar čovjek.
+		Nitko ne zna što se dogodilo te noći. Želiš li nešto popiti? Da, molim.
+		Koliko dugo već radiš ovdje? Razgovarajmo o tome sutra. Kasno je i stvarno
+		sam umoran. Jesi li vidio moje ključeve? Ne brini, sve će biti u redu.`,
+	"ro": `Ce faci aici? Ți-am spus să rămâi în mașină. Știu, dar nu puteam să
+		stau acolo și să aștept. Unde sunt toți? Au plecat acum o oră. Ar trebui să
+		plecăm acum, înainte să se întoarcă. Ascultă-mă, trebuie să ai încredere în
+		mine. Nu cred că e o idee bună. De ce nu? Pentru că ultima dată când ai spus
+		asta, aproape că ne-au omorât. Haide, nu a fost chiar așa de rău. Glumești?
+		Urcă în mașină. Mulțumesc pentru tot. Îmi pare rău pentru tatăl tău, a fost
+		un om bun. Nimeni nu știe ce s-a întâmplat în noaptea aceea. Vrei ceva de
+		băut? Da, te rog. De cât timp lucrezi aici? Hai să vorbim despre asta mâine.
+		E târziu și sunt foarte obosit. Ai văzut cheile mele? Nu-ți face griji, totul
+		va fi bine. O să le găsim. Te iubesc.`,
+	"hu": `Mit csinálsz itt? Mondtam, hogy maradj a kocsiban. Tudom, de nem
+		tudtam csak ülni és várni. Hol vannak a többiek? Egy órája elmentek. Most
+		kellene mennünk, mielőtt visszajönnek. Figyelj rám, bíznod kell bennem. Nem
+		hiszem, hogy ez jó ötlet. Miért nem? Mert amikor legutóbb ezt mondtad,
+		majdnem megöltek minket. Ugyan már, nem volt olyan szörnyű. Ugye csak viccelsz?
+		Szállj be a kocsiba. Köszönök mindent. Sajnálom az apádat, jó ember volt.
+		Senki sem tudja, mi történt azon az éjszakán. Kérsz valamit inni? Igen,
+		köszönöm. Mióta dolgozol itt? Beszéljünk erről holnap. Késő van, és nagyon
+		fáradt vagyok. Láttad a kulcsaimat? Ne aggódj, minden rendben lesz.`,
+	"tr": `Burada ne yapıyorsun? Sana arabada kalmanı söylemiştim. Biliyorum ama
+		orada öylece oturup bekleyemezdim. Herkes nerede? Bir saat önce gittiler.
+		Onlar geri gelmeden şimdi gitmeliyiz. Beni dinle, bana güvenmek zorundasın.
+		Bunun iyi bir fikir olduğunu sanmıyorum. Neden olmasın? Çünkü bunu en son
+		söylediğinde neredeyse öldürülüyorduk. Hadi ama, o kadar da kötü değildi.
+		Benimle dalga mı geçiyorsun? Sadece arabaya bin. Her şey için teşekkür
+		ederim. Baban için çok üzgünüm, iyi bir adamdı. O gece ne olduğunu kimse
+		bilmiyor. Bir şey içmek ister misin? Evet, lütfen. Ne zamandır burada
+		çalışıyorsun? Bunu yarın konuşalım. Geç oldu ve gerçekten çok yorgunum.
+		Anahtarlarımı gördün mü? Merak etme, her şey yoluna girecek.`,
+	"id": `Apa yang kau lakukan di sini? Sudah kubilang tetap di dalam mobil. Aku
+		tahu, tapi aku tidak bisa hanya duduk dan menunggu. Di mana semua orang?
+		Mereka pergi satu jam yang lalu. Kita harus pergi sekarang, sebelum mereka
+		kembali. Dengarkan aku, kau harus percaya padaku. Aku rasa itu bukan ide
+		yang bagus. Kenapa tidak? Karena terakhir kali kau bilang begitu, kita
+		hampir terbunuh. Ayolah, tidak seburuk itu. Kau bercanda? Masuk saja ke
+		mobil. Terima kasih untuk semuanya. Aku turut berduka atas ayahmu, dia orang
+		yang baik. Tidak ada yang tahu apa yang terjadi malam itu. Kau mau minum
+		sesuatu? Ya, tolong. Sudah berapa lama kau bekerja di sini? Kita bicarakan
+		besok saja. Sudah malam dan aku sangat lelah. Apa kau melihat kunciku?
+		Jangan khawatir, semuanya akan baik-baik saja. Kita akan menemukannya.`,
+	"vi": `Anh đang làm gì ở đây? Tôi đã bảo anh ở lại trong xe mà. Tôi biết,
+		nhưng tôi không thể cứ ngồi đó chờ đợi. Mọi người đâu hết rồi? Họ đi cách
+		đây một tiếng rồi. Chúng ta nên đi ngay bây giờ, trước khi họ quay lại.
+		Nghe tôi này, anh phải tin tôi. Tôi không nghĩ đó là ý hay. Tại sao không?
+		Vì lần trước anh nói vậy, chúng ta suýt bị giết. Thôi nào, đâu có tệ đến
+		thế. Anh đùa tôi à? Lên xe đi. Cảm ơn vì tất cả. Tôi rất tiếc về bố anh,
+		ông ấy là một người tốt. Không ai biết chuyện gì đã xảy ra đêm đó. Anh có
+		muốn uống gì không? Vâng, làm ơn. Anh làm việc ở đây bao lâu rồi? Để mai
+		chúng ta nói chuyện nhé. Muộn rồi và tôi thật sự mệt. Anh có thấy chìa khóa
+		của tôi không? Đừng lo, mọi chuyện sẽ ổn thôi.`,
+	"ru": `Что ты здесь делаешь? Я же сказал тебе оставаться в машине. Я знаю, но
+		я не могла просто сидеть и ждать. Где все? Они ушли час назад. Нам нужно
+		уходить сейчас, пока они не вернулись. Послушай меня, ты должна мне
+		доверять. Я не думаю, что это хорошая идея. Почему нет? Потому что в
+		прошлый раз, когда ты это сказал, нас чуть не убили. Да ладно, не так уж
+		всё было плохо. Ты издеваешься? Просто садись в машину. Спасибо за всё. Мне
+		очень жаль твоего отца, он был хорошим человеком. Никто не знает, что
+		случилось той ночью. Хочешь что-нибудь выпить? Да, пожалуйста. Как долго ты
+		здесь работаешь? Давай поговорим об этом завтра. Уже поздно, и я очень
+		устал. Ты не видел мои ключи? Не волнуйся, всё будет хорошо.`,
+	"uk": `Що ти тут робиш? Я ж казав тобі залишатися в машині. Я знаю, але я не
+		могла просто сидіти й чекати. Де всі? Вони пішли годину тому. Нам треба
+		йти зараз, поки вони не повернулися. Послухай мене, ти мусиш мені довіряти.
+		Я не думаю, що це гарна ідея. Чому ні? Тому що минулого разу, коли ти це
+		сказав, нас ледь не вбили. Та годі, не так уже й погано все було. Ти
+		знущаєшся? Просто сідай у машину. Дякую за все. Мені дуже шкода твого
+		батька, він був доброю людиною. Ніхто не знає, що сталося тієї ночі. Хочеш
+		щось випити? Так, будь ласка. Як довго ти тут працюєш? Давай поговоримо про
+		це завтра. Вже пізно, і я дуже втомився. Ти не бачив мої ключі? Не
+		хвилюйся, все буде добре. Ми їх знайдемо.`,
+	"bg": `Какво правиш тук? Казах ти да стоиш в колата. Знам, но не можех просто
+		да седя и да чакам. Къде са всички? Тръгнаха преди час. Трябва да тръгваме
+		веднага, преди да са се върнали. Слушай ме, трябва да ми вярваш. Не мисля,
+		че това е добра идея. Защо не? Защото последния път, когато каза това,
+		едва не ни убиха. Хайде де, не беше чак толкова зле. Майтапиш ли се? Просто
+		се качвай в колата. Благодаря ти за всичко. Съжалявам за баща ти, той беше
+		добър човек. Никой не знае какво се случи онази нощ. Искаш ли нещо за
+		пиене? Да, моля. Откога работиш тук? Нека да поговорим за това утре. Късно
+		е и съм много уморен. Виждал ли си ключовете ми? Не се тревожи, всичко ще
+		бъде наред. Ще ги намерим.`,
+	"ar": `ماذا تفعل هنا؟ قلت لك أن تبقى في السيارة. أعرف، لكنني لم أستطع الجلوس
+		هناك والانتظار فقط. أين الجميع؟ لقد غادروا قبل ساعة. يجب أن نذهب الآن قبل
+		أن يعودوا. اسمعني، عليك أن تثق بي. لا أعتقد أن هذه فكرة جيدة. لم لا؟ لأنه
+		في المرة الأخيرة التي قلت فيها ذلك كدنا نُقتل. هيا، لم يكن الأمر بهذا
+		السوء. هل تمزح معي؟ فقط اركب السيارة. شكراً لك على كل شيء. أنا آسف بشأن
+		والدك، لقد كان رجلاً طيباً. لا أحد يعرف ما حدث في تلك الليلة. هل تريد شيئاً
+		لتشربه؟ نعم، من فضلك. منذ متى وأنت تعمل هنا؟ لنتحدث عن ذلك غداً. الوقت
+		متأخر وأنا متعب حقاً. هل رأيت مفاتيحي؟ لا تقلق، كل شيء سيكون على ما يرام.`,
+	"fa": `اینجا چه کار می‌کنی؟ بهت گفتم توی ماشین بمونی. می‌دونم، ولی
+		نمی‌تونستم همینطوری بشینم و منتظر بمونم. بقیه کجان؟ یک ساعت پیش رفتن. باید
+		همین الان بریم، قبل از اینکه برگردن. به من گوش کن، باید به من اعتماد کنی.
+		فکر نمی‌کنم فکر خوبی باشه. چرا که نه؟ چون دفعه‌ی قبل که این رو گفتی
+		نزدیک بود کشته بشیم. بیخیال، اونقدرها هم بد نبود. شوخیت گرفته؟ فقط سوار
+		ماشین شو. بابت همه چیز ممنونم. بابت پدرت متاسفم، مرد خوبی بود. هیچکس
+		نمی‌دونه اون شب چه اتفاقی افتاد. چیزی می‌خوای بنوشی؟ آره، لطفاً. چند وقته
+		اینجا کار می‌کنی؟ بذار فردا درباره‌اش حرف بزنیم. دیروقته و من واقعاً
+		خسته‌ام. کلیدهای منو ندیدی؟ نگران نباش، همه چیز درست میشه.`,
+}
@@ -93,6 +93,20 @@ func (s *subtitleList) HearingImpaired(hi bool) types.SubtitleList {
 	return &list
 }
 
+// Completed returns a new subtitle collection where subtitles which are not
+// completely translated yet has been filtered
+func (s *subtitleList) Completed() types.SubtitleList {
+	_subs := make([]types.Subtitle, 0)
+	for _, sub := range *s {
+		if c, ok := sub.(types.Completable); ok && !c.Completed() {
+			continue
+		}
+		_subs = append(_subs, sub)
+	}
+	list := subtitleList(_subs)
+	return &list
+}
+
 // RateByMedia returns a rated subtitle list, where every subtitle has been
 // given a score according to how well it matches the argument media
 func (s *subtitleList) RateByMedia(m types.Media, e types.Evaluator) types.RatedSubtitleList {
@@ -79,6 +79,22 @@ func TestSubtitleList(t *testing.T) {
 
 }
 
+// incompleteSubtitle is a subtitle which is not completely translated yet
+type incompleteSubtitle struct {
+	subtitle
+}
+
+func (s incompleteSubtitle) Completed() bool { return false }
+
+func TestSubtitleListCompleted(t *testing.T) {
+	incomplete := incompleteSubtitle{subtitle{inception, language.English, false}}
+	subs := Subtitles(append([]types.Subtitle{incomplete}, languages...)...)
+
+	f := subs.Completed()
+	assert.Equal(t, len(languages), f.Len())
+	assert.NotContains(t, f.List(), incomplete)
+}
+
 func TestSubtitlesFromInterface(t *testing.T) {
 	list, err := NewSubtitlesFromInterface(languages)
 	require.NoError(t, err)
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/tympanix/supper/media/parse"
+	"github.com/tympanix/supper/media/subformat"
 	"github.com/tympanix/supper/types"
 )
 
@@ -21,9 +22,7 @@ var videoExt = []string{
 }
 
 // subExt contains the list of recognizable subtitle extensions
-var subExt = []string{
-	".srt",
-}
+var subExt = subformat.Extensions()
 
 func fileIsVideo(name string) bool {
 	for _, ext := range videoExt {
@@ -60,6 +59,22 @@ func NewLocalFile(path string) (types.LocalMedia, error) {
 		return nil, err
 	}
 
+	return newLocalFile(path, f, media), nil
+}
+
+// NewLocalFileInfo parses a filepath into a local media object, using the
+// given file information instead of reading it from disk
+func NewLocalFileInfo(path string, info os.FileInfo) (types.LocalMedia, error) {
+	media, err := NewFromFilename(filepath.Base(path))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newLocalFile(path, info, media), nil
+}
+
+func newLocalFile(path string, f os.FileInfo, media types.Media) types.LocalMedia {
 	file := &File{
 		FileInfo: f,
 		Media:    media,
@@ -67,15 +82,15 @@ func NewLocalFile(path string) (types.LocalMedia, error) {
 	}
 
 	if v, ok := file.Media.(Subtitlable); ok && v.IsVideo() {
-		return NewVideo(file), nil
+		return NewVideo(file)
 	}
 
-	return file, nil
+	return file
 }
 
 // NewFromFilename parses the filename and returns a media object. The filename
 // (with extenstion) may describe either some video material (.avi, .mkv, .mp4)
-// or a subtitle (.srt).
+// or a subtitle (.srt, .ass, .vtt).
 func NewFromFilename(name string) (types.Media, error) {
 	filename := parse.Filename(name)
 	if fileIsVideo(name) {
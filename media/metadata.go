@@ -27,6 +27,20 @@ func ParseMetadata(tags string) Metadata {
 	return m
 }
 
+// ParseRelease generates metadata from a release description as found on
+// subtitle sites, e.g. "KILLERS, 720p HDTV", where the release group is listed
+// first followed by optional tags
+func ParseRelease(release string) Metadata {
+	parts := strings.Split(release, ",")
+	m := ParseMetadata(strings.Join(parts, " "))
+	m.group = ""
+	name := strings.TrimSpace(parts[0])
+	if g := parse.Group(name); g == name {
+		m.group = g
+	}
+	return m
+}
+
 // ParseMetadataIndex generates metadata from a string and returns the index
 // of the first occurrence of metdata information in the string
 func ParseMetadataIndex(tags string) (int, Metadata) {
@@ -47,3 +47,17 @@ func TestMetadataJSON(t *testing.T) {
 	assert.Equal(t, j.Codec, codec.X264.String())
 	assert.Equal(t, j.Group, "GROUP")
 }
+
+func TestParseRelease(t *testing.T) {
+	m := ParseRelease("KILLERS, 720p HDTV")
+	assert.Equal(t, "KILLERS", m.Group())
+	assert.Equal(t, quality.HD720p, m.Quality())
+	assert.Equal(t, source.HDTV, m.Source())
+
+	m = ParseRelease("DIMENSION")
+	assert.Equal(t, "DIMENSION", m.Group())
+
+	m = ParseRelease("WEB-DL")
+	assert.Equal(t, "", m.Group())
+	assert.Equal(t, source.WEBDL, m.Source())
+}
@@ -0,0 +1,334 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/apex/log"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/parse"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// addic7edHost is the URL for addic7ed
+const addic7edHost = "https://www.addic7ed.com"
+
+var addic7edClient = NewAPIClient("Addic7ed", 2, time.Hour)
+
+// addic7edEpisode matches episode links of the form /serie/{show}/{season}/{episode}/{title}
+var addic7edEpisode = regexp.MustCompile(`^/serie/[^/]+/(\d+)/(\d+)/([^/]*)`)
+
+var addic7edVersion = regexp.MustCompile(`(?i)^\s*version\s+`)
+
+// addic7edShowsTTL is how long the list of shows on addic7ed is kept before
+// it is fetched again
+const addic7edShowsTTL = 24 * time.Hour
+
+// Addic7ed interfaces with addic7ed.com for downloading TV subtitles. Movies
+// are not supported
+func Addic7ed() types.Provider {
+	return &addic7ed{
+		client: addic7edClient,
+		host:   addic7edHost,
+	}
+}
+
+type addic7ed struct {
+	client *APIClient
+	host   string
+
+	mu      sync.Mutex
+	shows   map[string]string
+	fetched time.Time
+}
+
+func (a *addic7ed) Name() string {
+	return "addic7ed"
+}
+
+func (a *addic7ed) url(p string) string {
+	return strings.TrimSuffix(a.host, "/") + p
+}
+
+func (a *addic7ed) document(ctx context.Context, p string) (*goquery.Document, error) {
+	resp, err := a.client.GetContext(ctx, a.url(p))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("addic7ed returned status code %v", resp.StatusCode)
+	}
+
+	return goquery.NewDocumentFromReader(resp.Body)
+}
+
+// listShows returns the paths of the show pages on addic7ed by the identity
+// of the show titles. The list is fetched once and kept for a while, since
+// it is the same for every episode
+func (a *addic7ed) listShows(ctx context.Context) (map[string]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.shows != nil && time.Since(a.fetched) < addic7edShowsTTL {
+		return a.shows, nil
+	}
+
+	doc, err := a.document(ctx, "/shows.php")
+
+	if err != nil {
+		return nil, err
+	}
+
+	shows := make(map[string]string)
+	doc.Find(`a[href^="/show/"]`).Each(func(i int, s *goquery.Selection) {
+		ident := parse.Identity(cleanRegexp.ReplaceAllString(s.Text(), ""))
+		if _, exists := shows[ident]; exists {
+			return
+		}
+		if path, ok := s.Attr("href"); ok {
+			shows[ident] = path
+		}
+	})
+
+	a.shows, a.fetched = shows, time.Now()
+	return shows, nil
+}
+
+// findShow returns the path of the show page for the TV show
+func (a *addic7ed) findShow(ctx context.Context, show string) (string, error) {
+	shows, err := a.listShows(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	path, ok := shows[parse.Identity(cleanRegexp.ReplaceAllString(show, ""))]
+
+	if !ok {
+		return "", fmt.Errorf("could not find show %v on addic7ed", show)
+	}
+
+	return path, nil
+}
+
+// findEpisode returns the path of the episode page as well as the episode
+// title from the season listing of the show
+func (a *addic7ed) findEpisode(ctx context.Context, show string, e types.Episode) (string, string, error) {
+	q := url.Values{}
+	q.Set("season", strconv.Itoa(e.Season()))
+
+	doc, err := a.document(ctx, fmt.Sprintf("%s?%s", show, q.Encode()))
+
+	if err != nil {
+		return "", "", err
+	}
+
+	var path, title string
+	doc.Find(`a[href^="/serie/"]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		href, _ := s.Attr("href")
+		m := addic7edEpisode.FindStringSubmatch(href)
+		if m == nil {
+			return true
+		}
+		season, _ := strconv.Atoi(m[1])
+		episode, _ := strconv.Atoi(m[2])
+		if season == e.Season() && episode == e.Episode() {
+			path = href
+			title = strings.TrimSpace(s.Text())
+			return false
+		}
+		return true
+	})
+
+	if path == "" {
+		return "", "", fmt.Errorf("could not find episode %v on addic7ed", e)
+	}
+
+	return path, title, nil
+}
+
+// SearchSubtitles searches addic7ed.com for subtitles by navigating the show,
+// season and episode pages. Subtitles which are not completely translated
+// yet are included, but are not Completed
+func (a *addic7ed) SearchSubtitles(local types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	return a.SearchSubtitlesContext(context.Background(), local)
+}
+
+// SearchSubtitlesContext searches addic7ed.com for subtitles until the
+// context is cancelled
+func (a *addic7ed) SearchSubtitlesContext(ctx context.Context, local types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	episode, ok := local.TypeEpisode()
+
+	if !ok {
+		return nil, mediaNotSupported("addic7ed")
+	}
+
+	show, err := a.findShow(ctx, episode.TVShow())
+
+	if err != nil {
+		return nil, err
+	}
+
+	path, title, err := a.findEpisode(ctx, show, episode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithField("uri", path).Debug("Best match addic7ed.com")
+
+	doc, err := a.document(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	subs := make([]types.OnlineSubtitle, 0)
+
+	doc.Find("table.tabel95").Each(func(i int, t *goquery.Selection) {
+		version := t.Find("td.NewsTitle").First().Text()
+
+		if version == "" {
+			return
+		}
+
+		release := addic7edVersion.ReplaceAllString(strings.TrimSpace(version), "")
+
+		t.Find("td.language").Each(func(i int, s *goquery.Selection) {
+			row := s.Parent()
+
+			href, exists := row.Find("a.buttonDownload").First().Attr("href")
+
+			if !exists {
+				return
+			}
+
+			lang, err := parse.Language(strings.TrimSpace(s.Text()))
+
+			if err != nil {
+				return
+			}
+
+			sub := &addic7edSubtitle{
+				Media: &media.Episode{
+					Metadata:     media.ParseRelease(release),
+					NameX:        episode.TVShow(),
+					EpisodeNameX: title,
+					SeasonX:      episode.Season(),
+					EpisodeX:     episode.Episode(),
+				},
+				addic7edLink: addic7edLink{
+					provider: a,
+					path:     href,
+					referer:  a.url(path),
+				},
+				lang:      lang,
+				hi:        row.Next().Find(`img[title="Hearing Impaired"]`).Length() > 0,
+				completed: strings.EqualFold(strings.TrimSpace(row.Find("b").First().Text()), "completed"),
+			}
+
+			subs = append(subs, sub)
+		})
+	})
+
+	return subs, nil
+}
+
+func (a *addic7ed) ResolveSubtitle(l types.Linker) (types.Downloadable, error) {
+	if !strings.HasPrefix(l.Link(), "/") {
+		return nil, fmt.Errorf("addic7ed: invalid link %v", l.Link())
+	}
+	return addic7edLink{a, l.Link(), ""}, nil
+}
+
+// download retrieves the subtitle file. Addic7ed requires a referer, and
+// responds with a html page when the download limit has been exceeded
+func (a *addic7ed) download(path string, referer string) (io.ReadCloser, error) {
+	req, err := newDownloadRequest(a.url(path))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if referer == "" {
+		referer = a.url("/")
+	}
+
+	req.Header.Set("Referer", referer)
+
+	resp, err := a.client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("addic7ed download subtitle (%v)", resp.StatusCode)
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		resp.Body.Close()
+		return nil, errors.New("addic7ed download limit exceeded")
+	}
+
+	return resp.Body, nil
+}
+
+type addic7edLink struct {
+	provider *addic7ed
+	path     string
+	referer  string
+}
+
+func (l addic7edLink) Link() string {
+	return l.path
+}
+
+func (l addic7edLink) Download() (io.ReadCloser, error) {
+	return l.provider.download(l.path, l.referer)
+}
+
+type addic7edSubtitle struct {
+	types.Media
+	addic7edLink
+	lang      language.Tag
+	hi        bool
+	completed bool
+}
+
+func (s *addic7edSubtitle) String() string {
+	return display.English.Languages().Name(s.Language())
+}
+
+func (s *addic7edSubtitle) ForMedia() types.Media {
+	return s.Media
+}
+
+func (s *addic7edSubtitle) Language() language.Tag {
+	return s.lang
+}
+
+func (s *addic7edSubtitle) HearingImpaired() bool {
+	return s.hi
+}
+
+// Completed returns true if the translation of the subtitle is finished
+func (s *addic7edSubtitle) Completed() bool {
+	return s.completed
+}
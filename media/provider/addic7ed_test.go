@@ -0,0 +1,224 @@
+package provider
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/meta/quality"
+	"github.com/tympanix/supper/media/meta/source"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+func newAddic7edServer(t *testing.T) *httptest.Server {
+	server, _ := newCountingAddic7edServer(t)
+	return server
+}
+
+// newCountingAddic7edServer returns a fake addic7ed server, which counts the
+// number of times the list of shows is requested
+func newCountingAddic7edServer(t *testing.T) (*httptest.Server, *int32) {
+	mux := http.NewServeMux()
+	var shows int32
+
+	fixture := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			http.ServeFile(w, r, filepath.Join("test", "addic7ed", name))
+		}
+	}
+
+	mux.HandleFunc("/shows.php", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&shows, 1)
+		fixture("shows.html")(w, r)
+	})
+
+	mux.HandleFunc("/show/1245", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("season") != "2" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fixture("season.html")(w, r)
+	})
+
+	mux.HandleFunc("/serie/Game_of_Thrones/2/5/The_Ghost_of_Harrenhal", fixture("episode.html"))
+
+	mux.HandleFunc("/original/12345/0", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Referer") == "" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/srt")
+		w.Write([]byte("1\n00:00:01,000 --> 00:00:02,000\nWinter is coming\n"))
+	})
+
+	mux.HandleFunc("/original/12345/2", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html>Daily download count exceeded</html>"))
+	})
+
+	return httptest.NewServer(mux), &shows
+}
+
+func newTestAddic7ed(host string) *addic7ed {
+	return &addic7ed{
+		client: NewAPIClient("Addic7edTest", 100, 0),
+		host:   host,
+	}
+}
+
+func newTestLocalMedia(t *testing.T, dir string, name string) types.LocalMedia {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte("video"), 0644))
+	local, err := media.NewLocalFile(path)
+	require.NoError(t, err)
+	return local
+}
+
+func TestAddic7edSearch(t *testing.T) {
+	server := newAddic7edServer(t)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	local := newTestLocalMedia(t, dir, "Game.of.Thrones.S02E05.720p.HDTV.x264-KILLERS.mkv")
+
+	p := newTestAddic7ed(server.URL)
+
+	subs, err := p.SearchSubtitles(local)
+	require.NoError(t, err)
+	require.Len(t, subs, 4)
+
+	assert.Equal(t, "/original/12345/0", subs[0].Link())
+	assert.Equal(t, language.English, subs[0].Language())
+	assert.False(t, subs[0].HearingImpaired())
+	assert.True(t, subs[0].(*addic7edSubtitle).Completed())
+
+	meta := subs[0].ForMedia().Meta()
+	assert.Equal(t, "KILLERS", meta.Group())
+	assert.Equal(t, quality.HD720p, meta.Quality())
+	assert.Equal(t, source.HDTV, meta.Source())
+
+	episode, ok := subs[0].ForMedia().TypeEpisode()
+	require.True(t, ok)
+	assert.Equal(t, "Game of Thrones", episode.TVShow())
+	assert.Equal(t, "The Ghost of Harrenhal", episode.EpisodeName())
+	assert.Equal(t, 2, episode.Season())
+	assert.Equal(t, 5, episode.Episode())
+
+	assert.Equal(t, "/original/12345/1", subs[1].Link())
+	assert.False(t, subs[1].(types.Completable).Completed())
+
+	assert.Equal(t, "/original/12345/2", subs[2].Link())
+	assert.True(t, subs[2].HearingImpaired())
+	assert.True(t, subs[2].(types.Completable).Completed())
+
+	meta = subs[2].ForMedia().Meta()
+	assert.Equal(t, "", meta.Group())
+	assert.Equal(t, quality.HD1080p, meta.Quality())
+	assert.Equal(t, source.WEBDL, meta.Source())
+
+	assert.Equal(t, language.French, subs[3].Language())
+	assert.False(t, subs[3].HearingImpaired())
+}
+
+func TestAddic7edShowsCached(t *testing.T) {
+	server, shows := newCountingAddic7edServer(t)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := newTestAddic7ed(server.URL)
+
+	_, err = p.SearchSubtitles(newTestLocalMedia(t, dir, "Game.of.Thrones.S02E05.720p.HDTV.x264-KILLERS.mkv"))
+	require.NoError(t, err)
+
+	_, err = p.SearchSubtitles(newTestLocalMedia(t, dir, "Unknown.Show.S01E01.720p.mkv"))
+	assert.Error(t, err)
+
+	_, err = p.SearchSubtitles(newTestLocalMedia(t, dir, "Game.of.Thrones.S02E05.1080p.WEB-DL.mkv"))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(shows))
+
+	p.fetched = p.fetched.Add(-addic7edShowsTTL)
+
+	_, err = p.SearchSubtitles(newTestLocalMedia(t, dir, "Game.of.Thrones.S02E05.720p.HDTV.x264-KILLERS.mkv"))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(shows))
+}
+
+func TestAddic7edNotFound(t *testing.T) {
+	server := newAddic7edServer(t)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := newTestAddic7ed(server.URL)
+
+	_, err = p.SearchSubtitles(newTestLocalMedia(t, dir, "Unknown.Show.S01E01.720p.mkv"))
+	assert.Error(t, err)
+
+	_, err = p.SearchSubtitles(newTestLocalMedia(t, dir, "Game.of.Thrones.S02E11.720p.mkv"))
+	assert.Error(t, err)
+}
+
+func TestAddic7edMovieNotSupported(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := newTestAddic7ed("http://localhost")
+
+	_, err = p.SearchSubtitles(newTestLocalMedia(t, dir, "Inception.2010.720p.BluRay.mkv"))
+	require.Error(t, err)
+	assert.True(t, IsErrMediaNotSupported(err))
+}
+
+func TestAddic7edDownload(t *testing.T) {
+	c := useTestCache(t)
+	defer resetTestCache()
+
+	server := newAddic7edServer(t)
+	defer server.Close()
+
+	p := newTestAddic7ed(server.URL)
+
+	dl, err := p.ResolveSubtitle(testLink("/original/12345/0"))
+	require.NoError(t, err)
+
+	r, err := dl.Download()
+	require.NoError(t, err)
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Winter is coming")
+
+	dl, err = p.ResolveSubtitle(testLink("/original/12345/2"))
+	require.NoError(t, err)
+
+	_, err = dl.Download()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limit exceeded")
+
+	// the page shown when the download limit is exceeded must not be cached
+	assert.Empty(t, c.entries)
+
+	_, err = p.ResolveSubtitle(testLink("12345"))
+	assert.Error(t, err)
+}
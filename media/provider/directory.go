@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/extract"
+	"github.com/tympanix/supper/media/parse"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+// directoryRefresh is the duration after which the directory is indexed again
+const directoryRefresh = 5 * time.Minute
+
+// Directory is a provider which serves subtitles from a local directory. Every
+// subtitle in the directory, including subtitles inside zip and rar archives,
+// is indexed and matched against the media by its filename
+func Directory(root string) types.Provider {
+	return &directory{
+		root: filepath.Clean(root),
+	}
+}
+
+type directory struct {
+	root string
+
+	mu      sync.Mutex
+	entries []directoryEntry
+	indexed time.Time
+}
+
+type directoryEntry struct {
+	types.Subtitle
+	path  string
+	entry string
+}
+
+func (d *directory) Name() string {
+	return "directory"
+}
+
+// Offline returns true since the directory is stored locally
+func (d *directory) Offline() bool {
+	return true
+}
+
+// index returns the subtitles in the directory, walking the directory if
+// the index is missing or outdated
+func (d *directory) index() ([]directoryEntry, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.entries != nil && time.Since(d.indexed) < directoryRefresh {
+		return d.entries, nil
+	}
+
+	entries := make([]directoryEntry, 0)
+
+	err := filepath.Walk(d.root, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.IsDir() {
+			return nil
+		}
+		archive, err := extract.OpenMediaArchive(path)
+		if extract.IsNotArchive(err) {
+			if e, ok := d.entry(path, "", f.Name(), language.Und); ok {
+				entries = append(entries, e)
+			}
+			return nil
+		}
+		if err != nil {
+			log.WithError(err).WithField("path", path).Warn("Could not open subtitle archive")
+			return nil
+		}
+		defer archive.Close()
+
+		// Subtitles in archives may rely on the archive name for the language
+		var lang language.Tag
+		if sub, err := media.NewSubtitle(parse.Filename(path)); err == nil {
+			lang = sub.Language()
+		}
+
+		for {
+			m, err := archive.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.WithError(err).WithField("path", path).Warn("Could not read subtitle archive")
+				break
+			}
+			if e, ok := d.entry(path, m.Name(), m.Name(), lang); ok {
+				entries = append(entries, e)
+			}
+			m.Close()
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithField("path", d.root).WithField("subtitles", len(entries)).
+		Debug("Indexed subtitle directory")
+
+	d.entries = entries
+	d.indexed = time.Now()
+
+	return d.entries, nil
+}
+
+// entry parses the filename of a subtitle into an index entry. The fallback
+// language is used if no language could be parsed from the filename
+func (d *directory) entry(path string, entry string, name string, fallback language.Tag) (directoryEntry, bool) {
+	m, err := media.NewFromFilename(name)
+
+	if err != nil {
+		return directoryEntry{}, false
+	}
+
+	sub, ok := m.TypeSubtitle()
+
+	if !ok {
+		return directoryEntry{}, false
+	}
+
+	if sub.Language() == language.Und {
+		if fallback == language.Und {
+			return directoryEntry{}, false
+		}
+		sub = directorySubtitleLanguage{sub, fallback}
+	}
+
+	return directoryEntry{sub, path, entry}, true
+}
+
+// SearchSubtitles returns the subtitles in the directory for the same media
+func (d *directory) SearchSubtitles(local types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	if _, ok := local.TypeSubtitle(); ok {
+		return nil, mediaNotSupported("directory")
+	}
+
+	entries, err := d.index()
+
+	if err != nil {
+		return nil, err
+	}
+
+	subs := make([]types.OnlineSubtitle, 0)
+
+	for _, e := range entries {
+		if e.ForMedia().Identity() != local.Identity() {
+			continue
+		}
+		subs = append(subs, &directorySubtitle{
+			Subtitle:      e.Subtitle,
+			directoryLink: directoryLink{e.path, e.entry},
+		})
+	}
+
+	return subs, nil
+}
+
+// ResolveSubtitle resolves a file link to a subtitle in the directory. Links
+// outside of the directory are rejected
+func (d *directory) ResolveSubtitle(l types.Linker) (types.Downloadable, error) {
+	u, err := url.Parse(l.Link())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "file" {
+		return nil, fmt.Errorf("directory: invalid link %v", l.Link())
+	}
+
+	path := filepath.FromSlash(u.Path)
+	rel, err := filepath.Rel(d.root, path)
+
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("directory: link outside of %v", d.root)
+	}
+
+	return directoryLink{path, u.Fragment}, nil
+}
+
+// directoryLink is a link to a subtitle file, or a subtitle inside an archive
+// if entry is non-empty
+type directoryLink struct {
+	path  string
+	entry string
+}
+
+func (l directoryLink) Link() string {
+	u := url.URL{
+		Scheme:   "file",
+		Path:     filepath.ToSlash(l.path),
+		Fragment: l.entry,
+	}
+	return u.String()
+}
+
+func (l directoryLink) Download() (io.ReadCloser, error) {
+	if l.entry == "" {
+		return os.Open(l.path)
+	}
+
+	archive, err := extract.OpenMediaArchive(l.path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		m, err := archive.Next()
+		if err != nil {
+			archive.Close()
+			if err == io.EOF {
+				err = fmt.Errorf("directory: %v not found in %v", l.entry, l.path)
+			}
+			return nil, err
+		}
+		if m.Name() == l.entry {
+			return &archiveEntry{m, archive}, nil
+		}
+		m.Close()
+	}
+}
+
+// archiveEntry is a file inside an archive, which closes the archive as well
+type archiveEntry struct {
+	io.ReadCloser
+	archive io.Closer
+}
+
+func (a *archiveEntry) Close() error {
+	err := a.ReadCloser.Close()
+	if cerr := a.archive.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type directorySubtitle struct {
+	types.Subtitle
+	directoryLink
+}
+
+func (s *directorySubtitle) String() string {
+	return fmt.Sprint(s.Subtitle)
+}
+
+// directorySubtitleLanguage overrides the language of a subtitle
+type directorySubtitleLanguage struct {
+	types.Subtitle
+	lang language.Tag
+}
+
+func (s directorySubtitleLanguage) Language() language.Tag {
+	return s.lang
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+func writeZipTestFile(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, data := range files {
+		e, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = e.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+}
+
+func newTestDirectory(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+
+	sub := filepath.Join(dir, "movies")
+	require.NoError(t, os.Mkdir(sub, 0755))
+
+	files := map[string]string{
+		"Inception.2010.720p.BluRay.en.srt":  "english",
+		"movies/Inception.2010.1080p.da.srt": "danish",
+		"Fight.Club.1999.720p.en.srt":        "other",
+		"Inception.2010.720p.srt":            "no language",
+		"notes.txt":                          "not a subtitle",
+	}
+
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, ioutil.WriteFile(path, []byte(data), 0644))
+	}
+
+	writeZipTestFile(t, filepath.Join(sub, "Inception.2010.de.zip"), map[string]string{
+		"Inception.2010.720p.BluRay.srt": "german",
+		"Inception.2010.720p.BluRay.mkv": "not a subtitle",
+	})
+
+	return dir
+}
+
+func TestDirectorySearch(t *testing.T) {
+	dir := newTestDirectory(t)
+	defer os.RemoveAll(dir)
+
+	videos, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(videos)
+
+	local := newTestLocalMedia(t, videos, "Inception (2010) 720p.mkv")
+
+	p := Directory(dir)
+
+	subs, err := p.SearchSubtitles(local)
+	require.NoError(t, err)
+	require.Len(t, subs, 3)
+
+	langs := make(map[language.Tag]string)
+	for _, s := range subs {
+		dl, err := p.ResolveSubtitle(testLink(s.Link()))
+		require.NoError(t, err)
+
+		r, err := dl.Download()
+		require.NoError(t, err)
+
+		data, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+
+		langs[s.Language()] = string(data)
+	}
+
+	assert.Equal(t, "english", langs[language.English])
+	assert.Equal(t, "danish", langs[language.Danish])
+	assert.Equal(t, "german", langs[language.German])
+}
+
+func TestDirectoryResolve(t *testing.T) {
+	dir := newTestDirectory(t)
+	defer os.RemoveAll(dir)
+
+	p := Directory(dir)
+
+	_, err := p.ResolveSubtitle(testLink("file://" + filepath.ToSlash(filepath.Join(dir, "notes.txt"))))
+	assert.NoError(t, err)
+
+	_, err = p.ResolveSubtitle(testLink("file:///etc/passwd"))
+	assert.Error(t, err)
+
+	_, err = p.ResolveSubtitle(testLink("file://" + filepath.ToSlash(filepath.Join(dir, "..", "passwd"))))
+	assert.Error(t, err)
+
+	_, err = p.ResolveSubtitle(testLink("https://example.com/subtitle.srt"))
+	assert.Error(t, err)
+
+	dl, err := p.ResolveSubtitle(testLink("file://" + filepath.ToSlash(filepath.Join(dir, "movies", "Inception.2010.de.zip")) + "#missing.srt"))
+	require.NoError(t, err)
+
+	_, err = dl.Download()
+	assert.Error(t, err)
+}
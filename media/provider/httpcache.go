@@ -0,0 +1,227 @@
+package provider
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/tympanix/supper/types"
+)
+
+// httpBucket is the cache bucket for http responses
+const httpBucket = "http"
+
+// httpMaxCached is the maximum size of response bodies which are cached
+const httpMaxCached = 2 << 20
+
+// formContentType is the content type of url encoded form posts
+const formContentType = "application/x-www-form-urlencoded"
+
+var httpCache struct {
+	sync.RWMutex
+	cache   types.Cache
+	offline bool
+}
+
+// UseCache sets up the cache for http responses of every APIClient. Caching is
+// disabled if the cache is nil
+func UseCache(c types.Cache) {
+	httpCache.Lock()
+	defer httpCache.Unlock()
+	httpCache.cache = c
+}
+
+// SetOffline enables or disables offline mode. In offline mode responses are
+// served from the cache only, and requests which are not cached fail
+func SetOffline(offline bool) {
+	httpCache.Lock()
+	defer httpCache.Unlock()
+	httpCache.offline = offline
+}
+
+// Offline returns true if offline mode is enabled
+func Offline() bool {
+	httpCache.RLock()
+	defer httpCache.RUnlock()
+	return httpCache.offline
+}
+
+func currentCache() types.Cache {
+	httpCache.RLock()
+	defer httpCache.RUnlock()
+	return httpCache.cache
+}
+
+// cachedResponse is the representation of a http response in the cache
+type cachedResponse struct {
+	Status     string      `json:"status"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// cacheKey returns the key used to cache the response of the request. Get
+// requests are cached, as well as form posts which sites use for searching.
+// An empty key is returned if the request can not be cached, or if the request
+// forbids caching with the no-store directive
+func cacheKey(req *http.Request) string {
+	if hasDirective(req.Header, "no-store") {
+		return ""
+	}
+
+	key := req.Method + " " + req.URL.String()
+
+	switch req.Method {
+	case http.MethodGet:
+		return key
+	case http.MethodPost:
+		if req.Header.Get("Content-Type") != formContentType || req.GetBody == nil {
+			return ""
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return ""
+		}
+		defer body.Close()
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return ""
+		}
+		return key + " " + string(data)
+	}
+	return ""
+}
+
+// hasDirective returns true if the Cache-Control header has the directive
+func hasDirective(h http.Header, directive string) bool {
+	for _, d := range strings.Split(h.Get("Cache-Control"), ",") {
+		if strings.ToLower(strings.TrimSpace(d)) == directive {
+			return true
+		}
+	}
+	return false
+}
+
+// newDownloadRequest returns a get request for downloading a subtitle. Downloads
+// are never cached, since sites may respond with an error page instead of the
+// subtitle, e.g. when the download limit has been exceeded
+func newDownloadRequest(url string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Cache-Control", "no-store")
+	return req, nil
+}
+
+// cacheTTL returns the duration for which the response may be cached, using
+// the cache headers of the response and otherwise the default duration
+func cacheTTL(resp *http.Response, def time.Duration) time.Duration {
+	for _, d := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		switch {
+		case d == "no-store" || d == "no-cache":
+			return 0
+		case strings.HasPrefix(d, "max-age="):
+			if age, err := strconv.Atoi(strings.TrimPrefix(d, "max-age=")); err == nil {
+				return time.Duration(age) * time.Second
+			}
+		}
+	}
+
+	if exp := resp.Header.Get("Expires"); exp != "" {
+		t, err := http.ParseTime(exp)
+		if err != nil {
+			return 0
+		}
+		return time.Until(t)
+	}
+
+	return def
+}
+
+// cached returns the cached response for the request, or nil if not cached
+func (a *APIClient) cached(key string, req *http.Request) *http.Response {
+	c := currentCache()
+
+	if c == nil || key == "" {
+		return nil
+	}
+
+	var cr cachedResponse
+	ok, err := c.Get(httpBucket, key, &cr)
+
+	if err != nil {
+		log.WithError(err).WithField("client", a.name).Warn("Could not read http cache")
+		return nil
+	} else if !ok {
+		return nil
+	}
+
+	return &http.Response{
+		Status:        cr.Status,
+		StatusCode:    cr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cr.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+		Request:       req,
+	}
+}
+
+// store caches the response if possible, and returns a response which can be
+// read by the caller
+func (a *APIClient) store(key string, resp *http.Response) (*http.Response, error) {
+	c := currentCache()
+
+	if c == nil || key == "" || resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	ttl := cacheTTL(resp, a.ttl)
+
+	if ttl <= 0 || resp.ContentLength > httpMaxCached {
+		return resp, nil
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, httpMaxCached+1))
+
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	if len(data) > httpMaxCached {
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+		return resp, nil
+	}
+
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	err = c.Put(httpBucket, key, cachedResponse{
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       data,
+	}, ttl)
+
+	if err != nil {
+		log.WithError(err).WithField("client", a.name).Warn("Could not write http cache")
+	}
+
+	return resp, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,234 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryCache is an in-memory cache for testing purposes
+type memoryCache struct {
+	sync.Mutex
+	entries map[string][]byte
+	ttls    map[string]time.Duration
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{
+		entries: make(map[string][]byte),
+		ttls:    make(map[string]time.Duration),
+	}
+}
+
+func (c *memoryCache) Get(bucket string, key string, v interface{}) (bool, error) {
+	c.Lock()
+	defer c.Unlock()
+	data, ok := c.entries[bucket+key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+func (c *memoryCache) Put(bucket string, key string, v interface{}, ttl time.Duration) error {
+	c.Lock()
+	defer c.Unlock()
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.entries[bucket+key] = data
+	c.ttls[bucket+key] = ttl
+	return nil
+}
+
+func useTestCache(t *testing.T) *memoryCache {
+	c := newMemoryCache()
+	UseCache(c)
+	return c
+}
+
+func resetTestCache() {
+	UseCache(nil)
+	SetOffline(false)
+}
+
+func newCountingServer(header http.Header) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		r.ParseForm()
+		fmt.Fprintf(w, "%v %v", r.Method, r.Form.Get("query"))
+	}))
+	return server, &calls
+}
+
+func readTestBody(t *testing.T, resp *http.Response) string {
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestAPIClientCache(t *testing.T) {
+	c := useTestCache(t)
+	defer resetTestCache()
+
+	server, calls := newCountingServer(nil)
+	defer server.Close()
+
+	client := NewAPIClient("Test", 100, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL + "/page")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "GET ", readTestBody(t, resp))
+	}
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, time.Hour, c.ttls[httpBucket+"GET "+server.URL+"/page"])
+
+	for _, q := range []string{"foo", "bar", "foo"} {
+		resp, err := client.PostForm(server.URL+"/search", url.Values{"query": {q}})
+		require.NoError(t, err)
+		assert.Equal(t, "POST "+q, readTestBody(t, resp))
+	}
+
+	assert.Equal(t, 3, *calls)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(server.URL+"/login", "application/json", nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, 5, *calls)
+}
+
+func TestAPIClientDownloadNotCached(t *testing.T) {
+	c := useTestCache(t)
+	defer resetTestCache()
+
+	server, calls := newCountingServer(nil)
+	defer server.Close()
+
+	client := NewAPIClient("Test", 100, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		req, err := newDownloadRequest(server.URL + "/subtitle.srt")
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, "GET ", readTestBody(t, resp))
+	}
+
+	assert.Equal(t, 2, *calls)
+	assert.Empty(t, c.entries)
+}
+
+func TestAPIClientCacheHeaders(t *testing.T) {
+	c := useTestCache(t)
+	defer resetTestCache()
+
+	client := NewAPIClient("Test", 100, time.Hour)
+
+	server, calls := newCountingServer(http.Header{
+		"Cache-Control": {"public, max-age=60"},
+	})
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, time.Minute, c.ttls[httpBucket+"GET "+server.URL])
+
+	nostore, calls := newCountingServer(http.Header{
+		"Cache-Control": {"no-store"},
+	})
+	defer nostore.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(nostore.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, 2, *calls)
+
+	expired, calls := newCountingServer(http.Header{
+		"Expires": {time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)},
+	})
+	defer expired.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(expired.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, 2, *calls)
+}
+
+func TestAPIClientNoDefaultTTL(t *testing.T) {
+	useTestCache(t)
+	defer resetTestCache()
+
+	server, calls := newCountingServer(nil)
+	defer server.Close()
+
+	client := NewAPIClient("Test", 100, 0)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, 2, *calls)
+}
+
+func TestAPIClientOffline(t *testing.T) {
+	useTestCache(t)
+	defer resetTestCache()
+
+	server, calls := newCountingServer(nil)
+	defer server.Close()
+
+	client := NewAPIClient("Test", 100, time.Hour)
+
+	resp, err := client.Get(server.URL + "/cached")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	SetOffline(true)
+	assert.True(t, Offline())
+
+	resp, err = client.Get(server.URL + "/cached")
+	require.NoError(t, err)
+	assert.Equal(t, "GET ", readTestBody(t, resp))
+
+	_, err = client.Get(server.URL + "/missing")
+	assert.Error(t, err)
+
+	_, err = client.Post(server.URL+"/login", "application/json", nil)
+	assert.Error(t, err)
+
+	assert.Equal(t, 1, *calls)
+}
@@ -0,0 +1,502 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+const opensubtitlesHost = "https://api.opensubtitles.com/api/v1"
+
+const opensubtitlesUserAgent = "Supper v1.0"
+
+// opensubtitlesChunk is the size of the chunks at the start and end of a file
+// used when calculating the OpenSubtitles movie hash
+const opensubtitlesChunk = 64 * 1024
+
+var opensubtitlesClient = NewAPIClient("OpenSubtitles", 5, time.Hour)
+
+// OpenSubtitles is a provider for the opensubtitles.com REST API. The username
+// and password are optional, but downloads are severely limited without them
+func OpenSubtitles(key, username, password string) types.Provider {
+	return &opensubtitles{
+		client:   opensubtitlesClient,
+		host:     opensubtitlesHost,
+		key:      key,
+		username: username,
+		password: password,
+	}
+}
+
+type opensubtitles struct {
+	client   *APIClient
+	host     string
+	key      string
+	username string
+	password string
+
+	mu    sync.Mutex
+	token string
+}
+
+func (o *opensubtitles) Name() string {
+	return "opensubtitles"
+}
+
+// opensubtitlesHash calculates the OpenSubtitles hash of a file. The hash is
+// the file size plus the 64bit checksum of the first and last 64KB of the file
+func opensubtitlesHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	size := info.Size()
+	if size < 2*opensubtitlesChunk {
+		return "", errors.New("opensubtitles: file too small to hash")
+	}
+
+	buf := make([]byte, 2*opensubtitlesChunk)
+	if _, err := file.ReadAt(buf[:opensubtitlesChunk], 0); err != nil {
+		return "", err
+	}
+	if _, err := file.ReadAt(buf[opensubtitlesChunk:], size-opensubtitlesChunk); err != nil {
+		return "", err
+	}
+
+	hash := uint64(size)
+	for i := 0; i < len(buf); i += 8 {
+		hash += binary.LittleEndian.Uint64(buf[i:])
+	}
+
+	return fmt.Sprintf("%016x", hash), nil
+}
+
+func (o *opensubtitles) url(p string) (*url.URL, error) {
+	return url.Parse(strings.TrimSuffix(o.host, "/") + p)
+}
+
+func (o *opensubtitles) request(ctx context.Context, method string, p string, body interface{}) (*http.Request, error) {
+	url, err := o.url(p)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var data io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		data = bytes.NewBuffer(b)
+	}
+
+	req, err := http.NewRequest(method, url.String(), data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Api-Key", o.key)
+	req.Header.Set("User-Agent", opensubtitlesUserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
+
+// authenticate logs in to OpenSubtitles if credentials has been configured
+// and returns the bearer token to use for subsequent requests. Searching does
+// not require the user to be logged in, so this is only done for downloads
+func (o *opensubtitles) authenticate(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token != "" || o.username == "" {
+		return o.token, nil
+	}
+
+	req, err := o.request(ctx, http.MethodPost, "/login", struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{
+		o.username,
+		o.password,
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := o.client.Do(req)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("opensubtitles: could not authenticate %v", resp.StatusCode)
+	}
+
+	res := struct {
+		Token string `json:"token"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+
+	o.token = res.Token
+
+	return o.token, nil
+}
+
+// expire forgets the token, unless the user has logged in again since
+func (o *opensubtitles) expire(token string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token == token {
+		o.token = ""
+	}
+}
+
+// authorized performs the request as the logged in user, if credentials has
+// been configured. When the token has expired, the user is logged in again and
+// the request is retried once
+func (o *opensubtitles) authorized(req *http.Request) (*http.Response, error) {
+	for retry := 0; ; retry++ {
+		token, err := o.authenticate(req.Context())
+
+		if err != nil {
+			return nil, err
+		}
+
+		if token != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		}
+
+		resp, err := o.client.Do(req)
+
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || token == "" || retry > 0 || !rewindable(req) {
+			return resp, err
+		}
+
+		resp.Body.Close()
+		o.expire(token)
+
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (o *opensubtitles) query(local types.LocalMedia) (url.Values, error) {
+	q := url.Values{}
+
+	if hash, err := opensubtitlesHash(local.Path()); err == nil {
+		q.Set("moviehash", hash)
+	} else {
+		log.WithError(err).WithField("media", local).Debug("OpenSubtitles hash skipped")
+	}
+
+	if movie, ok := local.TypeMovie(); ok {
+		q.Set("type", "movie")
+		q.Set("query", movie.MovieName())
+		if movie.Year() != 0 {
+			q.Set("year", strconv.Itoa(movie.Year()))
+		}
+	} else if episode, ok := local.TypeEpisode(); ok {
+		q.Set("type", "episode")
+		q.Set("query", episode.TVShow())
+		q.Set("season_number", strconv.Itoa(episode.Season()))
+		q.Set("episode_number", strconv.Itoa(episode.Episode()))
+	} else {
+		return nil, mediaNotSupported("opensubtitles")
+	}
+
+	return q, nil
+}
+
+type opensubtitlesFeature struct {
+	FeatureType string `json:"feature_type"`
+	Title       string `json:"title"`
+	ParentTitle string `json:"parent_title"`
+	Year        int    `json:"year"`
+	Season      int    `json:"season_number"`
+	Episode     int    `json:"episode_number"`
+}
+
+type opensubtitlesFile struct {
+	ID       int    `json:"file_id"`
+	Filename string `json:"file_name"`
+}
+
+type opensubtitlesResult struct {
+	Attributes struct {
+		Language        string               `json:"language"`
+		HearingImpaired bool                 `json:"hearing_impaired"`
+		HashMatch       bool                 `json:"moviehash_match"`
+		Release         string               `json:"release"`
+		Feature         opensubtitlesFeature `json:"feature_details"`
+		Files           []opensubtitlesFile  `json:"files"`
+	} `json:"attributes"`
+}
+
+// SearchSubtitles searches opensubtitles.com for subtitles using the file hash
+// of the local media as well as the parsed title, year, season and episode.
+// The year is left out of the search if it is unknown
+func (o *opensubtitles) SearchSubtitles(local types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	return o.SearchSubtitlesContext(context.Background(), local)
+}
+
+// SearchSubtitlesContext searches opensubtitles.com for subtitles until the
+// context is cancelled
+func (o *opensubtitles) SearchSubtitlesContext(ctx context.Context, local types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	q, err := o.query(local)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := o.request(ctx, http.MethodGet, "/subtitles", nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := o.client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("opensubtitles: api returned %v", resp.StatusCode)
+	}
+
+	res := struct {
+		Data []opensubtitlesResult `json:"data"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	subs := make([]types.OnlineSubtitle, 0)
+
+	for _, r := range res.Data {
+		a := r.Attributes
+
+		if len(a.Files) == 0 {
+			continue
+		}
+
+		tag, err := language.Parse(a.Language)
+
+		if err != nil {
+			continue
+		}
+
+		base, _ := tag.Base()
+
+		m := o.media(a.Feature, a.Release)
+
+		if m == nil {
+			continue
+		}
+
+		if a.HashMatch {
+			m = hashMatch{m}
+		}
+
+		subs = append(subs, &opensubtitlesSubtitle{
+			Media: m,
+			opensubtitlesLink: opensubtitlesLink{
+				provider: o,
+				id:       a.Files[0].ID,
+			},
+			lang: language.Make(base.String()),
+			hi:   a.HearingImpaired,
+		})
+	}
+
+	return subs, nil
+}
+
+// media constructs the media described by the feature details of a subtitle
+// using the release name for metadata
+func (o *opensubtitles) media(f opensubtitlesFeature, release string) types.Media {
+	switch strings.ToLower(f.FeatureType) {
+	case "movie":
+		return &media.Movie{
+			Metadata: media.ParseMetadata(release),
+			NameX:    f.Title,
+			YearX:    f.Year,
+		}
+	case "episode":
+		return &media.Episode{
+			Metadata:     media.ParseMetadata(release),
+			NameX:        f.ParentTitle,
+			EpisodeNameX: f.Title,
+			SeasonX:      f.Season,
+			EpisodeX:     f.Episode,
+		}
+	}
+	return nil
+}
+
+func (o *opensubtitles) ResolveSubtitle(l types.Linker) (types.Downloadable, error) {
+	id, err := strconv.Atoi(l.Link())
+
+	if err != nil {
+		return nil, fmt.Errorf("opensubtitles: invalid link %v", l.Link())
+	}
+
+	return opensubtitlesLink{o, id}, nil
+}
+
+// download requests a temporary download link for the file and downloads it
+func (o *opensubtitles) download(id int) (io.ReadCloser, error) {
+	req, err := o.request(context.Background(), http.MethodPost, "/download", struct {
+		FileID int `json:"file_id"`
+	}{
+		id,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.authorized(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("opensubtitles: download returned %v", resp.StatusCode)
+	}
+
+	res := struct {
+		Link string `json:"link"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	if res.Link == "" {
+		return nil, errors.New("opensubtitles: missing download link")
+	}
+
+	req, err = newDownloadRequest(res.Link)
+
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := o.client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if file.StatusCode != 200 {
+		file.Body.Close()
+		return nil, fmt.Errorf("opensubtitles: download subtitle (%v)", file.StatusCode)
+	}
+
+	return file.Body, nil
+}
+
+// hashMatch is media which has been matched by file hash and is therefore
+// guaranteed to be in sync with the local media
+type hashMatch struct {
+	types.Media
+}
+
+// HashMatch returns true, since the media was matched by file hash
+func (h hashMatch) HashMatch() bool {
+	return true
+}
+
+// MarshalJSON returns the JSON representation of the underlying media
+func (h hashMatch) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Media)
+}
+
+type opensubtitlesLink struct {
+	provider *opensubtitles
+	id       int
+}
+
+func (l opensubtitlesLink) Link() string {
+	return strconv.Itoa(l.id)
+}
+
+func (l opensubtitlesLink) Download() (io.ReadCloser, error) {
+	return l.provider.download(l.id)
+}
+
+type opensubtitlesSubtitle struct {
+	types.Media
+	opensubtitlesLink
+	lang language.Tag
+	hi   bool
+}
+
+func (s *opensubtitlesSubtitle) String() string {
+	return display.English.Languages().Name(s.Language())
+}
+
+func (s *opensubtitlesSubtitle) ForMedia() types.Media {
+	return s.Media
+}
+
+func (s *opensubtitlesSubtitle) Language() language.Tag {
+	return s.lang
+}
+
+func (s *opensubtitlesSubtitle) HearingImpaired() bool {
+	return s.hi
+}
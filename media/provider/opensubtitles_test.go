@@ -0,0 +1,354 @@
+package provider
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/score"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+const opensubtitlesTestKey = "test-api-key"
+
+func writeHashTestFile(t *testing.T, dir string, name string) string {
+	data := make([]byte, 2*opensubtitlesChunk)
+	binary.LittleEndian.PutUint64(data, 1)
+	binary.LittleEndian.PutUint64(data[len(data)-8:], 2)
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestOpenSubtitlesHash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writeHashTestFile(t, dir, "test.mkv")
+
+	hash, err := opensubtitlesHash(path)
+	require.NoError(t, err)
+	assert.Equal(t, "0000000000020003", hash)
+
+	small := filepath.Join(dir, "small.mkv")
+	require.NoError(t, ioutil.WriteFile(small, []byte("too small"), 0644))
+
+	_, err = opensubtitlesHash(small)
+	assert.Error(t, err)
+}
+
+type opensubtitlesServer struct {
+	*httptest.Server
+	queries []map[string]string
+	logins  int
+	token   string
+}
+
+func newOpenSubtitlesServer(t *testing.T) *opensubtitlesServer {
+	s := &opensubtitlesServer{token: "test-token"}
+	mux := http.NewServeMux()
+
+	auth := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Api-Key") != opensubtitlesTestKey {
+			w.WriteHeader(http.StatusForbidden)
+			return false
+		}
+		if r.Header.Get("User-Agent") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return false
+		}
+		return true
+	}
+
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if !auth(w, r) {
+			return
+		}
+		var cred struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&cred))
+		if cred.Username != "user" || cred.Password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.logins++
+		w.Write([]byte(`{"token": "` + s.token + `"}`))
+	})
+
+	mux.HandleFunc("/subtitles", func(w http.ResponseWriter, r *http.Request) {
+		if !auth(w, r) {
+			return
+		}
+		q := make(map[string]string)
+		for k := range r.URL.Query() {
+			q[k] = r.URL.Query().Get(k)
+		}
+		s.queries = append(s.queries, q)
+		w.Write([]byte(`{
+			"total_count": 3,
+			"data": [{
+				"attributes": {
+					"language": "en",
+					"hearing_impaired": false,
+					"moviehash_match": true,
+					"release": "Inception.2010.720p.BluRay.x264-REWARD",
+					"feature_details": {
+						"feature_type": "Movie",
+						"title": "Inception",
+						"year": 2010
+					},
+					"files": [{"file_id": 101, "file_name": "Inception.en.srt"}]
+				}
+			}, {
+				"attributes": {
+					"language": "pt-BR",
+					"hearing_impaired": true,
+					"moviehash_match": false,
+					"release": "Inception.2010.1080p.WEB-DL",
+					"feature_details": {
+						"feature_type": "Movie",
+						"title": "Inception",
+						"year": 2010
+					},
+					"files": [{"file_id": 102, "file_name": "Inception.pt.srt"}]
+				}
+			}, {
+				"attributes": {
+					"language": "en",
+					"release": "No files",
+					"feature_details": {"feature_type": "Movie"},
+					"files": []
+				}
+			}]
+		}`))
+	})
+
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		if !auth(w, r) {
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+s.token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			FileID int `json:"file_id"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req.FileID != 101 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"link": "` + s.URL + `/files/101.srt"}`))
+	})
+
+	mux.HandleFunc("/files/101.srt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n"))
+	})
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func newTestOpenSubtitles(host string) *opensubtitles {
+	return &opensubtitles{
+		client:   NewAPIClient("OpenSubtitlesTest", 100, 0),
+		host:     host,
+		key:      opensubtitlesTestKey,
+		username: "user",
+		password: "pass",
+	}
+}
+
+func TestOpenSubtitlesSearch(t *testing.T) {
+	server := newOpenSubtitlesServer(t)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writeHashTestFile(t, dir, "Inception.2010.720p.BluRay.x264.mkv")
+	local, err := media.NewLocalFile(path)
+	require.NoError(t, err)
+
+	p := newTestOpenSubtitles(server.URL)
+
+	subs, err := p.SearchSubtitles(local)
+	require.NoError(t, err)
+	require.Len(t, subs, 2)
+
+	require.Len(t, server.queries, 1)
+	q := server.queries[0]
+	assert.Equal(t, "0000000000020003", q["moviehash"])
+	assert.Equal(t, "Inception", q["query"])
+	assert.Equal(t, "2010", q["year"])
+	assert.Equal(t, "movie", q["type"])
+	assert.Equal(t, 0, server.logins)
+
+	assert.Equal(t, language.English, subs[0].Language())
+	assert.False(t, subs[0].HearingImpaired())
+	assert.Equal(t, "101", subs[0].Link())
+
+	assert.Equal(t, language.Portuguese, subs[1].Language())
+	assert.True(t, subs[1].HearingImpaired())
+
+	movie, ok := subs[1].ForMedia().TypeMovie()
+	require.True(t, ok)
+	assert.Equal(t, "Inception", movie.MovieName())
+	assert.Equal(t, 2010, movie.Year())
+
+	e := new(score.DefaultEvaluator)
+	assert.InDelta(t, 0.99, e.Evaluate(local, subs[0].ForMedia()), 0.001)
+	assert.True(t, e.Evaluate(local, subs[1].ForMedia()) < 0.99)
+}
+
+func TestOpenSubtitlesSearchEpisode(t *testing.T) {
+	server := newOpenSubtitlesServer(t)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "Game.of.Thrones.S02E05.720p.mkv")
+	require.NoError(t, ioutil.WriteFile(path, []byte("small"), 0644))
+	local, err := media.NewLocalFile(path)
+	require.NoError(t, err)
+
+	p := newTestOpenSubtitles(server.URL)
+
+	_, err = p.SearchSubtitles(local)
+	require.NoError(t, err)
+
+	require.Len(t, server.queries, 1)
+	q := server.queries[0]
+	assert.NotContains(t, q, "moviehash")
+	assert.Equal(t, "Game of Thrones", q["query"])
+	assert.Equal(t, "2", q["season_number"])
+	assert.Equal(t, "5", q["episode_number"])
+	assert.Equal(t, "episode", q["type"])
+	assert.Equal(t, 0, server.logins)
+}
+
+// unknownYear is local media of a movie which was released in an unknown year
+type unknownYear struct {
+	types.LocalMedia
+}
+
+func (unknownYear) TypeMovie() (types.Movie, bool) {
+	return &media.Movie{NameX: "Inception"}, true
+}
+
+func TestOpenSubtitlesSearchUnknownYear(t *testing.T) {
+	server := newOpenSubtitlesServer(t)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writeHashTestFile(t, dir, "Inception.2010.720p.BluRay.x264.mkv")
+	local, err := media.NewLocalFile(path)
+	require.NoError(t, err)
+
+	p := newTestOpenSubtitles(server.URL)
+
+	_, err = p.SearchSubtitles(unknownYear{local})
+	require.NoError(t, err)
+
+	require.Len(t, server.queries, 1)
+	assert.Equal(t, "Inception", server.queries[0]["query"])
+	assert.NotContains(t, server.queries[0], "year")
+}
+
+func TestOpenSubtitlesDownload(t *testing.T) {
+	server := newOpenSubtitlesServer(t)
+	defer server.Close()
+
+	p := newTestOpenSubtitles(server.URL)
+
+	dl, err := p.ResolveSubtitle(testLink("101"))
+	require.NoError(t, err)
+
+	r, err := dl.Download()
+	require.NoError(t, err)
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Hello")
+
+	dl, err = p.ResolveSubtitle(testLink("102"))
+	require.NoError(t, err)
+
+	_, err = dl.Download()
+	assert.Error(t, err)
+
+	_, err = p.ResolveSubtitle(testLink("not a number"))
+	assert.Error(t, err)
+
+	assert.Equal(t, 1, server.logins)
+}
+
+func TestOpenSubtitlesExpiredToken(t *testing.T) {
+	server := newOpenSubtitlesServer(t)
+	defer server.Close()
+
+	p := newTestOpenSubtitles(server.URL)
+
+	dl, err := p.ResolveSubtitle(testLink("101"))
+	require.NoError(t, err)
+
+	r, err := dl.Download()
+	require.NoError(t, err)
+	r.Close()
+	assert.Equal(t, 1, server.logins)
+
+	// The user is logged in again, when the token is no longer accepted
+	server.token = "renewed-token"
+
+	r, err = dl.Download()
+	require.NoError(t, err)
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Hello")
+	assert.Equal(t, 2, server.logins)
+	assert.Equal(t, "renewed-token", p.token)
+}
+
+func TestOpenSubtitlesInvalidCredentials(t *testing.T) {
+	server := newOpenSubtitlesServer(t)
+	defer server.Close()
+
+	p := newTestOpenSubtitles(server.URL)
+	p.password = "wrong"
+
+	dl, err := p.ResolveSubtitle(testLink("101"))
+	require.NoError(t, err)
+
+	_, err = dl.Download()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "authenticate")
+}
+
+type testLink string
+
+func (l testLink) Link() string {
+	return string(l)
+}
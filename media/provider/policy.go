@@ -0,0 +1,223 @@
+package provider
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy configures timeouts, retries and the circuit breaker of an APIClient
+type Policy struct {
+	// Timeout is the maximum duration of a single request
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// Retries is the number of times a failed request is retried
+	Retries int `mapstructure:"retries"`
+
+	// Backoff is the delay before the first retry, which doubles for each
+	// following retry
+	Backoff time.Duration `mapstructure:"backoff"`
+
+	// MaxBackoff is the maximum delay between retries. Requests are not
+	// retried if the server asks to wait longer than this
+	MaxBackoff time.Duration `mapstructure:"maxbackoff"`
+
+	// Breaker is the number of consecutive failures after which requests
+	// are short-circuited. The circuit breaker is disabled if zero
+	Breaker int `mapstructure:"breaker"`
+
+	// Cooldown is the duration requests are short-circuited when the
+	// circuit breaker has tripped
+	Cooldown time.Duration `mapstructure:"cooldown"`
+
+	// Searches is the maximum number of concurrent searches for subtitles.
+	// Searches are unlimited if zero
+	Searches int `mapstructure:"searches"`
+
+	// Downloads is the maximum number of concurrent downloads of subtitles.
+	// Downloads are unlimited if zero
+	Downloads int `mapstructure:"downloads"`
+}
+
+// DefaultPolicy is the policy used when nothing else is configured
+var DefaultPolicy = Policy{
+	Timeout:    30 * time.Second,
+	Retries:    2,
+	Backoff:    time.Second,
+	MaxBackoff: 30 * time.Second,
+	Breaker:    5,
+	Cooldown:   5 * time.Minute,
+	Searches:   2,
+	Downloads:  2,
+}
+
+var policies struct {
+	sync.RWMutex
+	def     Policy
+	clients map[string]Policy
+}
+
+func init() {
+	policies.def = DefaultPolicy
+}
+
+// SetPolicies sets the default policy of every APIClient, as well as the
+// policies of individual clients by their (case insensitive) name
+func SetPolicies(def Policy, clients map[string]Policy) {
+	policies.Lock()
+	defer policies.Unlock()
+	policies.def = def
+	policies.clients = make(map[string]Policy)
+	for name, p := range clients {
+		policies.clients[strings.ToLower(name)] = p
+	}
+}
+
+// PolicyFor returns the policy of the client with the name
+func PolicyFor(name string) Policy {
+	policies.RLock()
+	defer policies.RUnlock()
+	if p, ok := policies.clients[strings.ToLower(name)]; ok {
+		return p
+	}
+	return policies.def
+}
+
+// policy returns the policy of the client
+func (a *APIClient) policy() Policy {
+	return PolicyFor(a.name)
+}
+
+// ErrCircuitOpen is an error for requests which are short-circuited because
+// the api has failed too many times
+type ErrCircuitOpen struct {
+	error
+	Until time.Time
+}
+
+// IsErrCircuitOpen returns true if the error dictates that the request was
+// short-circuited by the circuit breaker
+func IsErrCircuitOpen(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := err.(ErrCircuitOpen); ok {
+		return true
+	}
+	return false
+}
+
+// breaker is a circuit breaker which opens after a number of consecutive
+// failures. Once the cooldown has passed a single request is let through, and
+// the breaker closes again if it succeeds
+type breaker struct {
+	sync.Mutex
+	failures int
+	until    time.Time
+	probing  bool
+}
+
+// allow returns an error if requests are currently short-circuited
+func (b *breaker) allow(name string) error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.until.IsZero() {
+		return nil
+	}
+
+	if time.Now().Before(b.until) || b.probing {
+		return ErrCircuitOpen{
+			fmt.Errorf("%v is unavailable after %v failures", name, b.failures),
+			b.until,
+		}
+	}
+
+	b.probing = true
+	return nil
+}
+
+// success closes the breaker
+func (b *breaker) success() {
+	b.Lock()
+	defer b.Unlock()
+	b.failures = 0
+	b.until = time.Time{}
+	b.probing = false
+}
+
+// release lets the next request through after a request which neither
+// succeeded nor failed, e.g. because it was cancelled. The breaker stays open
+// until the next request succeeds or fails
+func (b *breaker) release() {
+	b.Lock()
+	defer b.Unlock()
+	b.probing = false
+}
+
+// failure records a failed request and returns true if the breaker tripped
+func (b *breaker) failure(p Policy) bool {
+	b.Lock()
+	defer b.Unlock()
+	b.failures++
+	b.probing = false
+	if p.Breaker > 0 && b.failures >= p.Breaker {
+		b.until = time.Now().Add(p.Cooldown)
+		return true
+	}
+	return false
+}
+
+// retryable returns true if the request may succeed if retried
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the delay before the retry with exponential growth and
+// jitter, such that concurrent clients do not retry at the same time
+func (p Policy) backoff(retry int) time.Duration {
+	if p.Backoff <= 0 {
+		return 0
+	}
+	d := p.Backoff << uint(retry)
+	if p.MaxBackoff > 0 && (d <= 0 || d > p.MaxBackoff) {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the
+// response, given either in seconds or as a http date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	h := resp.Header.Get("Retry-After")
+	if h == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(h); err == nil {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
@@ -0,0 +1,252 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func useTestPolicy(p Policy) {
+	SetPolicies(p, nil)
+}
+
+func resetTestPolicy() {
+	SetPolicies(DefaultPolicy, nil)
+}
+
+// newFailingServer returns a server which fails with the status code for the
+// first number of requests
+func newFailingServer(fails int, status int, header http.Header) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= fails {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	return server, &calls
+}
+
+func TestAPIClientRetry(t *testing.T) {
+	useTestPolicy(Policy{Retries: 3, Backoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+	defer resetTestPolicy()
+
+	server, calls := newFailingServer(2, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+
+	client := NewAPIClient("Test", 100, 0)
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok", readTestBody(t, resp))
+	assert.Equal(t, 3, *calls)
+}
+
+func TestAPIClientRetryBody(t *testing.T) {
+	useTestPolicy(Policy{Retries: 1, Backoff: time.Millisecond})
+	defer resetTestPolicy()
+
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		bodies = append(bodies, r.Form.Get("query"))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	client := NewAPIClient("Test", 100, 0)
+
+	resp, err := client.Post(server.URL, formContentType, strings.NewReader("query=foo"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"foo", "foo"}, bodies)
+}
+
+func TestAPIClientRetriesExhausted(t *testing.T) {
+	useTestPolicy(Policy{Retries: 2, Backoff: time.Millisecond})
+	defer resetTestPolicy()
+
+	server, calls := newFailingServer(10, http.StatusTooManyRequests, nil)
+	defer server.Close()
+
+	client := NewAPIClient("Test", 100, 0)
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, 3, *calls)
+}
+
+func TestAPIClientRetryAfter(t *testing.T) {
+	useTestPolicy(Policy{Retries: 2, Backoff: time.Hour, MaxBackoff: time.Minute})
+	defer resetTestPolicy()
+
+	server, calls := newFailingServer(1, http.StatusTooManyRequests, http.Header{
+		"Retry-After": {"0"},
+	})
+	defer server.Close()
+
+	client := NewAPIClient("Test", 100, 0)
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, *calls)
+
+	// Servers asking to wait longer than the maximum backoff are not retried
+	server, calls = newFailingServer(1, http.StatusTooManyRequests, http.Header{
+		"Retry-After": {"3600"},
+	})
+	defer server.Close()
+
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestAPIClientTimeout(t *testing.T) {
+	useTestPolicy(Policy{Timeout: 20 * time.Millisecond})
+	defer resetTestPolicy()
+
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	client := NewAPIClient("Test", 100, 0)
+
+	start := time.Now()
+	_, err := client.Get(server.URL)
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+}
+
+func TestAPIClientCircuitBreaker(t *testing.T) {
+	useTestPolicy(Policy{Breaker: 2, Cooldown: 50 * time.Millisecond})
+	defer resetTestPolicy()
+
+	server, calls := newFailingServer(3, http.StatusInternalServerError, nil)
+	defer server.Close()
+
+	client := NewAPIClient("Test", 100, 0)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	_, err := client.Get(server.URL)
+	require.Error(t, err)
+	assert.True(t, IsErrCircuitOpen(err))
+	assert.Equal(t, 2, *calls)
+
+	time.Sleep(60 * time.Millisecond)
+
+	// A single failing request re-opens the breaker after the cooldown
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 3, *calls)
+
+	_, err = client.Get(server.URL)
+	assert.True(t, IsErrCircuitOpen(err))
+
+	time.Sleep(60 * time.Millisecond)
+
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", readTestBody(t, resp))
+
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 5, *calls)
+}
+
+func TestPolicyByClientName(t *testing.T) {
+	SetPolicies(Policy{Retries: 1}, map[string]Policy{
+		"Subscene": {Retries: 5},
+	})
+	defer resetTestPolicy()
+
+	assert.Equal(t, 5, NewAPIClient("subscene", 1, 0).policy().Retries)
+	assert.Equal(t, 1, NewAPIClient("Addic7ed", 1, 0).policy().Retries)
+}
+
+func TestAPIClientCancel(t *testing.T) {
+	useTestPolicy(Policy{Retries: 5, Backoff: time.Hour, Breaker: 1, Cooldown: time.Hour})
+	defer resetTestPolicy()
+
+	server, calls := newFailingServer(10, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+
+	client := NewAPIClient("Test", 100, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	_, err := client.GetContext(ctx, server.URL)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, *calls)
+
+	// Cancelled requests does not trip the circuit breaker
+	useTestPolicy(Policy{Breaker: 1, Cooldown: time.Hour})
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestAPIClientCancelProbe(t *testing.T) {
+	useTestPolicy(Policy{Breaker: 1, Cooldown: 50 * time.Millisecond})
+	defer resetTestPolicy()
+
+	server, calls := newFailingServer(1, http.StatusInternalServerError, nil)
+	defer server.Close()
+
+	client := NewAPIClient("Test", 100, 0)
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	time.Sleep(60 * time.Millisecond)
+
+	// A cancelled probe lets the next request through
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.GetContext(ctx, server.URL)
+	assert.Equal(t, context.Canceled, err)
+
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", readTestBody(t, resp))
+	assert.Equal(t, 2, *calls)
+}
@@ -1,11 +1,14 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/apex/log"
 	"go.uber.org/ratelimit"
@@ -22,6 +25,12 @@ func mediaNotSupported(api string) ErrMediaNotSupported {
 	}
 }
 
+// NewErrMediaNotSupported returns an error dictating that the media type is
+// not supported by the api
+func NewErrMediaNotSupported(api string) error {
+	return mediaNotSupported(api)
+}
+
 // IsErrMediaNotSupported return true if the error dictates taht the media
 // type was not supported by the scraper
 func IsErrMediaNotSupported(err error) bool {
@@ -34,57 +43,242 @@ func IsErrMediaNotSupported(err error) bool {
 	return false
 }
 
-// APIClient is a http client with rate limiting
+// ErrNotFound is an error for media which could not be found by a scraper
+type ErrNotFound struct {
+	error
+}
+
+func notFound(api string) ErrNotFound {
+	return ErrNotFound{
+		fmt.Errorf("media not found on %v", api),
+	}
+}
+
+// NewErrNotFound returns an error dictating that the media could not be
+// found by the api
+func NewErrNotFound(api string) error {
+	return notFound(api)
+}
+
+// IsErrNotFound returns true if the error dictates that the media could not
+// be found by the scraper
+func IsErrNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := err.(ErrNotFound); ok {
+		return true
+	}
+	return false
+}
+
+// APIClient is a http client with rate limiting. Failed requests are retried
+// and short-circuited according to the policy of the client, and responses are
+// cached when a cache has been set up with UseCache
 type APIClient struct {
 	*http.Client
 	ratelimit.Limiter
-	name string
+	name    string
+	ttl     time.Duration
+	breaker breaker
 }
 
 func simpleURL(url string) string {
 	return strings.Split(url, "?")[0]
 }
 
-// NewAPIClient return a new APIClient
-func NewAPIClient(name string, limit int) *APIClient {
+// NewAPIClient return a new APIClient. Responses are cached for the duration
+// of ttl, unless the cache headers of the response dictates otherwise
+func NewAPIClient(name string, limit int, ttl time.Duration) *APIClient {
 	return &APIClient{
-		Client:  http.DefaultClient,
+		Client:  &http.Client{},
 		Limiter: ratelimit.New(limit),
 		name:    name,
+		ttl:     ttl,
 	}
 }
 
-// Do performs a http request with rate limiting
+// Do performs a http request with rate limiting. Cached responses are served
+// without performing the request
 func (a *APIClient) Do(req *http.Request) (*http.Response, error) {
-	a.Take()
-	log.WithField("url", simpleURL(req.URL.String())).WithField("method", req.Method).Debug(a.name)
-	return a.Client.Do(req)
+	key := cacheKey(req)
+
+	if resp := a.cached(key, req); resp != nil {
+		log.WithField("url", simpleURL(req.URL.String())).WithField("method", req.Method).Debug(a.name + " (cached)")
+		return resp, nil
+	}
+
+	if Offline() {
+		return nil, fmt.Errorf("%v: offline and not cached: %v %v", a.name, req.Method, simpleURL(req.URL.String()))
+	}
+
+	if err := a.breaker.allow(a.name); err != nil {
+		return nil, err
+	}
+	defer a.breaker.release()
+
+	p := a.policy()
+	resp, err := a.retry(req, p)
+
+	if err := req.Context().Err(); err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+
+	if retryable(resp, err) || (resp != nil && resp.StatusCode >= 500) {
+		if a.breaker.failure(p) {
+			log.WithField("client", a.name).WithField("cooldown", p.Cooldown).
+				Warn("Too many failed requests, client is paused")
+		}
+	} else {
+		a.breaker.success()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return a.store(key, resp)
+}
+
+// retry performs the request and retries it with backoff according to the
+// policy if it fails
+func (a *APIClient) retry(req *http.Request, p Policy) (*http.Response, error) {
+	for retry := 0; ; retry++ {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+
+		if retry > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.WithContext(req.Context())
+			req.Body = body
+		}
+
+		a.Take()
+		log.WithField("url", simpleURL(req.URL.String())).WithField("method", req.Method).Debug(a.name)
+		resp, err := a.send(req, p.Timeout)
+
+		if !retryable(resp, err) || retry >= p.Retries || !rewindable(req) {
+			return resp, err
+		}
+
+		wait := p.backoff(retry)
+
+		if d, ok := retryAfter(resp); ok {
+			if p.MaxBackoff > 0 && d > p.MaxBackoff {
+				return resp, err
+			}
+			wait = d
+		}
+
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		ctx := log.WithField("client", a.name).WithField("retry", retry+1).WithField("wait", wait)
+		if err != nil {
+			ctx = ctx.WithError(err)
+		} else {
+			ctx = ctx.WithField("status", resp.StatusCode)
+		}
+		ctx.Debug("Retrying request")
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// send performs a single request which is cancelled after the timeout
+func (a *APIClient) send(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	if timeout <= 0 {
+		return a.Client.Do(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := a.Client.Do(req.WithContext(ctx))
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = cancelBody{resp.Body, cancel}
+	return resp, nil
+}
+
+// rewindable returns true if the request can be sent again
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// cancelBody cancels the context of the request when the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
 }
 
 // Get performs a http get request with rate limiting
 func (a *APIClient) Get(url string) (*http.Response, error) {
-	a.Take()
-	log.WithField("url", simpleURL(url)).WithField("method", "GET").Debug(a.name)
-	return a.Client.Get(url)
+	return a.GetContext(context.Background(), url)
+}
+
+// GetContext performs a http get request with rate limiting, which is
+// cancelled when the context is done
+func (a *APIClient) GetContext(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return a.Do(req.WithContext(ctx))
 }
 
 // Head performs a http head request with rate limiting
 func (a *APIClient) Head(url string) (*http.Response, error) {
-	a.Take()
-	log.WithField("url", simpleURL(url)).WithField("method", "HEAD").Debug(a.name)
-	return a.Client.Head(url)
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return a.Do(req)
 }
 
 // Post performs a http post request with rate limiting
 func (a *APIClient) Post(url string, contentType string, body io.Reader) (*http.Response, error) {
-	a.Take()
-	log.WithField("url", simpleURL(url)).WithField("method", "POST").Debug(a.name)
-	return a.Client.Post(url, contentType, body)
+	return a.PostContext(context.Background(), url, contentType, body)
+}
+
+// PostContext performs a http post request with rate limiting, which is
+// cancelled when the context is done
+func (a *APIClient) PostContext(ctx context.Context, url string, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return a.Do(req.WithContext(ctx))
 }
 
 // PostForm performs a http post form request with rate limiting
 func (a *APIClient) PostForm(url string, data url.Values) (*http.Response, error) {
-	a.Take()
-	log.WithField("url", simpleURL(url)).WithField("method", "POST").Debug(a.name)
-	return a.Client.PostForm(url, data)
+	return a.PostFormContext(context.Background(), url, data)
+}
+
+// PostFormContext performs a http post form request with rate limiting, which
+// is cancelled when the context is done
+func (a *APIClient) PostFormContext(ctx context.Context, url string, data url.Values) (*http.Response, error) {
+	return a.PostContext(ctx, url, formContentType, strings.NewReader(data.Encode()))
 }
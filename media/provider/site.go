@@ -0,0 +1,412 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/apex/log"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/parse"
+	"github.com/tympanix/supper/media/subformat"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// SiteConfig is the declarative definition of a subtitle website which is
+// scraped using CSS selectors
+type SiteConfig struct {
+	// Name is the name of the provider
+	Name string `mapstructure:"name"`
+	// Rate is the maximum number of requests per second
+	Rate int `mapstructure:"rate"`
+	// Media restricts the provider to movies or episodes
+	Media []string `mapstructure:"media"`
+	// Search is the template for the search URL
+	Search string `mapstructure:"search"`
+	// Results selects each subtitle on the search page
+	Results string `mapstructure:"results"`
+	// Title selects the release name within a result
+	Title string `mapstructure:"title"`
+	// Language selects the language within a result
+	Language string `mapstructure:"language"`
+	// Lang is the fixed language for sites without a language selector
+	Lang string `mapstructure:"lang"`
+	// Link selects the download link (href) within a result
+	Link string `mapstructure:"link"`
+	// HI selects an element which is present for hearing impaired subtitles
+	HI string `mapstructure:"hi"`
+	// Download selects the download link on the page of the subtitle, for
+	// sites where the link of a result is not the file itself
+	Download string `mapstructure:"download"`
+	// Unpack is the format of the downloaded file (zip, rar, srt). The format
+	// is detected from the response if left empty
+	Unpack string `mapstructure:"unpack"`
+	// Entry is the pattern of the subtitle file inside archives
+	Entry string `mapstructure:"entry"`
+}
+
+// siteQuery is the data available to the search URL template
+type siteQuery struct {
+	Title   string
+	Year    int
+	Season  int
+	Episode int
+}
+
+var siteFuncs = template.FuncMap{
+	"pad": func(i int) string {
+		return fmt.Sprintf("%02d", i)
+	},
+}
+
+func (c SiteConfig) valid() error {
+	if c.Name == "" {
+		return errors.New("missing provider name")
+	}
+	for k, v := range map[string]string{
+		"search":  c.Search,
+		"results": c.Results,
+		"link":    c.Link,
+	} {
+		if v == "" {
+			return fmt.Errorf("missing %v for provider %v", k, c.Name)
+		}
+	}
+	if c.Language == "" && c.Lang == "" {
+		return fmt.Errorf("missing language for provider %v", c.Name)
+	}
+	for _, m := range c.Media {
+		if m != "movie" && m != "episode" {
+			return fmt.Errorf("unknown media %v for provider %v", m, c.Name)
+		}
+	}
+	switch c.Unpack {
+	case "", "zip", "rar", "srt":
+	default:
+		return fmt.Errorf("unknown unpack format %v for provider %v", c.Unpack, c.Name)
+	}
+	return nil
+}
+
+// Site returns a provider which scrapes a subtitle website as described by
+// the configuration
+func Site(c SiteConfig) (types.Provider, error) {
+	if err := c.valid(); err != nil {
+		return nil, err
+	}
+
+	search, err := template.New(c.Name).Funcs(siteFuncs).Parse(c.Search)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var lang language.Tag
+	if c.Lang != "" {
+		if lang, err = language.Parse(c.Lang); err != nil {
+			return nil, err
+		}
+	}
+
+	rate := c.Rate
+	if rate <= 0 {
+		rate = 1
+	}
+
+	return &site{
+		SiteConfig: c,
+		client:     NewAPIClient(c.Name, rate, time.Hour),
+		search:     search,
+		lang:       lang,
+	}, nil
+}
+
+type site struct {
+	SiteConfig
+	client *APIClient
+	search *template.Template
+	lang   language.Tag
+}
+
+func (s *site) Name() string {
+	return s.SiteConfig.Name
+}
+
+func (s *site) supports(kind string) bool {
+	if len(s.Media) == 0 {
+		return true
+	}
+	for _, m := range s.Media {
+		if m == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *site) query(m types.Media) (*siteQuery, error) {
+	if movie, ok := m.TypeMovie(); ok && s.supports("movie") {
+		return &siteQuery{
+			Title: movie.MovieName(),
+			Year:  movie.Year(),
+		}, nil
+	} else if episode, ok := m.TypeEpisode(); ok && s.supports("episode") {
+		return &siteQuery{
+			Title:   episode.TVShow(),
+			Season:  episode.Season(),
+			Episode: episode.Episode(),
+		}, nil
+	}
+	return nil, mediaNotSupported(s.Name())
+}
+
+func (s *site) document(ctx context.Context, uri string) (*goquery.Document, error) {
+	resp, err := s.client.GetContext(ctx, uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%v returned status code %v", s.Name(), resp.StatusCode)
+	}
+
+	return goquery.NewDocumentFromReader(resp.Body)
+}
+
+// SearchSubtitles searches the website and parses the results using the
+// configured selectors. Results which does not describe similar media are
+// left out
+func (s *site) SearchSubtitles(local types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	return s.SearchSubtitlesContext(context.Background(), local)
+}
+
+// SearchSubtitlesContext searches the website for subtitles until the context
+// is cancelled
+func (s *site) SearchSubtitlesContext(ctx context.Context, local types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	q, err := s.query(local)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := s.search.Execute(&buf, q); err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(buf.String())
+
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.document(ctx, base.String())
+
+	if err != nil {
+		return nil, err
+	}
+
+	subs := make([]types.OnlineSubtitle, 0)
+
+	doc.Find(s.Results).Each(func(i int, r *goquery.Selection) {
+		href, exists := r.Find(s.Link).First().Attr("href")
+
+		if !exists {
+			return
+		}
+
+		link, err := base.Parse(href)
+
+		if err != nil {
+			return
+		}
+
+		title := r
+		if s.Title != "" {
+			title = r.Find(s.Title).First()
+		}
+
+		m, err := media.NewFromString(strings.TrimSpace(title.Text()))
+
+		if err != nil || !m.Similar(local) {
+			return
+		}
+
+		lang := s.lang
+		if s.Language != "" {
+			if lang, err = parse.Language(strings.TrimSpace(r.Find(s.Language).First().Text())); err != nil {
+				return
+			}
+		}
+
+		subs = append(subs, &siteSubtitle{
+			Media:    m,
+			siteLink: siteLink{s, link.String()},
+			lang:     lang,
+			hi:       s.HI != "" && r.Find(s.HI).Length() > 0,
+		})
+	})
+
+	log.WithField("provider", s.Name()).WithField("subtitles", len(subs)).
+		Debug("Scraped subtitle site")
+
+	return subs, nil
+}
+
+func (s *site) ResolveSubtitle(l types.Linker) (types.Downloadable, error) {
+	u, err := url.Parse(l.Link())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%v: invalid link %v", s.Name(), l.Link())
+	}
+
+	return siteLink{s, u.String()}, nil
+}
+
+// downloadURL returns the URL of the subtitle file, following the download
+// link on the subtitle page if configured
+func (s *site) downloadURL(link string) (string, error) {
+	if s.Download == "" {
+		return link, nil
+	}
+
+	doc, err := s.document(context.Background(), link)
+
+	if err != nil {
+		return "", err
+	}
+
+	href, exists := doc.Find(s.Download).First().Attr("href")
+
+	if !exists {
+		return "", fmt.Errorf("could not find download link from %v", s.Name())
+	}
+
+	base, err := url.Parse(link)
+
+	if err != nil {
+		return "", err
+	}
+
+	u, err := base.Parse(href)
+
+	if err != nil {
+		return "", err
+	}
+
+	return u.String(), nil
+}
+
+// format returns the format of the downloaded file, either from configuration,
+// the response headers or the URL
+func (s *site) format(resp *http.Response) string {
+	if s.Unpack != "" {
+		return "." + s.Unpack
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if ext := path.Ext(params["filename"]); ext != "" {
+			return strings.ToLower(ext)
+		}
+	}
+	return strings.ToLower(path.Ext(resp.Request.URL.Path))
+}
+
+func (s *site) download(link string) (io.ReadCloser, error) {
+	uri, err := s.downloadURL(link)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := newDownloadRequest(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%v download subtitle (%v)", s.Name(), resp.StatusCode)
+	}
+
+	file, err := tempDownload(resp.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	format := s.format(resp)
+
+	if format != ".zip" && format != ".rar" && !subformat.IsSubtitle(format) {
+		if format, err = sniffFormat(file); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return nil, err
+		}
+	}
+
+	return unpackSubtitle(file, format, s.Entry)
+}
+
+type siteLink struct {
+	provider *site
+	link     string
+}
+
+func (l siteLink) Link() string {
+	return l.link
+}
+
+func (l siteLink) Download() (io.ReadCloser, error) {
+	return l.provider.download(l.link)
+}
+
+type siteSubtitle struct {
+	types.Media
+	siteLink
+	lang language.Tag
+	hi   bool
+}
+
+func (s *siteSubtitle) String() string {
+	return display.English.Languages().Name(s.Language())
+}
+
+func (s *siteSubtitle) ForMedia() types.Media {
+	return s.Media
+}
+
+func (s *siteSubtitle) Language() language.Tag {
+	return s.lang
+}
+
+func (s *siteSubtitle) HearingImpaired() bool {
+	return s.hi
+}
@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/media/meta/quality"
+	"golang.org/x/text/language"
+)
+
+func zipTestData(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range files {
+		e, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = e.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func newSiteServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+
+	fixture := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			http.ServeFile(w, r, filepath.Join("test", "site", name))
+		}
+	}
+
+	archive := zipTestData(t, map[string]string{
+		"readme.txt":                 "not a subtitle",
+		"Inception.2010.720p.en.srt": "zipped subtitle",
+	})
+
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "Inception" || r.URL.Query().Get("year") != "2010" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fixture("search.html")(w, r)
+	})
+
+	mux.HandleFunc("/subtitle/1", fixture("subtitle.html"))
+
+	mux.HandleFunc("/files/1.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	})
+
+	mux.HandleFunc("/raw/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("plain subtitle"))
+	})
+
+	mux.HandleFunc("/raw/2", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func newTestSiteConfig(host string) SiteConfig {
+	return SiteConfig{
+		Name:     "testsite",
+		Rate:     100,
+		Search:   host + "/search?q={{ .Title | urlquery }}&year={{ .Year }}",
+		Results:  "table.subtitles tr",
+		Title:    "td.release",
+		Language: "td.language",
+		Link:     "a.download",
+		HI:       "td.hi",
+		Download: "#downloadButton",
+	}
+}
+
+func TestSiteSearch(t *testing.T) {
+	server := newSiteServer(t)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p, err := Site(newTestSiteConfig(server.URL))
+	require.NoError(t, err)
+	assert.Equal(t, "testsite", p.Name())
+
+	subs, err := p.SearchSubtitles(newTestLocalMedia(t, dir, "Inception.2010.720p.mkv"))
+	require.NoError(t, err)
+	require.Len(t, subs, 2)
+
+	assert.Equal(t, server.URL+"/subtitle/1", subs[0].Link())
+	assert.Equal(t, language.English, subs[0].Language())
+	assert.False(t, subs[0].HearingImpaired())
+	assert.Equal(t, quality.HD720p, subs[0].ForMedia().Meta().Quality())
+	assert.Equal(t, "REWARD", subs[0].ForMedia().Meta().Group())
+
+	assert.Equal(t, server.URL+"/subtitle/2", subs[1].Link())
+	assert.Equal(t, language.Danish, subs[1].Language())
+	assert.True(t, subs[1].HearingImpaired())
+
+	r, err := subs[0].Download()
+	require.NoError(t, err)
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "zipped subtitle", string(data))
+}
+
+func TestSiteNotSupported(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := newTestSiteConfig("http://localhost")
+	c.Media = []string{"episode"}
+
+	p, err := Site(c)
+	require.NoError(t, err)
+
+	_, err = p.SearchSubtitles(newTestLocalMedia(t, dir, "Inception.2010.720p.mkv"))
+	require.Error(t, err)
+	assert.True(t, IsErrMediaNotSupported(err))
+}
+
+func TestSiteDownloadFormat(t *testing.T) {
+	server := newSiteServer(t)
+	defer server.Close()
+
+	c := newTestSiteConfig(server.URL)
+	c.Download = ""
+
+	p, err := Site(c)
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"/raw/1": "plain subtitle",
+		"/raw/2": "zipped subtitle",
+	}
+
+	for link, expected := range tests {
+		dl, err := p.ResolveSubtitle(testLink(server.URL + link))
+		require.NoError(t, err)
+
+		r, err := dl.Download()
+		require.NoError(t, err)
+
+		data, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+
+		assert.Equal(t, expected, string(data))
+	}
+
+	_, err = p.ResolveSubtitle(testLink("file:///etc/passwd"))
+	assert.Error(t, err)
+
+	c.Unpack = "rar"
+	p, err = Site(c)
+	require.NoError(t, err)
+
+	dl, err := p.ResolveSubtitle(testLink(server.URL + "/raw/1"))
+	require.NoError(t, err)
+
+	_, err = dl.Download()
+	assert.Error(t, err)
+}
+
+func TestSiteInvalidConfig(t *testing.T) {
+	c := newTestSiteConfig("http://localhost")
+
+	invalid := []func(c *SiteConfig){
+		func(c *SiteConfig) { c.Name = "" },
+		func(c *SiteConfig) { c.Search = "" },
+		func(c *SiteConfig) { c.Search = "{{ .Title" },
+		func(c *SiteConfig) { c.Language = "" },
+		func(c *SiteConfig) { c.Media = []string{"music"} },
+		func(c *SiteConfig) { c.Unpack = "7z" },
+	}
+
+	for _, f := range invalid {
+		v := c
+		f(&v)
+		_, err := Site(v)
+		assert.Error(t, err)
+	}
+}
@@ -1,7 +1,7 @@
 package provider
 
 import (
-	"archive/zip"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -14,10 +14,8 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/nwaples/rardecode"
 	"github.com/xrash/smetrics"
 
 	"github.com/tympanix/supper/media"
@@ -33,20 +31,26 @@ import (
 // subsceneHost is the URL for subscene
 const subsceneHost = "https://subscene.com"
 
-// subsceneDelay is the delay between calls to subscene to prevent spamming
-const subsceneDelay = 500 * time.Millisecond
-
-var subsceneLock = new(sync.Mutex)
+// subsceneClient limits the number of calls to subscene to prevent spamming
+var subsceneClient = NewAPIClient("Subscene", 2, time.Hour)
 
 var subsceneIllegal = regexp.MustCompile(`[^\p{L}0-9\-\s]`)
 
-// lockSubscene is used to limit the number of calls to subscene to prevent spamming
-func lockSubscene() {
-	subsceneLock.Lock()
-	go func() {
-		time.Sleep(subsceneDelay)
-		subsceneLock.Unlock()
-	}()
+// subsceneDocument retrieves and parses a html page from subscene
+func subsceneDocument(ctx context.Context, url string) (*goquery.Document, error) {
+	resp, err := subsceneClient.GetContext(ctx, url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("subscene.com returned status code %v", resp.StatusCode)
+	}
+
+	return goquery.NewDocumentFromReader(resp.Body)
 }
 
 // Subscene interfaces with subscene.com for downloading subtitles
@@ -56,6 +60,10 @@ func Subscene() types.Provider {
 
 type subscene struct{}
 
+func (s *subscene) Name() string {
+	return "subscene"
+}
+
 func (s *subscene) ResolveSubtitle(l types.Linker) (types.Downloadable, error) {
 	return subsceneURL(l.Link()), nil
 }
@@ -101,7 +109,7 @@ func (s *subscene) cleanSearchTerm(search string) string {
 }
 
 // FindMediaURL retrieves the subscene.com URL for the given media item
-func (s *subscene) FindMediaURL(media types.Media, retries int) ([]searchResult, error) {
+func (s *subscene) FindMediaURL(ctx context.Context, media types.Media, retries int) ([]searchResult, error) {
 	searchURL, err := url.Parse("https://subscene.com/subtitles/searchbytitle")
 
 	if err != nil {
@@ -119,8 +127,7 @@ func (s *subscene) FindMediaURL(media types.Media, retries int) ([]searchResult,
 	data := url.Values{}
 	data.Add("query", s.cleanSearchTerm(search))
 
-	lockSubscene()
-	res, err := http.Post(searchURL.String(), "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+	res, err := subsceneClient.PostFormContext(ctx, searchURL.String(), data)
 	if err != nil {
 		return nil, err
 	}
@@ -131,8 +138,12 @@ func (s *subscene) FindMediaURL(media types.Media, retries int) ([]searchResult,
 		log.WithField("media", media).WithField("status", 409).
 			WithField("retries", retries).
 			Debug("Retrying subscene.com")
-		time.Sleep(1500 * time.Millisecond)
-		return s.FindMediaURL(media, retries-1)
+		select {
+		case <-time.After(1500 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return s.FindMediaURL(ctx, media, retries-1)
 	}
 
 	if res.StatusCode != 200 {
@@ -222,8 +233,14 @@ func (r resultList) Filter(m types.Media) resultList {
 }
 
 // SearchSubtitles searches subscene.com for subtitles
-func (s *subscene) SearchSubtitles(local types.LocalMedia) (subs []types.OnlineSubtitle, err error) {
-	search, err := s.FindMediaURL(local, 3)
+func (s *subscene) SearchSubtitles(local types.LocalMedia) ([]types.OnlineSubtitle, error) {
+	return s.SearchSubtitlesContext(context.Background(), local)
+}
+
+// SearchSubtitlesContext searches subscene.com for subtitles until the context
+// is cancelled
+func (s *subscene) SearchSubtitlesContext(ctx context.Context, local types.LocalMedia) (subs []types.OnlineSubtitle, err error) {
+	search, err := s.FindMediaURL(ctx, local, 3)
 
 	if err != nil {
 		return
@@ -245,8 +262,7 @@ func (s *subscene) SearchSubtitles(local types.LocalMedia) (subs []types.OnlineS
 
 	url.Path = best.Path
 
-	lockSubscene()
-	doc, err := goquery.NewDocument(url.String())
+	doc, err := subsceneDocument(ctx, url.String())
 
 	if err != nil {
 		return
@@ -295,102 +311,6 @@ func (s *subscene) SearchSubtitles(local types.LocalMedia) (subs []types.OnlineS
 	return
 }
 
-func newZipReader(file *os.File) (*zipReader, error) {
-	data, err := zip.OpenReader(file.Name())
-
-	if err != nil {
-		return nil, err
-	}
-
-	var srt io.ReadCloser
-	for _, f := range data.File {
-		if filepath.Ext(f.Name) == ".srt" {
-			if srt, err = f.Open(); err != nil {
-				return nil, err
-			}
-			break
-		}
-	}
-
-	if srt == nil {
-		return nil, errors.New("no srt file found in zip")
-	}
-
-	return &zipReader{srt, data, file}, nil
-}
-
-type zipReader struct {
-	io.ReadCloser
-	zip  *zip.ReadCloser
-	file *os.File
-}
-
-func (t *zipReader) Close() error {
-	t.ReadCloser.Close()
-	t.zip.Close()
-	t.file.Close()
-	if err := os.Remove(t.file.Name()); err != nil {
-		log.WithError(err).Error("Could not cleaup temporary zip file")
-		return err
-	}
-	return nil
-}
-
-func newRarReader(file *os.File) (*rarReader, error) {
-	r, err := rardecode.NewReader(file, "")
-
-	if err != nil {
-		return nil, err
-	}
-
-	var found bool
-	h, err := r.Next()
-	for err != io.EOF {
-		if filepath.Ext(h.Name) == ".srt" {
-			found = true
-			break
-		}
-		h, err = r.Next()
-	}
-
-	if !found {
-		return nil, errors.New("no subtitle found in rar archive")
-	}
-
-	return &rarReader{Reader: r, file: file}, nil
-}
-
-type rarReader struct {
-	*rardecode.Reader
-	file *os.File
-}
-
-func (r *rarReader) Close() error {
-	r.file.Close()
-	if err := os.Remove(r.file.Name()); err != nil {
-		log.WithError(err).Error("Could not cleaup temporary zip file")
-		return err
-	}
-	return nil
-}
-
-func newSrtReader(file *os.File) (*srtReader, error) {
-	return &srtReader{file}, nil
-}
-
-type srtReader struct {
-	*os.File
-}
-
-func (s *srtReader) Close() error {
-	s.File.Close()
-	if err := os.Remove(s.File.Name()); err != nil {
-		log.WithError(err).Error("Could not cleaup temporary zip file")
-		return err
-	}
-	return nil
-}
-
 type subsceneURL string
 
 func (uri subsceneURL) Link() string {
@@ -414,8 +334,7 @@ func (uri subsceneURL) Download() (r io.ReadCloser, err error) {
 		return nil, err
 	}
 
-	lockSubscene()
-	doc, err := goquery.NewDocument(suburl.String())
+	doc, err := subsceneDocument(context.Background(), suburl.String())
 
 	if err != nil {
 		return nil, err
@@ -435,7 +354,7 @@ func (uri subsceneURL) Download() (r io.ReadCloser, err error) {
 
 	download = fmt.Sprintf("%s%s", subsceneHost, download)
 
-	resp, err := http.Get(download)
+	resp, err := subsceneClient.Get(download)
 
 	if err != nil {
 		return nil, err
@@ -484,14 +403,7 @@ func (uri subsceneURL) Download() (r io.ReadCloser, err error) {
 		"format": strings.TrimPrefix(ext, "."),
 	}).Debug("Downloading from subscene.com")
 
-	if ext == ".zip" {
-		return newZipReader(file)
-	} else if ext == ".rar" {
-		return newRarReader(file)
-	} else if ext == ".srt" {
-		return newSrtReader(file)
-	}
-	return nil, fmt.Errorf("unknown subtitle format %s from subscene.com", ext)
+	return unpackSubtitle(file, ext, "")
 }
 
 type subsceneSubtitle struct {
@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,12 +18,10 @@ const tmdbHost = "https://api.themoviedb.org/3/"
 
 const tmdbTimeFormat = "2006-01-02"
 
-var tmdbClient = NewAPIClient("TheMovieDB", 35)
+var tmdbClient = NewAPIClient("TheMovieDB", 35, 24*time.Hour)
 
 var tmdbToken string
 
-var tmdbCache = make(map[string]types.Media)
-
 // TheMovieDB is a scraper for themoviedb.org
 func TheMovieDB(token string) types.Scraper {
 	if token == "" {
@@ -40,27 +39,27 @@ type tmdb struct {
 	token  string
 }
 
-func (t *tmdb) Scrape(m types.Media) (src types.Media, err error) {
+func (t *tmdb) Name() string {
+	return "themoviedb"
+}
+
+func (t *tmdb) Scrape(m types.Media) (types.Media, error) {
+	return t.ScrapeContext(context.Background(), m)
+}
+
+func (t *tmdb) ScrapeContext(ctx context.Context, m types.Media) (types.Media, error) {
 	if t.token == "" {
 		return nil, errors.New("tmdb: missing API token")
 	}
 	if m == nil {
 		return nil, errors.New("tmdb: can't scrape nil media")
 	}
-	if cache, ok := tmdbCache[m.Identity()]; ok {
-		return cache, nil
-	}
 	if movie, ok := m.TypeMovie(); ok {
-		src, err = t.searchMovie(movie)
+		return t.searchMovie(ctx, movie)
 	} else if sub, ok := m.TypeSubtitle(); ok {
-		src, err = t.Scrape(sub.ForMedia())
-	} else {
-		return nil, mediaNotSupported("tmdb")
-	}
-	if err == nil {
-		tmdbCache[m.Identity()] = src
+		return t.ScrapeContext(ctx, sub.ForMedia())
 	}
-	return
+	return nil, mediaNotSupported("tmdb")
 }
 
 func (t *tmdb) url(p string) (*url.URL, error) {
@@ -79,7 +78,7 @@ func (t *tmdb) url(p string) (*url.URL, error) {
 	return url, nil
 }
 
-func (t *tmdb) searchMovie(m types.Movie) (types.Media, error) {
+func (t *tmdb) searchMovie(ctx context.Context, m types.Movie) (types.Media, error) {
 	url, err := t.url("/search/movie")
 
 	if err != nil {
@@ -91,7 +90,7 @@ func (t *tmdb) searchMovie(m types.Movie) (types.Media, error) {
 	q.Set("year", strconv.Itoa(m.Year()))
 	url.RawQuery = q.Encode()
 
-	resp, err := t.client.Get(url.String())
+	resp, err := t.client.GetContext(ctx, url.String())
 
 	if err != nil {
 		return nil, err
@@ -122,7 +121,7 @@ func (t *tmdb) searchMovie(m types.Movie) (types.Media, error) {
 	}
 
 	if len(res.Results) == 0 {
-		return nil, errors.New("could not find media on tmdb")
+		return nil, notFound("tmdb")
 	}
 
 	d, err := time.Parse(tmdbTimeFormat, res.Results[0].ReleaseDate)
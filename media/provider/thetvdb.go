@@ -2,6 +2,7 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"net/url"
 	"path"
 	"strconv"
+	"time"
 
 	"github.com/tympanix/supper/media"
 	"github.com/tympanix/supper/types"
@@ -16,9 +18,7 @@ import (
 
 const thetvdbHost = "https://api.thetvdb.com/"
 
-var thetvdbClient = NewAPIClient("TheTVDB", 35)
-
-var thetvdbCache = make(map[string]types.Media)
+var thetvdbClient = NewAPIClient("TheTVDB", 35, 24*time.Hour)
 
 var thetvdbToken string
 
@@ -40,37 +40,39 @@ type thetvdb struct {
 	token  string
 }
 
-func (t *thetvdb) Scrape(m types.Media) (src types.Media, err error) {
+func (t *thetvdb) Name() string {
+	return "thetvdb"
+}
+
+func (t *thetvdb) Scrape(m types.Media) (types.Media, error) {
+	return t.ScrapeContext(context.Background(), m)
+}
+
+func (t *thetvdb) ScrapeContext(ctx context.Context, m types.Media) (types.Media, error) {
 	if t.key == "" {
 		return nil, errors.New("thetvdb: missing API key")
 	}
 	if m == nil {
 		return nil, errors.New("thetvdb: can't scrape nil media")
 	}
-	if cache, ok := thetvdbCache[m.Identity()]; ok {
-		return cache, nil
-	}
 	if e, ok := m.TypeEpisode(); ok {
-		src, err = t.searchTV(e)
+		return t.searchTV(ctx, e)
 	} else if sub, ok := m.TypeSubtitle(); ok {
-		src, err = t.Scrape(sub.ForMedia())
-	} else {
-		return nil, mediaNotSupported("thetvdb")
-	}
-	if err == nil {
-		thetvdbCache[m.Identity()] = src
+		return t.ScrapeContext(ctx, sub.ForMedia())
 	}
-	return
+	return nil, mediaNotSupported("thetvdb")
 }
 
-func (t *thetvdb) Get(url string) (*http.Response, error) {
+func (t *thetvdb) Get(ctx context.Context, url string) (*http.Response, error) {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if t.token == "" {
+	req = req.WithContext(ctx)
+
+	if t.token == "" && !Offline() {
 		return nil, errors.New("thetvdb: not authenticated")
 	}
 
@@ -91,7 +93,7 @@ func (t *thetvdb) url(p string) (*url.URL, error) {
 	return url, nil
 }
 
-func (t *thetvdb) authenticate() error {
+func (t *thetvdb) authenticate(ctx context.Context) error {
 	url, err := t.url("/login")
 
 	if err != nil {
@@ -110,7 +112,7 @@ func (t *thetvdb) authenticate() error {
 		return err
 	}
 
-	resp, err := t.client.Post(url.String(), "application/json", bytes.NewBuffer(data))
+	resp, err := t.client.PostContext(ctx, url.String(), "application/json", bytes.NewBuffer(data))
 
 	if err != nil {
 		return err
@@ -135,9 +137,10 @@ func (t *thetvdb) authenticate() error {
 	return nil
 }
 
-func (t *thetvdb) searchTV(e types.Episode) (types.Media, error) {
-	if t.token == "" {
-		if err := t.authenticate(); err != nil {
+func (t *thetvdb) searchTV(ctx context.Context, e types.Episode) (types.Media, error) {
+	// Cached responses are served without authentication in offline mode
+	if t.token == "" && !Offline() {
+		if err := t.authenticate(ctx); err != nil {
 			return nil, err
 		}
 	}
@@ -152,12 +155,17 @@ func (t *thetvdb) searchTV(e types.Episode) (types.Media, error) {
 	q.Set("name", e.TVShow())
 	url.RawQuery = q.Encode()
 
-	resp, err := t.Get(url.String())
+	resp, err := t.Get(ctx, url.String())
 
 	if err != nil {
 		return nil, err
 	}
 
+	if resp.StatusCode == 404 {
+		resp.Body.Close()
+		return nil, notFound("thetvdb")
+	}
+
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("thetvdb: api returned %v", resp.StatusCode)
 	}
@@ -179,7 +187,7 @@ func (t *thetvdb) searchTV(e types.Episode) (types.Media, error) {
 	}
 
 	if len(seriesData.Data) == 0 {
-		return nil, errors.New("no media found on thetvdb")
+		return nil, notFound("thetvdb")
 	}
 
 	url, err = t.url(fmt.Sprintf("/series/%v/episodes/query", seriesData.Data[0].ID))
@@ -193,12 +201,17 @@ func (t *thetvdb) searchTV(e types.Episode) (types.Media, error) {
 	q.Set("airedEpisode", strconv.Itoa(e.Episode()))
 	url.RawQuery = q.Encode()
 
-	resp, err = t.Get(url.String())
+	resp, err = t.Get(ctx, url.String())
 
 	if err != nil {
 		return nil, err
 	}
 
+	if resp.StatusCode == 404 {
+		resp.Body.Close()
+		return nil, notFound("thetvdb")
+	}
+
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("thetvdb: api returned %v", resp.StatusCode)
 	}
@@ -219,6 +232,10 @@ func (t *thetvdb) searchTV(e types.Episode) (types.Media, error) {
 		return nil, err
 	}
 
+	if len(episodeData.Data) == 0 {
+		return nil, notFound("thetvdb")
+	}
+
 	scraped := media.Episode{
 		NameX:        seriesData.Data[0].SeriesName,
 		EpisodeNameX: episodeData.Data[0].EpisodeName,
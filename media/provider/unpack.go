@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/apex/log"
+	"github.com/nwaples/rardecode"
+	"github.com/tympanix/supper/media/subformat"
+)
+
+// tempDownload stores the downloaded content in a temporary file, which is
+// rewinded such that it is ready to be unpacked
+func tempDownload(r io.Reader) (*os.File, error) {
+	file, err := ioutil.TempFile("", "supper")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = io.Copy(file, r); err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// sniffFormat detects the format of a downloaded file by its magic number.
+// Files which are not recognized as archives are assumed to be subtitles, and
+// are reported as srt regardless of the subtitle format
+func sniffFormat(file *os.File) (string, error) {
+	magic := make([]byte, 4)
+
+	n, err := file.ReadAt(magic, 0)
+
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	magic = magic[:n]
+
+	if bytes.Equal(magic, []byte("PK\x03\x04")) {
+		return ".zip", nil
+	} else if bytes.Equal(magic, []byte("Rar!")) {
+		return ".rar", nil
+	}
+	return ".srt", nil
+}
+
+// unpackSubtitle returns a reader for the subtitle in the temporary file. The
+// format is the extension of the downloaded file and pattern matches the name
+// of the subtitle inside archives. Any supported subtitle inside archives is
+// matched if no pattern is given. The temporary file is removed on close
+func unpackSubtitle(file *os.File, format string, pattern string) (io.ReadCloser, error) {
+	switch format {
+	case ".zip":
+		return newZipReader(file, pattern)
+	case ".rar":
+		return newRarReader(file, pattern)
+	}
+
+	if subformat.IsSubtitle(format) {
+		return newSubtitleReader(file)
+	}
+
+	file.Close()
+	os.Remove(file.Name())
+	return nil, fmt.Errorf("unknown subtitle format %s", format)
+}
+
+func matchPattern(pattern string, name string) bool {
+	if pattern == "" {
+		return subformat.IsSubtitle(name)
+	}
+	ok, err := filepath.Match(pattern, filepath.Base(name))
+	return err == nil && ok
+}
+
+func newZipReader(file *os.File, pattern string) (*zipReader, error) {
+	data, err := zip.OpenReader(file.Name())
+
+	if err != nil {
+		return nil, err
+	}
+
+	var srt io.ReadCloser
+	for _, f := range data.File {
+		if matchPattern(pattern, f.Name) {
+			if srt, err = f.Open(); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if srt == nil {
+		return nil, errors.New("no subtitle found in zip")
+	}
+
+	return &zipReader{srt, data, file}, nil
+}
+
+type zipReader struct {
+	io.ReadCloser
+	zip  *zip.ReadCloser
+	file *os.File
+}
+
+func (t *zipReader) Close() error {
+	t.ReadCloser.Close()
+	t.zip.Close()
+	t.file.Close()
+	if err := os.Remove(t.file.Name()); err != nil {
+		log.WithError(err).Error("Could not cleaup temporary zip file")
+		return err
+	}
+	return nil
+}
+
+func newRarReader(file *os.File, pattern string) (*rarReader, error) {
+	r, err := rardecode.NewReader(file, "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var found bool
+	h, err := r.Next()
+	for err != io.EOF {
+		if err != nil {
+			return nil, err
+		}
+		if matchPattern(pattern, h.Name) {
+			found = true
+			break
+		}
+		h, err = r.Next()
+	}
+
+	if !found {
+		return nil, errors.New("no subtitle found in rar archive")
+	}
+
+	return &rarReader{Reader: r, file: file}, nil
+}
+
+type rarReader struct {
+	*rardecode.Reader
+	file *os.File
+}
+
+func (r *rarReader) Close() error {
+	r.file.Close()
+	if err := os.Remove(r.file.Name()); err != nil {
+		log.WithError(err).Error("Could not cleaup temporary zip file")
+		return err
+	}
+	return nil
+}
+
+func newSubtitleReader(file *os.File) (*subtitleReader, error) {
+	return &subtitleReader{file}, nil
+}
+
+type subtitleReader struct {
+	*os.File
+}
+
+func (s *subtitleReader) Close() error {
+	s.File.Close()
+	if err := os.Remove(s.File.Name()); err != nil {
+		log.WithError(err).Error("Could not cleaup temporary zip file")
+		return err
+	}
+	return nil
+}
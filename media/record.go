@@ -0,0 +1,158 @@
+package media
+
+import (
+	"errors"
+	"os"
+
+	"github.com/tympanix/supper/media/meta/codec"
+	"github.com/tympanix/supper/media/meta/misc"
+	"github.com/tympanix/supper/media/meta/quality"
+	"github.com/tympanix/supper/media/meta/source"
+	"github.com/tympanix/supper/types"
+	"golang.org/x/text/language"
+)
+
+// Record is the information parsed from the filename of a media file. It can
+// be stored and turned back into media without parsing the filename again
+type Record struct {
+	Type        string      `json:"type"`
+	Name        string      `json:"name"`
+	Year        int         `json:"year,omitempty"`
+	Season      int         `json:"season,omitempty"`
+	Episode     int         `json:"episode,omitempty"`
+	EpisodeName string      `json:"episode_name,omitempty"`
+	Group       string      `json:"group,omitempty"`
+	Codec       codec.Tag   `json:"codec,omitempty"`
+	Quality     quality.Tag `json:"quality,omitempty"`
+	Source      source.Tag  `json:"source,omitempty"`
+	Misc        misc.List   `json:"misc,omitempty"`
+	Tags        []string    `json:"tags,omitempty"`
+	Subtitle    bool        `json:"subtitle,omitempty"`
+	Language    string      `json:"language,omitempty"`
+}
+
+// NewRecord returns the record of the media parsed from a filename
+func NewRecord(m types.Media) (*Record, error) {
+	switch v := m.(type) {
+	case *Video:
+		return NewRecord(v.File.Media)
+	case *File:
+		return NewRecord(v.Media)
+	case *LocalSubtitle:
+		return NewRecord(v.Subtitle)
+	case *Subtitle:
+		r, err := NewRecord(v.ForMedia())
+		if err != nil {
+			return nil, err
+		}
+		r.Subtitle = true
+		if v.Language() != language.Und {
+			r.Language = v.Language().String()
+		}
+		return r, nil
+	}
+
+	r := &Record{
+		Group:   m.Meta().Group(),
+		Codec:   m.Meta().Codec(),
+		Quality: m.Meta().Quality(),
+		Source:  m.Meta().Source(),
+		Misc:    m.Meta().Misc(),
+		Tags:    m.Meta().AllTags(),
+	}
+
+	if movie, ok := m.TypeMovie(); ok {
+		r.Type = "movie"
+		r.Name = movie.MovieName()
+		r.Year = movie.Year()
+	} else if episode, ok := m.TypeEpisode(); ok {
+		r.Type = "episode"
+		r.Name = episode.TVShow()
+		r.Season = episode.Season()
+		r.Episode = episode.Episode()
+		r.EpisodeName = episode.EpisodeName()
+	} else {
+		return nil, errors.New("media can not be recorded")
+	}
+
+	return r, nil
+}
+
+// media returns the movie or episode of the record
+func (r *Record) media() (types.Media, error) {
+	meta := Metadata{
+		group:   r.Group,
+		codec:   r.Codec,
+		quality: r.Quality,
+		source:  r.Source,
+		misc:    r.Misc,
+		tags:    r.Tags,
+	}
+
+	switch r.Type {
+	case "movie":
+		return &Movie{
+			Metadata: meta,
+			NameX:    r.Name,
+			YearX:    r.Year,
+		}, nil
+	case "episode":
+		return &Episode{
+			Metadata:     meta,
+			NameX:        r.Name,
+			EpisodeNameX: r.EpisodeName,
+			SeasonX:      r.Season,
+			EpisodeX:     r.Episode,
+		}, nil
+	}
+
+	return nil, errors.New("unknown media type in record")
+}
+
+// subtitle returns the subtitle of the record
+func (r *Record) subtitle() (*Subtitle, error) {
+	if !r.Subtitle {
+		return nil, errors.New("record is not a subtitle")
+	}
+	m, err := r.media()
+	if err != nil {
+		return nil, err
+	}
+	lang := language.Und
+	if r.Language != "" {
+		lang = language.Make(r.Language)
+	}
+	return &Subtitle{
+		forMedia: m,
+		lang:     lang,
+	}, nil
+}
+
+// LocalFile returns the media of the record for the file at the path
+func (r *Record) LocalFile(path string, info os.FileInfo) (types.LocalMedia, error) {
+	if r.Subtitle {
+		sub, err := r.subtitle()
+		if err != nil {
+			return nil, err
+		}
+		return newLocalFile(path, info, sub), nil
+	}
+	m, err := r.media()
+	if err != nil {
+		return nil, err
+	}
+	return newLocalFile(path, info, m), nil
+}
+
+// LocalSubtitle returns the subtitle of the record for the file at the path
+func (r *Record) LocalSubtitle(path string, info os.FileInfo) (types.LocalSubtitle, error) {
+	sub, err := r.subtitle()
+	if err != nil {
+		return nil, err
+	}
+	return &LocalSubtitle{
+		FileInfo: info,
+		Pather:   FilePath(path),
+		Subtitle: sub,
+	}, nil
+}
@@ -0,0 +1,56 @@
+package media
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/types"
+)
+
+func TestRecord(t *testing.T) {
+	info, err := os.Stat("test/Inception 2010 720p.mp4")
+	require.NoError(t, err)
+
+	for _, name := range []string{
+		"Inception.2010.720p.BluRay.x264-REWARD.mkv",
+		"Game.of.Thrones.S02E05.The.Ghost.of.Harrenhal.1080p.WEB-DL.mkv",
+		"Inception.2010.720p.BluRay.x264-REWARD.de.srt",
+		"Inception.2010.720p.BluRay.x264-REWARD.srt",
+	} {
+		parsed, err := NewLocalFileInfo(name, info)
+		require.NoError(t, err, name)
+
+		r, err := NewRecord(parsed)
+		require.NoError(t, err, name)
+
+		data, err := json.Marshal(r)
+		require.NoError(t, err, name)
+
+		var stored Record
+		require.NoError(t, json.Unmarshal(data, &stored), name)
+
+		m, err := stored.LocalFile(name, info)
+		require.NoError(t, err, name)
+
+		assert.Equal(t, parsed.Identity(), m.Identity(), name)
+		assert.Equal(t, parsed.Meta(), m.Meta(), name)
+		assert.Equal(t, parsed.String(), m.String(), name)
+
+		_, video := parsed.(types.Video)
+		_, ok := m.(types.Video)
+		assert.Equal(t, video, ok, name)
+
+		if s, ok := parsed.TypeSubtitle(); ok {
+			sub, err := stored.LocalSubtitle(name, info)
+			require.NoError(t, err, name)
+			assert.Equal(t, s.Language(), sub.Language(), name)
+			assert.Equal(t, s.ForMedia().Identity(), sub.ForMedia().Identity(), name)
+		} else {
+			_, err := stored.LocalSubtitle(name, info)
+			assert.Error(t, err, name)
+		}
+	}
+}
@@ -18,6 +18,9 @@ const (
 	groupWeight   = 0.33
 )
 
+// hashScore is the score given to media matched by file hash
+const hashScore = 0.99
+
 const (
 	missingMultiplier     = 2.25
 	unavailableMultiplier = 0.18
@@ -36,6 +39,9 @@ func (e *DefaultEvaluator) Evaluate(f types.Media, s types.Media) float32 {
 	if s == nil || s.Meta() == nil {
 		return 0.0
 	}
+	if h, ok := s.(types.HashMatcher); ok && h.HashMatch() {
+		return hashScore
+	}
 	if !f.Similar(s) {
 		return 0.0
 	}
@@ -0,0 +1,33 @@
+package score_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tympanix/supper/media"
+	"github.com/tympanix/supper/media/score"
+	"github.com/tympanix/supper/types"
+)
+
+// hashMatch is media which was matched against the local media by file hash
+type hashMatch struct {
+	types.Media
+}
+
+func (hashMatch) HashMatch() bool {
+	return true
+}
+
+func TestEvaluateHashMatch(t *testing.T) {
+	movie, err := media.NewMovie("Inception.2010.720p.BluRay.x264-REWARD")
+	require.NoError(t, err)
+
+	// the provider has registered the release under another year
+	sub, err := media.NewMovie("Inception.2008.720p.BluRay.x264-REWARD")
+	require.NoError(t, err)
+
+	e := new(score.DefaultEvaluator)
+	assert.Equal(t, float32(0.0), e.Evaluate(movie, sub))
+	assert.InDelta(t, 0.99, e.Evaluate(movie, hashMatch{sub}), 0.001)
+}
@@ -0,0 +1,173 @@
+package sidecar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tympanix/supper/media/subformat"
+)
+
+// Filename is the name of the file in each directory which describes the
+// subtitles downloaded to the directory
+const Filename = ".supper.json"
+
+// Record describes where a downloaded subtitle came from
+type Record struct {
+	Provider        string    `json:"provider,omitempty"`
+	Link            string    `json:"link,omitempty"`
+	Release         string    `json:"release,omitempty"`
+	Score           float32   `json:"score"`
+	HearingImpaired bool      `json:"hi"`
+	Downloaded      time.Time `json:"downloaded"`
+	Hash            string    `json:"hash,omitempty"`
+	Edited          bool      `json:"edited,omitempty"`
+	Check           *Check    `json:"check,omitempty"`
+}
+
+// Check is the outcome of the sanity checks of a downloaded subtitle
+type Check struct {
+	subformat.Stats
+	// Duration is the duration of the video, if known
+	Duration time.Duration `json:"duration,omitempty"`
+	// Problems found with the subtitle
+	Problems []string `json:"problems,omitempty"`
+}
+
+// mu serializes updates of the sidecar files, since subtitles for several
+// videos in the same directory may be downloaded at once
+var mu sync.Mutex
+
+// Get returns the record of the subtitle at the path. False is returned if
+// the subtitle has no record
+func Get(path string) (Record, bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	records, err := read(filepath.Dir(path))
+	if err != nil {
+		return Record{}, false, err
+	}
+	r, ok := records[filepath.Base(path)]
+	return r, ok, nil
+}
+
+// List returns the records of every subtitle in the directory, by filename
+func List(dir string) (map[string]Record, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return read(dir)
+}
+
+// Set stores the record of the subtitle at the path
+func Set(path string, r Record) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	dir := filepath.Dir(path)
+	records, err := read(dir)
+	if err != nil {
+		return err
+	}
+	records[filepath.Base(path)] = r
+	return write(dir, records)
+}
+
+// MarkEdited marks the record of the subtitle at the path as edited, after
+// its content has been changed by supper. The hash of the downloaded content is
+// kept. Nothing is done if the subtitle has no record
+func MarkEdited(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	dir := filepath.Dir(path)
+	records, err := read(dir)
+	if err != nil {
+		return err
+	}
+	r, ok := records[filepath.Base(path)]
+	if !ok || r.Edited {
+		return nil
+	}
+	r.Edited = true
+	records[filepath.Base(path)] = r
+	return write(dir, records)
+}
+
+// Remove forgets the record of the subtitle at the path
+func Remove(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	dir := filepath.Dir(path)
+	records, err := read(dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := records[filepath.Base(path)]; !ok {
+		return nil
+	}
+	delete(records, filepath.Base(path))
+	if len(records) == 0 {
+		return os.Remove(filepath.Join(dir, Filename))
+	}
+	return write(dir, records)
+}
+
+// Hash returns the SHA-256 hash of the content of the file at the path, as
+// stored in records
+func Hash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return HashReader(f)
+}
+
+// HashReader returns the SHA-256 hash of the content read from the reader
+func HashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// read returns the records of the subtitles in the directory
+func read(dir string) (map[string]Record, error) {
+	records := make(map[string]Record)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, Filename))
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func write(dir string, records map[string]Record) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(dir, Filename+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, Filename))
+}
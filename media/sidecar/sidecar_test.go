@@ -0,0 +1,90 @@
+package sidecar
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSidecar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "Inception.2010.720p.en.srt")
+
+	_, ok, err := Get(path)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	now := time.Now().Round(time.Second)
+	require.NoError(t, Set(path, Record{Score: 0.55, Provider: "subscene", Downloaded: now}))
+	require.NoError(t, Set(filepath.Join(dir, "Inception.2010.720p.de.srt"), Record{Score: 0.9}))
+
+	r, ok, err := Get(path)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, float32(0.55), r.Score)
+	assert.Equal(t, "subscene", r.Provider)
+	assert.True(t, now.Equal(r.Downloaded))
+
+	records, err := List(dir)
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, float32(0.9), records["Inception.2010.720p.de.srt"].Score)
+
+	require.NoError(t, MarkEdited(path))
+	require.NoError(t, MarkEdited(filepath.Join(dir, "Inception.2010.720p.fr.srt")))
+	r, _, err = Get(path)
+	require.NoError(t, err)
+	assert.True(t, r.Edited)
+	assert.Equal(t, "subscene", r.Provider)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, Filename, files[0].Name())
+
+	require.NoError(t, Remove(path))
+	_, ok, err = Get(path)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// The file is removed with the last record
+	require.NoError(t, Remove(filepath.Join(dir, "Inception.2010.720p.de.srt")))
+	files, err = ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestSidecarInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, Filename), []byte("{"), 0644))
+
+	_, _, err = Get(filepath.Join(dir, "Inception.2010.720p.en.srt"))
+	assert.Error(t, err)
+}
+
+func TestSidecarHash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supper")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "Inception.2010.720p.en.srt")
+	require.NoError(t, ioutil.WriteFile(path, []byte("hello"), 0644))
+
+	h, err := Hash(path)
+	require.NoError(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", h)
+
+	_, err = Hash(filepath.Join(dir, "missing.srt"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,294 @@
+package subformat
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// alignThreshold is the similarity above which a cue may be matched with a
+// cue of the reference
+const alignThreshold = 0.75
+
+// alignTolerance is the largest difference between the aligned time of a cue
+// and the time of the matched reference cue, for the match to be consistent
+const alignTolerance = 300 * time.Millisecond
+
+// alignJump is the change in offset between consecutive matches which starts
+// a new linear segment of the alignment (e.g. at a cut or an ad break)
+const alignJump = time.Second
+
+// alignMinSegment is the fewest matches which make up a segment
+const alignMinSegment = 3
+
+// alignEdgeGap is the gap assumed before the first and after the last cue
+const alignEdgeGap = 10 * time.Second
+
+// Alignment is the result of aligning a subtitle to a reference subtitle
+type Alignment struct {
+	// Timing maps the times of the subtitle to the times of the reference
+	Timing Timing
+	// Segments is the number of linear pieces of the timing
+	Segments int
+	// Matched is the number of cues consistently matched with the reference
+	Matched int
+	// Confidence is the fraction of the cues consistently matched with the
+	// reference, between 0 and 1
+	Confidence float64
+}
+
+// match is a cue of the subtitle matched with a cue of the reference
+type match struct {
+	target    time.Duration
+	reference time.Duration
+}
+
+// segment maps the times of the subtitle between from and to linearly
+type segment struct {
+	from  time.Duration
+	to    time.Duration
+	scale float64
+	shift float64
+}
+
+func (s segment) apply(d time.Duration) time.Duration {
+	return time.Duration(s.scale*float64(d) + s.shift)
+}
+
+// Align computes the timing which aligns the cues of the subtitle with the
+// cues of the reference subtitle (e.g. a correctly timed subtitle in another
+// language for the same release). Cues are matched by the structure of their
+// timing and lines, and a piecewise linear timing is fitted to the matches
+func Align(reference, target []Cue) (Alignment, error) {
+	if len(reference) == 0 || len(target) == 0 {
+		return Alignment{}, errors.New("no cues to align")
+	}
+
+	matches := matchCues(reference, target)
+	segments := fitSegments(matches)
+
+	if len(segments) == 0 {
+		return Alignment{}, errors.New("subtitles could not be aligned")
+	}
+
+	timing := segmentTiming(segments)
+
+	var matched int
+	for _, m := range matches {
+		if absDuration(timing(m.target)-m.reference) <= alignTolerance {
+			matched++
+		}
+	}
+
+	return Alignment{
+		Timing:     timing,
+		Segments:   len(segments),
+		Matched:    matched,
+		Confidence: float64(matched) / float64(len(target)),
+	}, nil
+}
+
+// matchCues pairs the cues of the subtitle with the cues of the reference,
+// keeping the order of the cues, such that the total similarity of the pairs
+// is the highest possible
+func matchCues(reference, target []Cue) []match {
+	const (
+		skipTarget byte = iota + 1
+		skipReference
+		matchBoth
+	)
+
+	n, m := len(target), len(reference)
+	prev := make([]float64, m+1)
+	cur := make([]float64, m+1)
+	choice := make([]byte, (n+1)*(m+1))
+
+	for i := 1; i <= n; i++ {
+		cur[0] = 0
+		for j := 1; j <= m; j++ {
+			best, c := prev[j], skipTarget
+			if cur[j-1] > best {
+				best, c = cur[j-1], skipReference
+			}
+			s := cueSimilarity(target, reference, i-1, j-1) - alignThreshold
+			if s > 0 && prev[j-1]+s > best {
+				best, c = prev[j-1]+s, matchBoth
+			}
+			cur[j] = best
+			choice[i*(m+1)+j] = c
+		}
+		prev, cur = cur, prev
+	}
+
+	var matches []match
+	for i, j := n, m; i > 0 && j > 0; {
+		switch choice[i*(m+1)+j] {
+		case matchBoth:
+			matches = append(matches, match{target[i-1].Start, reference[j-1].Start})
+			i, j = i-1, j-1
+		case skipTarget:
+			i--
+		default:
+			j--
+		}
+	}
+
+	for l, r := 0, len(matches)-1; l < r; l, r = l+1, r-1 {
+		matches[l], matches[r] = matches[r], matches[l]
+	}
+	return matches
+}
+
+// cueSimilarity returns how alike two cues are by their duration, the gaps
+// around them and their number of lines, between 0 and 1
+func cueSimilarity(a, b []Cue, i, j int) float64 {
+	sim := 0.4 * durationRatio(a[i].End-a[i].Start, b[j].End-b[j].Start)
+	sim += 0.2 * durationRatio(gapBefore(a, i), gapBefore(b, j))
+	sim += 0.2 * durationRatio(gapAfter(a, i), gapAfter(b, j))
+	if len(a[i].Lines) == len(b[j].Lines) {
+		sim += 0.2
+	}
+	return sim
+}
+
+// durationRatio returns the ratio of the shortest to the longest duration
+func durationRatio(a, b time.Duration) float64 {
+	a, b = clamp(a)+100*time.Millisecond, clamp(b)+100*time.Millisecond
+	if a > b {
+		a, b = b, a
+	}
+	return float64(a) / float64(b)
+}
+
+func gapBefore(c []Cue, i int) time.Duration {
+	if i == 0 {
+		return alignEdgeGap
+	}
+	return c[i].Start - c[i-1].End
+}
+
+func gapAfter(c []Cue, i int) time.Duration {
+	if i == len(c)-1 {
+		return alignEdgeGap
+	}
+	return c[i+1].Start - c[i].End
+}
+
+// fitSegments splits the matches where the offset between the subtitle and
+// the reference changes abruptly, and fits a line to each part. Parts with
+// too few matches are ignored
+func fitSegments(matches []match) []segment {
+	offsets := make([]time.Duration, len(matches))
+	for k, m := range matches {
+		offsets[k] = m.reference - m.target
+	}
+	offsets = medianFilter(offsets, 2)
+
+	var segments []segment
+	begin := 0
+	for k := 1; k <= len(matches); k++ {
+		if k < len(matches) && absDuration(offsets[k]-offsets[k-1]) <= alignJump {
+			continue
+		}
+		if s, ok := fitLine(matches[begin:k]); ok {
+			segments = append(segments, s)
+		}
+		begin = k
+	}
+	return segments
+}
+
+// fitLine fits a line to the matches by least squares. The line is fitted
+// again without the matches which are far from the first line
+func fitLine(matches []match) (segment, bool) {
+	s, ok := leastSquares(matches)
+	if !ok {
+		return s, false
+	}
+
+	var inliers []match
+	for _, m := range matches {
+		if absDuration(s.apply(m.target)-m.reference) <= alignTolerance {
+			inliers = append(inliers, m)
+		}
+	}
+	if len(inliers) < len(matches) {
+		if better, ok := leastSquares(inliers); ok {
+			s = better
+		}
+	}
+
+	// Subtitles are never played at a vastly different speed
+	if s.scale < 0.5 || s.scale > 2 {
+		return s, false
+	}
+	return s, true
+}
+
+func leastSquares(matches []match) (segment, bool) {
+	if len(matches) < alignMinSegment {
+		return segment{}, false
+	}
+
+	var sx, sy, sxx, sxy float64
+	for _, m := range matches {
+		x, y := float64(m.target), float64(m.reference)
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+
+	n := float64(len(matches))
+	det := n*sxx - sx*sx
+	if det == 0 {
+		return segment{}, false
+	}
+
+	scale := (n*sxy - sx*sy) / det
+	return segment{
+		from:  matches[0].target,
+		to:    matches[len(matches)-1].target,
+		scale: scale,
+		shift: (sy - scale*sx) / n,
+	}, true
+}
+
+// segmentTiming returns the timing which uses the segment closest to each
+// time. Segments must be sorted by time
+func segmentTiming(segments []segment) Timing {
+	return func(d time.Duration) time.Duration {
+		s := segments[0]
+		for k := 1; k < len(segments); k++ {
+			if d >= (segments[k-1].to+segments[k].from)/2 {
+				s = segments[k]
+			}
+		}
+		return s.apply(d)
+	}
+}
+
+// medianFilter replaces each value by the median of the values at most width
+// positions away, removing single outliers
+func medianFilter(values []time.Duration, width int) []time.Duration {
+	filtered := make([]time.Duration, len(values))
+	window := make([]time.Duration, 0, 2*width+1)
+	for k := range values {
+		window = window[:0]
+		for l := k - width; l <= k+width; l++ {
+			if l >= 0 && l < len(values) {
+				window = append(window, values[l])
+			}
+		}
+		sort.Slice(window, func(a, b int) bool { return window[a] < window[b] })
+		filtered[k] = window[len(window)/2]
+	}
+	return filtered
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,87 @@
+package subformat
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// randomCues returns cues with varied durations, gaps and lines
+func randomCues(seed int64, n int) []Cue {
+	r := rand.New(rand.NewSource(seed))
+	cues := make([]Cue, n)
+	at := 20 * time.Second
+	for i := range cues {
+		at += time.Duration(100+r.Intn(5000)) * time.Millisecond
+		end := at + time.Duration(800+r.Intn(4000))*time.Millisecond
+		lines := []string{"line"}
+		if r.Intn(2) == 0 {
+			lines = append(lines, "line")
+		}
+		cues[i] = Cue{Start: at, End: end, Lines: lines}
+		at = end
+	}
+	return cues
+}
+
+func TestAlign(t *testing.T) {
+	reference := randomCues(1, 300)
+
+	rate, err := Framerate(23.976, 25)
+	require.NoError(t, err)
+
+	// The subtitle is for another frame rate, starts later and is missing a
+	// few cues of the reference
+	var target []Cue
+	for i, c := range Chain(rate, Shift(7*time.Second)).Apply(reference) {
+		if i%25 != 10 {
+			target = append(target, c)
+		}
+	}
+
+	a, err := Align(reference, target)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, a.Segments)
+	assert.True(t, a.Confidence > 0.9, "confidence %v", a.Confidence)
+
+	aligned := a.Timing.Apply(target)
+	for i, j := 0, 0; i < len(aligned); i, j = i+1, j+1 {
+		if j%25 == 10 {
+			j++
+		}
+		assert.InDelta(t, reference[j].Start, aligned[i].Start, float64(50*time.Millisecond))
+	}
+}
+
+func TestAlignSegments(t *testing.T) {
+	reference := randomCues(2, 300)
+
+	// The subtitle is for a release with a break in the middle
+	target := Shift(-3 * time.Second).Apply(reference)
+	target = append(target[:150], Shift(45*time.Second).Apply(target[150:])...)
+
+	a, err := Align(reference, target)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, a.Segments)
+	assert.True(t, a.Confidence > 0.9, "confidence %v", a.Confidence)
+
+	aligned := a.Timing.Apply(target)
+	for i := range aligned {
+		assert.Equal(t, reference[i].Start, aligned[i].Start)
+	}
+}
+
+func TestAlignUnrelated(t *testing.T) {
+	a, err := Align(randomCues(3, 300), randomCues(4, 300))
+	if err == nil {
+		assert.True(t, a.Confidence < 0.2, "confidence %v", a.Confidence)
+	}
+
+	_, err = Align(nil, randomCues(4, 10))
+	assert.Error(t, err)
+}
@@ -0,0 +1,252 @@
+package subformat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var assOverride = regexp.MustCompile(`\{[^}]*\}`)
+
+var assFields = []string{
+	"Layer", "Start", "End", "Style", "Name", "MarginL", "MarginR", "MarginV", "Effect", "Text",
+}
+
+const assHeader = `[Script Info]
+ScriptType: v4.00+
+PlayResX: 384
+PlayResY: 288
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,20,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,2,10,10,10,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+const ssaHeader = `[Script Info]
+ScriptType: v4.00
+PlayResX: 384
+PlayResY: 288
+
+[V4 Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, TertiaryColour, BackColour, Bold, Italic, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, AlphaLevel, Encoding
+Style: Default,Arial,20,16777215,65535,65535,0,0,0,1,2,2,2,10,10,10,0,0
+
+[Events]
+Format: Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+// ass is the Advanced SubStation Alpha format, or the SubStation Alpha format
+// which it is based on
+type ass struct {
+	ssa bool
+}
+
+func (a ass) Name() string {
+	if a.ssa {
+		return "ssa"
+	}
+	return "ass"
+}
+
+func (a ass) Ext() string {
+	return "." + a.Name()
+}
+
+func (a ass) Match(data []byte) bool {
+	if !bytes.Contains(data, []byte("[Script Info]")) {
+		return false
+	}
+	plus := bytes.Contains(data, []byte("[V4+ Styles]")) ||
+		bytes.Contains(bytes.ToLower(data), []byte("scripttype: v4.00+"))
+	return plus != a.ssa
+}
+
+func (ass) Decode(r io.Reader) ([]Cue, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var cues []Cue
+	var events bool
+	fields := assFields
+
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "[") {
+			events = strings.EqualFold(l, "[Events]")
+			continue
+		}
+		if !events {
+			continue
+		}
+
+		i := strings.Index(l, ":")
+		if i < 0 {
+			continue
+		}
+		key, value := l[:i], strings.TrimSpace(l[i+1:])
+
+		switch key {
+		case "Format":
+			fields = strings.Split(value, ",")
+			for i := range fields {
+				fields[i] = strings.TrimSpace(fields[i])
+			}
+		case "Dialogue":
+			c, err := assCue(fields, strings.SplitN(value, ",", len(fields)))
+			if err != nil {
+				return nil, err
+			}
+			cues = append(cues, c)
+		}
+	}
+	return cues, nil
+}
+
+// assCue returns the cue of the values of a dialogue line
+func assCue(fields []string, values []string) (Cue, error) {
+	var c Cue
+	for i, f := range fields {
+		if i >= len(values) {
+			break
+		}
+		var err error
+		switch f {
+		case "Start":
+			c.Start, err = parseClock(values[i])
+		case "End":
+			c.End, err = parseClock(values[i])
+		case "Text":
+			c.Lines = assText(values[i])
+		}
+		if err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// assText returns the lines of the text without override codes
+func assText(text string) []string {
+	text = assOverride.ReplaceAllString(text, "")
+	text = strings.Replace(text, `\h`, " ", -1)
+	text = strings.Replace(text, `\n`, `\N`, -1)
+	return strings.Split(text, `\N`)
+}
+
+func (ass) retime(lines []string, t Timing) error {
+	var events bool
+	fields := assFields
+
+	for n, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if strings.HasPrefix(trimmed, "[") {
+			events = strings.EqualFold(trimmed, "[Events]")
+			continue
+		}
+		if !events {
+			continue
+		}
+
+		i := strings.Index(l, ":")
+		if i < 0 {
+			continue
+		}
+
+		switch strings.TrimSpace(l[:i]) {
+		case "Format":
+			fields = strings.Split(strings.TrimSpace(l[i+1:]), ",")
+			for i := range fields {
+				fields[i] = strings.TrimSpace(fields[i])
+			}
+		case "Dialogue", "Comment":
+			values := strings.SplitN(l[i+1:], ",", len(fields))
+			for j, f := range fields {
+				if j >= len(values) || (f != "Start" && f != "End") {
+					continue
+				}
+				v := strings.TrimSpace(values[j])
+				retimed, err := retimeClock(v, t)
+				if err != nil {
+					return err
+				}
+				values[j] = strings.Replace(values[j], v, retimed, 1)
+			}
+			lines[n] = l[:i+1] + strings.Join(values, ",")
+		}
+	}
+	return nil
+}
+
+func (ass) spans(lines []string) []span {
+	var spans []span
+	var events bool
+	fields := assFields
+
+	for n, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if strings.HasPrefix(trimmed, "[") {
+			events = strings.EqualFold(trimmed, "[Events]")
+			continue
+		}
+		if !events {
+			continue
+		}
+
+		i := strings.Index(trimmed, ":")
+		if i < 0 {
+			continue
+		}
+
+		switch trimmed[:i] {
+		case "Format":
+			fields = strings.Split(strings.TrimSpace(trimmed[i+1:]), ",")
+			for i := range fields {
+				fields[i] = strings.TrimSpace(fields[i])
+			}
+		case "Dialogue":
+			// The text is the last field, which may contain commas
+			line := strings.TrimRight(l, "\r")
+			values := strings.SplitN(line[strings.Index(line, ":")+1:], ",", len(fields))
+			offset := len(line)
+			if len(values) == len(fields) {
+				offset -= len(values[len(values)-1])
+			}
+			spans = append(spans, span{start: n, text: n, end: n + 1, offset: offset, number: -1})
+		}
+	}
+	return spans
+}
+
+func (ass) replace(lines []string, s span, text []string) []string {
+	return []string{lines[s.start][:s.offset] + strings.Join(text, `\N`)}
+}
+
+func (a ass) Encode(w io.Writer, cues []Cue) error {
+	buf := bufio.NewWriter(w)
+	if a.ssa {
+		fmt.Fprint(buf, ssaHeader)
+	} else {
+		fmt.Fprint(buf, assHeader)
+	}
+
+	marked := "0"
+	if a.ssa {
+		marked = "Marked=0"
+	}
+
+	for _, c := range cues {
+		fmt.Fprintf(buf, "Dialogue: %s,%s,%s,Default,,0,0,0,,%s\n", marked,
+			formatClock(c.Start, 1, ".", 2),
+			formatClock(c.End, 1, ".", 2),
+			strings.Join(c.Lines, `\N`))
+	}
+	return buf.Flush()
+}
@@ -0,0 +1,183 @@
+package subformat
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// span is the lines of a cue within a subtitle, from the line start until the
+// line end. The text of the cue begins at the line text, or at the offset
+// within the line for formats with one line per cue. The number is the line
+// which numbers the cue, if any
+type span struct {
+	start  int
+	text   int
+	end    int
+	offset int
+	number int
+}
+
+// editor is implemented by formats which can change the text of cues in
+// place, keeping everything else of the subtitle untouched
+type editor interface {
+	spans(lines []string) []span
+	replace(lines []string, s span, text []string) []string
+}
+
+// Edit replaces the lines of every cue of the subtitle, in any supported
+// format, with the lines returned by the function. Cues are removed when no
+// lines are returned. Only cues which are changed are rewritten, such that
+// headers, styles, formatting and any text which is not understood are kept
+// as they are. Numbered cues are numbered again when cues are removed
+func Edit(data []byte, fn func(Cue) []string) ([]byte, error) {
+	f, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cues, err := f.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(trimBOM(data)), "\n")
+
+	e, ok := f.(editor)
+	if !ok {
+		return nil, fmt.Errorf("%v subtitles can not be edited", f.Name())
+	}
+	spans := e.spans(lines)
+	if len(spans) != len(cues) {
+		return nil, fmt.Errorf("cues of the %v subtitle can not be edited", f.Name())
+	}
+
+	texts := make([][]string, len(cues))
+	edits := make([]bool, len(cues))
+	var changed, removed bool
+	for i, c := range cues {
+		text := fn(c)
+		if len(text) > 0 && equalLines(text, c.Lines) {
+			continue
+		}
+		texts[i], edits[i] = text, true
+		changed = true
+		removed = removed || len(text) == 0
+	}
+	if !changed {
+		return data, nil
+	}
+
+	if removed {
+		var n int
+		for i, s := range spans {
+			if edits[i] && len(texts[i]) == 0 {
+				continue
+			}
+			n++
+			if s.number < 0 {
+				continue
+			}
+			if _, err := strconv.Atoi(strings.TrimSpace(lines[s.number])); err == nil {
+				lines[s.number] = strconv.Itoa(n) + lineEnding(lines[s.number])
+			}
+		}
+	}
+
+	var edited []string
+	var next int
+	for i, s := range spans {
+		if !edits[i] {
+			continue
+		}
+		edited = append(edited, lines[next:s.start]...)
+		next = s.end
+
+		if len(texts[i]) == 0 {
+			// Cues separated by empty lines are removed along with the
+			// empty lines which follow them
+			if len(edited) == 0 || strings.TrimSpace(edited[len(edited)-1]) == "" {
+				for next < len(lines)-1 && strings.TrimSpace(lines[next]) == "" {
+					next++
+				}
+			}
+			continue
+		}
+
+		eol := lineEnding(lines[s.start])
+		for _, l := range e.replace(lines, s, texts[i]) {
+			edited = append(edited, strings.TrimSuffix(l, eol)+eol)
+		}
+	}
+	edited = append(edited, lines[next:]...)
+
+	result := []byte(strings.Join(edited, "\n"))
+	if bytes.HasPrefix(data, bom) {
+		result = append(append([]byte(nil), bom...), result...)
+	}
+	return result, nil
+}
+
+// blockSpans returns the first and last line, exclusive, of every group of
+// lines separated by empty lines
+func blockSpans(lines []string) [][2]int {
+	var all [][2]int
+	start := -1
+	for i, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			if start >= 0 {
+				all = append(all, [2]int{start, i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		all = append(all, [2]int{start, len(lines)})
+	}
+	return all
+}
+
+// timedSpans returns the spans of blocks of lines where the first line which
+// matches the timing of a cue is followed by the text of the cue
+func timedSpans(lines []string, blocks [][2]int, match func(string) bool) []span {
+	var spans []span
+	for _, b := range blocks {
+		for i := b[0]; i < b[1]; i++ {
+			if !match(lines[i]) {
+				continue
+			}
+			s := span{start: b[0], text: i + 1, end: b[1], number: -1}
+			if i > b[0] {
+				s.number = i - 1
+			}
+			spans = append(spans, s)
+			break
+		}
+	}
+	return spans
+}
+
+// lineEnding returns the carriage return which ends the line, if any
+func lineEnding(line string) string {
+	if strings.HasSuffix(line, "\r") {
+		return "\r"
+	}
+	return ""
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package subformat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEdit(t *testing.T) {
+	tests := []struct {
+		file string
+		cues []Cue
+	}{
+		{"sample.srt", taggedCues},
+		{"sample.vtt", taggedCues},
+		{"sample.ass", plainCues},
+		{"sample.ssa", plainCues},
+		{"sample.microdvd.sub", frameCues},
+		{"sample.subviewer.sub", plainCues},
+	}
+
+	for _, test := range tests {
+		data := readTestFile(t, test.file)
+
+		edited, err := Edit(data, func(c Cue) []string {
+			if c.Start < 2*time.Second {
+				return nil
+			}
+			return []string{"Hi", "there"}
+		})
+		require.NoError(t, err, test.file)
+
+		cues, err := Parse(edited)
+		require.NoError(t, err, test.file)
+
+		expected := test.cues[1]
+		expected.Lines = []string{"Hi", "there"}
+		assert.Equal(t, []Cue{expected}, cues, test.file)
+	}
+}
+
+func TestEditUnchanged(t *testing.T) {
+	data := readTestFile(t, "sample.ass")
+
+	edited, err := Edit(data, func(c Cue) []string {
+		return c.Lines
+	})
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(edited))
+}
+
+func TestEditKeepsContent(t *testing.T) {
+	data := readTestFile(t, "sample.ass")
+
+	edited, err := Edit(data, func(c Cue) []string {
+		if c.Start > 2*time.Second {
+			return c.Lines
+		}
+		return []string{"Hi"}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, strings.Replace(string(data), ",,Hello there.", ",,Hi", 1), string(edited))
+
+	srt := "\xef\xbb\xbf1\r\n00:00:01,000 --> 00:00:02,000\r\n<b>Ad</b>\r\n\r\n" +
+		"2\r\n00:00:03,000 --> 00:00:04,000\r\n<i>Gr\xfc\xdf</i>\r\ndich\r\n\r\n" +
+		"3\r\n00:00:05,000 --> 00:00:06,000\r\n[Musik]\r\n"
+
+	edited, err = Edit([]byte(srt), func(c Cue) []string {
+		switch c.Lines[0] {
+		case "<b>Ad</b>":
+			return nil
+		case "[Musik]":
+			return []string{"Musik"}
+		}
+		return c.Lines
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "\xef\xbb\xbf1\r\n00:00:03,000 --> 00:00:04,000\r\n<i>Gr\xfc\xdf</i>\r\ndich\r\n\r\n"+
+		"2\r\n00:00:05,000 --> 00:00:06,000\r\nMusik\r\n", string(edited))
+}
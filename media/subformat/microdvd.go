@@ -0,0 +1,155 @@
+package subformat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// microDVDRate is the frame rate used when the subtitle does not specify one
+const microDVDRate = 23.976
+
+var microDVDLine = regexp.MustCompile(`^\{(\d+)\}\{(\d*)\}(.*)$`)
+
+var microDVDStyle = regexp.MustCompile(`\{[^}]*\}`)
+
+// microDVD is the MicroDVD format, where cues are timed in frames. The frame
+// rate may be given by the first line
+type microDVD struct{}
+
+func (microDVD) Name() string {
+	return "microdvd"
+}
+
+func (microDVD) Ext() string {
+	return ".sub"
+}
+
+func (microDVD) Match(data []byte) bool {
+	return microDVDLine.MatchString(firstLine(data))
+}
+
+func (microDVD) Decode(r io.Reader) ([]Cue, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := microDVDRate
+
+	var cues []Cue
+	for i, l := range lines {
+		m := microDVDLine.FindStringSubmatch(strings.TrimSpace(l))
+		if m == nil {
+			continue
+		}
+		start, _ := strconv.Atoi(m[1])
+		end, err := strconv.Atoi(m[2])
+		if err != nil {
+			end = start
+		}
+
+		if i == 0 && start <= 1 && end <= 1 {
+			if fps, err := strconv.ParseFloat(m[3], 64); err == nil && fps > 0 {
+				rate = fps
+				continue
+			}
+		}
+
+		text := microDVDStyle.ReplaceAllString(m[3], "")
+		cues = append(cues, Cue{
+			Start: frameTime(start, rate),
+			End:   frameTime(end, rate),
+			Lines: strings.Split(text, "|"),
+		})
+	}
+	return cues, nil
+}
+
+func (microDVD) retime(lines []string, t Timing) error {
+	rate := microDVDRate
+	frame := func(n int) int {
+		return timeFrame(clamp(t(frameTime(n, rate))), rate)
+	}
+
+	for i, l := range lines {
+		m := microDVDLine.FindStringSubmatch(strings.TrimSpace(l))
+		if m == nil {
+			continue
+		}
+		start, _ := strconv.Atoi(m[1])
+		end, err := strconv.Atoi(m[2])
+		if err != nil {
+			end = start
+		}
+
+		if i == 0 && start <= 1 && end <= 1 {
+			if fps, err := strconv.ParseFloat(m[3], 64); err == nil && fps > 0 {
+				rate = fps
+				continue
+			}
+		}
+
+		retimed := fmt.Sprintf("{%d}{}%s", frame(start), m[3])
+		if m[2] != "" {
+			retimed = fmt.Sprintf("{%d}{%d}%s", frame(start), frame(end), m[3])
+		}
+		if strings.HasSuffix(l, "\r") {
+			retimed += "\r"
+		}
+		lines[i] = retimed
+	}
+	return nil
+}
+
+func (microDVD) spans(lines []string) []span {
+	var spans []span
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		m := microDVDLine.FindStringSubmatchIndex(trimmed)
+		if m == nil {
+			continue
+		}
+		start, _ := strconv.Atoi(trimmed[m[2]:m[3]])
+		end, err := strconv.Atoi(trimmed[m[4]:m[5]])
+		if err != nil {
+			end = start
+		}
+		if i == 0 && start <= 1 && end <= 1 {
+			if fps, err := strconv.ParseFloat(trimmed[m[6]:m[7]], 64); err == nil && fps > 0 {
+				continue
+			}
+		}
+		offset := strings.Index(l, trimmed) + m[6]
+		spans = append(spans, span{start: i, text: i, end: i + 1, offset: offset, number: -1})
+	}
+	return spans
+}
+
+func (microDVD) replace(lines []string, s span, text []string) []string {
+	return []string{lines[s.start][:s.offset] + strings.Join(text, "|")}
+}
+
+func (microDVD) Encode(w io.Writer, cues []Cue) error {
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "{1}{1}%.3f\n", microDVDRate)
+	for _, c := range cues {
+		fmt.Fprintf(buf, "{%d}{%d}%s\n",
+			timeFrame(c.Start, microDVDRate),
+			timeFrame(c.End, microDVDRate),
+			strings.Join(c.Lines, "|"))
+	}
+	return buf.Flush()
+}
+
+func frameTime(frame int, rate float64) time.Duration {
+	return time.Duration(float64(frame) / rate * float64(time.Second)).Round(time.Millisecond)
+}
+
+func timeFrame(d time.Duration, rate float64) int {
+	return int(d.Seconds()*rate + 0.5)
+}
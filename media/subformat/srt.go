@@ -0,0 +1,79 @@
+package subformat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var srtTiming = regexp.MustCompile(`^\s*(\d+:\d{1,2}:\d{1,2}[,.]\d+)\s*-->\s*(\d+:\d{1,2}:\d{1,2}[,.]\d+)`)
+
+var srtMatch = regexp.MustCompile(`(?m)^\s*\d+:\d{1,2}:\d{1,2},\d+\s*-->`)
+
+// srt is the SubRip format
+type srt struct{}
+
+func (srt) Name() string {
+	return "srt"
+}
+
+func (srt) Ext() string {
+	return ".srt"
+}
+
+func (srt) Match(data []byte) bool {
+	return srtMatch.Match(data)
+}
+
+func (srt) Decode(r io.Reader) ([]Cue, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var cues []Cue
+	for _, b := range blocks(lines) {
+		for i, l := range b {
+			m := srtTiming.FindStringSubmatch(l)
+			if m == nil {
+				continue
+			}
+			start, err := parseClock(m[1])
+			if err != nil {
+				return nil, err
+			}
+			end, err := parseClock(m[2])
+			if err != nil {
+				return nil, err
+			}
+			cues = append(cues, Cue{start, end, b[i+1:]})
+			break
+		}
+	}
+	return cues, nil
+}
+
+func (srt) retime(lines []string, t Timing) error {
+	return retimeMatches(lines, srtTiming, t)
+}
+
+func (srt) spans(lines []string) []span {
+	return timedSpans(lines, blockSpans(lines), srtTiming.MatchString)
+}
+
+func (srt) replace(lines []string, s span, text []string) []string {
+	return append(append([]string(nil), lines[s.start:s.text]...), text...)
+}
+
+func (srt) Encode(w io.Writer, cues []Cue) error {
+	buf := bufio.NewWriter(w)
+	for i, c := range cues {
+		fmt.Fprintf(buf, "%d\n%s --> %s\n%s\n\n", i+1,
+			formatClock(c.Start, 2, ",", 3),
+			formatClock(c.End, 2, ",", 3),
+			strings.Join(c.Lines, "\n"))
+	}
+	return buf.Flush()
+}
@@ -0,0 +1,102 @@
+package subformat
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxCPS is the reading speed, in characters per second, above which a cue is
+// shown too briefly to be read
+const MaxCPS = 25
+
+// Stats describes the timing and text of the cues of a subtitle
+type Stats struct {
+	// Cues is the number of cues
+	Cues int `json:"cues"`
+	// Empty is the number of cues without text
+	Empty int `json:"empty,omitempty"`
+	// Invalid is the number of cues with negative timing, or which end
+	// before they start
+	Invalid int `json:"invalid,omitempty"`
+	// Overlaps is the number of cues starting before an earlier cue ends
+	Overlaps int `json:"overlaps,omitempty"`
+	// Fast is the number of cues read faster than MaxCPS
+	Fast int `json:"fast,omitempty"`
+	// CPS is the average reading speed in characters per second
+	CPS float64 `json:"cps"`
+	// Last is the end of the last cue
+	Last time.Duration `json:"last"`
+}
+
+// Analyze returns the stats of the cues. Invalid cues are counted, but are
+// otherwise left out of the stats. Cues are analyzed in the order they are
+// shown, as cues may be in any order (e.g. signs in ASS subtitles)
+func Analyze(cues []Cue) Stats {
+	s := Stats{Cues: len(cues)}
+
+	cues = append([]Cue(nil), cues...)
+	sort.SliceStable(cues, func(i, j int) bool {
+		return cues[i].Start < cues[j].Start
+	})
+
+	var chars int
+	var shown, end time.Duration
+	for i, c := range cues {
+		n := textLength(c.Lines)
+		if n == 0 {
+			s.Empty++
+		}
+		if c.Start < 0 || c.End <= c.Start {
+			s.Invalid++
+			continue
+		}
+		if i > 0 && c.Start < end {
+			s.Overlaps++
+		}
+		if c.End > end {
+			end = c.End
+		}
+
+		d := c.End - c.Start
+		if float64(n) > MaxCPS*d.Seconds() {
+			s.Fast++
+		}
+		chars += n
+		shown += d
+	}
+
+	s.Last = end
+	if shown > 0 {
+		s.CPS = math.Floor(float64(chars)/shown.Seconds()*10+0.5) / 10
+	}
+	return s
+}
+
+// textLength returns the number of characters of the lines, without markup
+// (e.g. <i> and {\an8}) and surrounding spaces
+func textLength(lines []string) int {
+	var n int
+	for _, l := range lines {
+		var text []rune
+		var end rune
+		for _, r := range l {
+			switch {
+			case end != 0:
+				if r == end {
+					end = 0
+				}
+			case r == '<':
+				end = '>'
+			case r == '{':
+				end = '}'
+			default:
+				text = append(text, r)
+			}
+		}
+		n += utf8.RuneCountInString(strings.TrimSpace(string(text)))
+	}
+	return n
+}
@@ -0,0 +1,42 @@
+package subformat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	cues := []Cue{
+		{Start: time.Second, End: 3 * time.Second, Lines: []string{"<i>Hello there</i>"}},
+		{Start: 2 * time.Second, End: 4 * time.Second, Lines: []string{"{\\an8}How are you?"}},
+		{Start: 5 * time.Second, End: 5500 * time.Millisecond, Lines: []string{"This line is far too long to be read in half a second"}},
+		{Start: 7 * time.Second, End: 6 * time.Second, Lines: []string{"Backwards"}},
+		{Start: 8 * time.Second, End: 9 * time.Second, Lines: []string{" "}},
+	}
+
+	s := Analyze(cues)
+	assert.Equal(t, 5, s.Cues)
+	assert.Equal(t, 1, s.Empty)
+	assert.Equal(t, 1, s.Invalid)
+	assert.Equal(t, 1, s.Overlaps)
+	assert.Equal(t, 1, s.Fast)
+	assert.Equal(t, 9*time.Second, s.Last)
+	assert.Equal(t, 13.8, s.CPS)
+
+	assert.Equal(t, Stats{}, Analyze(nil))
+}
+
+func TestAnalyzeUnsorted(t *testing.T) {
+	cues := []Cue{
+		{Start: 5 * time.Second, End: 6 * time.Second, Lines: []string{"Sign"}},
+		{Start: time.Second, End: 3 * time.Second, Lines: []string{"Hello there"}},
+		{Start: 3 * time.Second, End: 4 * time.Second, Lines: []string{"How are you?"}},
+	}
+
+	s := Analyze(cues)
+	assert.Equal(t, 0, s.Overlaps)
+	assert.Equal(t, 6*time.Second, s.Last)
+	assert.Equal(t, "Sign", cues[0].Lines[0])
+}
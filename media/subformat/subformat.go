@@ -0,0 +1,301 @@
+package subformat
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Cue is text which is shown on screen from the start until the end time
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Lines []string
+}
+
+// Format reads and writes subtitles of a file format as a list of cues
+type Format interface {
+	Name() string
+	Ext() string
+	Match([]byte) bool
+	Decode(io.Reader) ([]Cue, error)
+	Encode(io.Writer, []Cue) error
+}
+
+// formats contains the supported formats in the order they are detected.
+// The first format with an extension is the default for the extension
+var formats = []Format{
+	vtt{},
+	ass{ssa: false},
+	ass{ssa: true},
+	microDVD{},
+	subViewer{},
+	srt{},
+}
+
+// Default is the format which content is assumed to be in when no other
+// format has been detected
+var Default Format = srt{}
+
+// ErrUnknownFormat is returned when the format of a subtitle is unknown
+var ErrUnknownFormat = errors.New("unknown subtitle format")
+
+// Formats returns every supported format
+func Formats() []Format {
+	return append([]Format(nil), formats...)
+}
+
+// ByName returns the format with the name (e.g. srt, ass, vtt)
+func ByName(name string) (Format, error) {
+	for _, f := range formats {
+		if strings.EqualFold(f.Name(), name) {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown subtitle format %v", name)
+}
+
+// Extensions returns the file extensions of every supported format
+func Extensions() []string {
+	var exts []string
+	seen := make(map[string]bool)
+	for _, f := range formats {
+		if !seen[f.Ext()] {
+			seen[f.Ext()] = true
+			exts = append(exts, f.Ext())
+		}
+	}
+	return exts
+}
+
+// IsSubtitle returns true if the extension of the filename is the extension
+// of a supported format
+func IsSubtitle(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range Extensions() {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect returns the format of the subtitle by looking at its content
+func Detect(data []byte) (Format, error) {
+	data = trimBOM(data)
+	for _, f := range formats {
+		if f.Match(data) {
+			return f, nil
+		}
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Parse decodes the subtitle in any supported format into cues
+func Parse(data []byte) ([]Cue, error) {
+	f, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+	return f.Decode(bytes.NewReader(data))
+}
+
+// Convert converts the subtitle in any supported format into the format.
+// Subtitles which are already in the format are returned untouched
+func Convert(data []byte, to Format) ([]byte, error) {
+	from, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+	if from.Name() == to.Name() {
+		return data, nil
+	}
+	cues, err := from.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := to.Encode(&buf, cues); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var bom = []byte("\xef\xbb\xbf")
+
+func trimBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, bom)
+}
+
+// readLines returns the lines of the reader without line endings and the
+// byte order mark
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, string(bom))
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// blocks splits the lines into groups separated by empty lines
+func blocks(lines []string) [][]string {
+	var all [][]string
+	var block []string
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			if len(block) > 0 {
+				all = append(all, block)
+				block = nil
+			}
+			continue
+		}
+		block = append(block, l)
+	}
+	if len(block) > 0 {
+		all = append(all, block)
+	}
+	return all
+}
+
+// parseClock parses a time of the form [hh:]mm:ss[.,]fff, where the number
+// of fractional digits may vary
+func parseClock(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	var frac string
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 {
+		s, frac = s[:i], s[i+1:]
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time %v", s)
+	}
+
+	var d time.Duration
+	for _, p := range parts {
+		n, err := parseInt(p)
+		if err != nil {
+			return 0, err
+		}
+		d = d*60 + time.Duration(n)
+	}
+	d *= time.Second
+
+	if frac != "" {
+		n, err := parseInt(frac)
+		if err != nil {
+			return 0, err
+		}
+		unit := time.Second
+		for range frac {
+			unit /= 10
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
+
+// formatClock formats the time as hh:mm:ss followed by the separator and the
+// given number of fractional digits, if any
+func formatClock(d time.Duration, hourDigits int, sep string, fracDigits int) string {
+	if d < 0 {
+		d = 0
+	}
+	unit := time.Second
+	for i := 0; i < fracDigits; i++ {
+		unit /= 10
+	}
+	d = d.Round(unit)
+
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	f := (d % time.Second) / unit
+
+	if fracDigits == 0 {
+		return fmt.Sprintf("%0*d:%02d:%02d", hourDigits, h, m, s)
+	}
+	return fmt.Sprintf("%0*d:%02d:%02d%s%0*d", hourDigits, h, m, s, sep, fracDigits, f)
+}
+
+// retimeClock applies the timing to a time of the form [hh:]mm:ss[.,]fff,
+// keeping the number of digits and the separator of the time
+func retimeClock(s string, t Timing) (string, error) {
+	d, err := parseClock(s)
+	if err != nil {
+		return "", err
+	}
+	d = clamp(t(d))
+
+	clock, sep, frac := s, "", 0
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 {
+		clock, sep, frac = s[:i], s[i:i+1], len(s)-i-1
+	}
+
+	parts := strings.Split(clock, ":")
+	if len(parts) == 3 {
+		return formatClock(d, len(parts[0]), sep, frac), nil
+	}
+	if d >= time.Hour {
+		return formatClock(d, 2, sep, frac), nil
+	}
+	formatted := formatClock(d, 1, sep, frac)
+	return formatted[strings.Index(formatted, ":")+1:], nil
+}
+
+// retimeMatches applies the timing to the times captured by the first two
+// groups of the regular expression in every line which it matches
+func retimeMatches(lines []string, re *regexp.Regexp, t Timing) error {
+	for i, l := range lines {
+		m := re.FindStringSubmatchIndex(l)
+		if m == nil {
+			continue
+		}
+		start, err := retimeClock(l[m[2]:m[3]], t)
+		if err != nil {
+			return err
+		}
+		end, err := retimeClock(l[m[4]:m[5]], t)
+		if err != nil {
+			return err
+		}
+		lines[i] = l[:m[2]] + start + l[m[3]:m[4]] + end + l[m[5]:]
+	}
+	return nil
+}
+
+func parseInt(s string) (int, error) {
+	if s == "" {
+		return 0, errors.New("invalid number")
+	}
+	var n int
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid number %v", s)
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, nil
+}
+
+// firstLine returns the first non-empty line of the data
+func firstLine(data []byte) string {
+	for _, l := range strings.Split(string(data), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			return l
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package subformat
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var plainCues = []Cue{
+	{time.Second, 3500 * time.Millisecond, []string{"Hello there."}},
+	{4250 * time.Millisecond, 6 * time.Second, []string{"General Kenobi!", "You are a bold one."}},
+}
+
+var taggedCues = []Cue{
+	{time.Second, 3500 * time.Millisecond, []string{"Hello there."}},
+	{4250 * time.Millisecond, 6 * time.Second, []string{"<i>General Kenobi!</i>", "You are a bold one."}},
+}
+
+var frameCues = []Cue{
+	{time.Second, 3520 * time.Millisecond, []string{"Hello there."}},
+	{4240 * time.Millisecond, 6 * time.Second, []string{"General Kenobi!", "You are a bold one."}},
+}
+
+func readTestFile(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("test", name))
+	require.NoError(t, err)
+	return data
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		file   string
+		format string
+		cues   []Cue
+	}{
+		{"sample.srt", "srt", taggedCues},
+		{"sample.vtt", "vtt", taggedCues},
+		{"sample.ass", "ass", plainCues},
+		{"sample.ssa", "ssa", plainCues},
+		{"sample.microdvd.sub", "microdvd", frameCues},
+		{"sample.subviewer.sub", "subviewer", plainCues},
+	}
+
+	for _, test := range tests {
+		data := readTestFile(t, test.file)
+
+		f, err := Detect(data)
+		require.NoError(t, err, test.file)
+		assert.Equal(t, test.format, f.Name(), test.file)
+
+		cues, err := Parse(data)
+		require.NoError(t, err, test.file)
+		assert.Equal(t, test.cues, cues, test.file)
+	}
+}
+
+func TestDetectUnknown(t *testing.T) {
+	_, err := Detect([]byte("this is not a subtitle"))
+	assert.Equal(t, ErrUnknownFormat, err)
+
+	_, err = Parse([]byte{})
+	assert.Equal(t, ErrUnknownFormat, err)
+}
+
+func TestDetectBOM(t *testing.T) {
+	data := append([]byte("\xef\xbb\xbf"), readTestFile(t, "sample.srt")...)
+
+	cues, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, taggedCues, cues)
+}
+
+func TestEncode(t *testing.T) {
+	for _, f := range Formats() {
+		var buf bytes.Buffer
+		require.NoError(t, f.Encode(&buf, plainCues), f.Name())
+
+		detected, err := Detect(buf.Bytes())
+		require.NoError(t, err, f.Name())
+		assert.Equal(t, f.Name(), detected.Name())
+
+		cues, err := f.Decode(&buf)
+		require.NoError(t, err, f.Name())
+		require.Len(t, cues, len(plainCues), f.Name())
+
+		for i, c := range cues {
+			assert.WithinDuration(t, time.Time{}.Add(plainCues[i].Start), time.Time{}.Add(c.Start), 50*time.Millisecond, f.Name())
+			assert.WithinDuration(t, time.Time{}.Add(plainCues[i].End), time.Time{}.Add(c.End), 50*time.Millisecond, f.Name())
+			assert.Equal(t, plainCues[i].Lines, c.Lines, f.Name())
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	data := readTestFile(t, "sample.ass")
+
+	ass, err := ByName("ASS")
+	require.NoError(t, err)
+	same, err := Convert(data, ass)
+	require.NoError(t, err)
+	assert.Equal(t, data, same)
+
+	vtt, err := ByName("vtt")
+	require.NoError(t, err)
+	converted, err := Convert(data, vtt)
+	require.NoError(t, err)
+
+	assert.Equal(t, "WEBVTT\n\n"+
+		"00:00:01.000 --> 00:00:03.500\nHello there.\n\n"+
+		"00:00:04.250 --> 00:00:06.000\nGeneral Kenobi!\nYou are a bold one.\n\n", string(converted))
+
+	_, err = ByName("docx")
+	assert.Error(t, err)
+}
+
+func TestIsSubtitle(t *testing.T) {
+	for _, name := range []string{"a.srt", "a.en.ass", "a.ssa", "a.vtt", "a.sub", "A.SRT"} {
+		assert.True(t, IsSubtitle(name), name)
+	}
+	for _, name := range []string{"a.mkv", "a.txt", "srt"} {
+		assert.False(t, IsSubtitle(name), name)
+	}
+	assert.Equal(t, []string{".vtt", ".ass", ".ssa", ".sub", ".srt"}, Extensions())
+}
@@ -0,0 +1,104 @@
+package subformat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var subViewerTiming = regexp.MustCompile(`^\s*(\d{1,2}:\d{2}:\d{2}\.\d+)\s*,\s*(\d{1,2}:\d{2}:\d{2}\.\d+)\s*$`)
+
+var subViewerMatch = regexp.MustCompile(`(?m)^\s*\d{1,2}:\d{2}:\d{2}\.\d+\s*,\s*\d{1,2}:\d{2}:\d{2}\.\d+\s*$`)
+
+const subViewerHeader = `[INFORMATION]
+[TITLE]
+[AUTHOR]
+[SOURCE]
+[PRG]
+[FILEPATH]
+[DELAY]0
+[CD TRACK]0
+[COMMENT]
+[END INFORMATION]
+[SUBTITLE]
+[COLF]&HFFFFFF,[STYLE]no,[SIZE]18,[FONT]Arial
+
+`
+
+// subViewer is the SubViewer 2.0 format
+type subViewer struct{}
+
+func (subViewer) Name() string {
+	return "subviewer"
+}
+
+func (subViewer) Ext() string {
+	return ".sub"
+}
+
+func (subViewer) Match(data []byte) bool {
+	return bytes.Contains(data, []byte("[INFORMATION]")) || subViewerMatch.Match(data)
+}
+
+func (subViewer) Decode(r io.Reader) ([]Cue, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var cues []Cue
+	for _, b := range blocks(lines) {
+		for i, l := range b {
+			m := subViewerTiming.FindStringSubmatch(l)
+			if m == nil {
+				continue
+			}
+			start, err := parseClock(m[1])
+			if err != nil {
+				return nil, err
+			}
+			end, err := parseClock(m[2])
+			if err != nil {
+				return nil, err
+			}
+			var text []string
+			for _, t := range b[i+1:] {
+				text = append(text, strings.Split(t, "[br]")...)
+			}
+			cues = append(cues, Cue{start, end, text})
+			break
+		}
+	}
+	return cues, nil
+}
+
+func (subViewer) retime(lines []string, t Timing) error {
+	return retimeMatches(lines, subViewerTiming, t)
+}
+
+func (subViewer) spans(lines []string) []span {
+	spans := timedSpans(lines, blockSpans(lines), subViewerTiming.MatchString)
+	for i := range spans {
+		spans[i].number = -1
+	}
+	return spans
+}
+
+func (subViewer) replace(lines []string, s span, text []string) []string {
+	return append(append([]string(nil), lines[s.start:s.text]...), strings.Join(text, "[br]"))
+}
+
+func (subViewer) Encode(w io.Writer, cues []Cue) error {
+	buf := bufio.NewWriter(w)
+	fmt.Fprint(buf, subViewerHeader)
+	for _, c := range cues {
+		fmt.Fprintf(buf, "%s,%s\n%s\n\n",
+			formatClock(c.Start, 2, ".", 2),
+			formatClock(c.End, 2, ".", 2),
+			strings.Join(c.Lines, "[br]"))
+	}
+	return buf.Flush()
+}
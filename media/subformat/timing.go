@@ -0,0 +1,188 @@
+package subformat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timing maps the time of a cue to a new time
+type Timing func(time.Duration) time.Duration
+
+// Apply returns the cues with new start and end times. Times before the
+// beginning of the subtitle are moved to the beginning
+func (t Timing) Apply(cues []Cue) []Cue {
+	timed := make([]Cue, len(cues))
+	for i, c := range cues {
+		timed[i] = Cue{
+			Start: clamp(t(c.Start)),
+			End:   clamp(t(c.End)),
+			Lines: c.Lines,
+		}
+	}
+	return timed
+}
+
+// retimer is implemented by formats which can change the times of a subtitle
+// in place, keeping everything else of the subtitle untouched
+type retimer interface {
+	retime(lines []string, t Timing) error
+}
+
+// Retime applies the timing to every cue of the subtitle in any supported
+// format. Only the times are rewritten, such that headers, styles, formatting
+// and any text which is not understood are kept as they are
+func Retime(data []byte, t Timing) ([]byte, error) {
+	f, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok := f.(retimer)
+	if !ok {
+		cues, err := f.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := f.Encode(&buf, t.Apply(cues)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	hasBOM := bytes.HasPrefix(data, bom)
+	lines := strings.Split(string(trimBOM(data)), "\n")
+	if err := r.retime(lines, t); err != nil {
+		return nil, err
+	}
+
+	retimed := []byte(strings.Join(lines, "\n"))
+	if hasBOM {
+		retimed = append(append([]byte(nil), bom...), retimed...)
+	}
+	return retimed, nil
+}
+
+// Chain returns the timing which applies each of the timings in order
+func Chain(timings ...Timing) Timing {
+	return func(d time.Duration) time.Duration {
+		for _, t := range timings {
+			d = t(d)
+		}
+		return d
+	}
+}
+
+// Shift returns the timing which moves every cue by the offset
+func Shift(offset time.Duration) Timing {
+	return func(d time.Duration) time.Duration {
+		return d + offset
+	}
+}
+
+// Scale returns the timing which linearly rescales the cues, such that the
+// time a1 becomes b1 and the time a2 becomes b2
+func Scale(a1, b1, a2, b2 time.Duration) (Timing, error) {
+	if a1 == a2 {
+		return nil, errors.New("anchor points must be at different times")
+	}
+	ratio := float64(b2-b1) / float64(a2-a1)
+	return func(d time.Duration) time.Duration {
+		return b1 + time.Duration(float64(d-a1)*ratio)
+	}, nil
+}
+
+// Framerate returns the timing which converts cues timed for a video with
+// one frame rate to a video with another frame rate (e.g. 25 to 23.976)
+func Framerate(from, to float64) (Timing, error) {
+	if from <= 0 || to <= 0 {
+		return nil, errors.New("frame rates must be positive")
+	}
+	ratio := from / to
+	return func(d time.Duration) time.Duration {
+		return time.Duration(float64(d) * ratio)
+	}, nil
+}
+
+// ParseTime parses a time of the form [hh:]mm:ss[.fff]
+func ParseTime(s string) (time.Duration, error) {
+	return parseClock(s)
+}
+
+// ParseTiming returns the timing which converts between frame rates, rescales
+// between two anchor points and shifts by an offset, in that order. The offset
+// is a duration (e.g. -1.5s), each anchor maps a time in the subtitle to a
+// time in the video (e.g. 01:00:10=01:00:12.5) and the frame rates are
+// separated by a colon (e.g. 25:23.976). Empty values are ignored
+func ParseTiming(offset string, anchors []string, fps string) (Timing, error) {
+	var timings []Timing
+
+	if fps != "" {
+		parts := strings.Split(fps, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid frame rates %v", fps)
+		}
+		from, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		to, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		t, err := Framerate(from, to)
+		if err != nil {
+			return nil, err
+		}
+		timings = append(timings, t)
+	}
+
+	if len(anchors) > 0 {
+		if len(anchors) != 2 {
+			return nil, errors.New("exactly two anchors must be given")
+		}
+		var times []time.Duration
+		for _, a := range anchors {
+			parts := strings.Split(a, "=")
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid anchor %v", a)
+			}
+			for _, p := range parts {
+				d, err := ParseTime(p)
+				if err != nil {
+					return nil, err
+				}
+				times = append(times, d)
+			}
+		}
+		t, err := Scale(times[0], times[1], times[2], times[3])
+		if err != nil {
+			return nil, err
+		}
+		timings = append(timings, t)
+	}
+
+	if offset != "" {
+		d, err := time.ParseDuration(offset)
+		if err != nil {
+			return nil, err
+		}
+		timings = append(timings, Shift(d))
+	}
+
+	if len(timings) == 0 {
+		return nil, errors.New("missing offset, anchors or frame rates")
+	}
+	return Chain(timings...), nil
+}
+
+func clamp(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d.Round(time.Millisecond)
+}
@@ -0,0 +1,145 @@
+package subformat
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShift(t *testing.T) {
+	cues := Shift(-1500 * time.Millisecond).Apply(plainCues)
+
+	assert.Equal(t, time.Duration(0), cues[0].Start)
+	assert.Equal(t, 2*time.Second, cues[0].End)
+	assert.Equal(t, 2750*time.Millisecond, cues[1].Start)
+	assert.Equal(t, plainCues[1].Lines, cues[1].Lines)
+
+	// The original cues are untouched
+	assert.Equal(t, time.Second, plainCues[0].Start)
+}
+
+func TestScale(t *testing.T) {
+	scale, err := Scale(time.Second, 2*time.Second, 6*time.Second, 12*time.Second)
+	require.NoError(t, err)
+
+	cues := scale.Apply(plainCues)
+	assert.Equal(t, 2*time.Second, cues[0].Start)
+	assert.Equal(t, 7*time.Second, cues[0].End)
+	assert.Equal(t, 8500*time.Millisecond, cues[1].Start)
+	assert.Equal(t, 12*time.Second, cues[1].End)
+
+	_, err = Scale(time.Second, 2*time.Second, time.Second, 3*time.Second)
+	assert.Error(t, err)
+}
+
+func TestFramerate(t *testing.T) {
+	rate, err := Framerate(25, 23.976)
+	require.NoError(t, err)
+
+	cues := rate.Apply([]Cue{{Start: time.Hour, End: time.Hour + time.Second}})
+	assert.Equal(t, 3753754*time.Millisecond, cues[0].Start)
+
+	_, err = Framerate(0, 25)
+	assert.Error(t, err)
+}
+
+func TestChain(t *testing.T) {
+	rate, err := Framerate(24, 25)
+	require.NoError(t, err)
+
+	cues := Chain(rate, Shift(time.Second)).Apply(plainCues)
+	assert.Equal(t, 1960*time.Millisecond, cues[0].Start)
+}
+
+func TestParseTime(t *testing.T) {
+	d, err := ParseTime("01:02:03.5")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour+2*time.Minute+3500*time.Millisecond, d)
+
+	d, err = ParseTime("02:03,250")
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute+3250*time.Millisecond, d)
+
+	_, err = ParseTime("soon")
+	assert.Error(t, err)
+}
+
+func TestParseTiming(t *testing.T) {
+	timing, err := ParseTiming("-1s", []string{"00:01=00:02", "00:06=00:12"}, "25:25")
+	require.NoError(t, err)
+
+	cues := timing.Apply(plainCues)
+	assert.Equal(t, time.Second, cues[0].Start)
+	assert.Equal(t, 11*time.Second, cues[1].End)
+
+	for _, invalid := range []struct {
+		offset  string
+		anchors []string
+		fps     string
+	}{
+		{"", nil, ""},
+		{"soon", nil, ""},
+		{"", []string{"00:01=00:02"}, ""},
+		{"", []string{"00:01", "00:06=00:12"}, ""},
+		{"", nil, "25"},
+		{"", nil, "25:fast"},
+	} {
+		_, err := ParseTiming(invalid.offset, invalid.anchors, invalid.fps)
+		assert.Error(t, err, "%v", invalid)
+	}
+}
+
+func TestRetime(t *testing.T) {
+	files := []string{
+		"sample.srt",
+		"sample.vtt",
+		"sample.ass",
+		"sample.ssa",
+		"sample.microdvd.sub",
+		"sample.subviewer.sub",
+	}
+
+	shift := Shift(time.Second)
+
+	for _, file := range files {
+		data := readTestFile(t, file)
+
+		retimed, err := Retime(data, shift)
+		require.NoError(t, err, file)
+
+		cues, err := Parse(data)
+		require.NoError(t, err, file)
+
+		timed, err := Parse(retimed)
+		require.NoError(t, err, file)
+		assert.Equal(t, shift.Apply(cues), timed, file)
+		assert.Equal(t, bytes.Count(data, []byte("\n")), bytes.Count(retimed, []byte("\n")), file)
+	}
+}
+
+func TestRetimeKeepsContent(t *testing.T) {
+	shift := Shift(time.Second)
+
+	ass, err := Retime(readTestFile(t, "sample.ass"), shift)
+	require.NoError(t, err)
+	assert.Contains(t, string(ass), "Title: Sample\n")
+	assert.Contains(t, string(ass), "Style: Default,Arial,20,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,2,10,10,10,1\n")
+	assert.Contains(t, string(ass), "Dialogue: 0,0:00:05.25,0:00:07.00,Default,,0,0,0,,{\\i1}General Kenobi!{\\i0}\\NYou are a bold one.\n")
+
+	vtt, err := Retime(readTestFile(t, "sample.vtt"), shift)
+	require.NoError(t, err)
+	assert.Contains(t, string(vtt), "NOTE This is a comment\n\n1\n00:02.000 --> 00:04.500 align:middle\n")
+	assert.Contains(t, string(vtt), "00:00:05.250 --> 00:00:07.000\n<i>General Kenobi!</i>\n")
+
+	srt := "\xef\xbb\xbf1\r\n00:59:59,500 --> 01:00:00,000\r\nGr\xfc\xdf dich.\r\n"
+	retimed, err := Retime([]byte(srt), shift)
+	require.NoError(t, err)
+	assert.Equal(t, "\xef\xbb\xbf1\r\n01:00:00,500 --> 01:00:01,000\r\nGr\xfc\xdf dich.\r\n", string(retimed))
+
+	sub, err := Retime(readTestFile(t, "sample.microdvd.sub"), shift)
+	require.NoError(t, err)
+	assert.Equal(t, "{1}{1}25.000\n{50}{113}Hello there.\n{131}{175}{y:i}General Kenobi!|You are a bold one.\n", string(sub))
+}
@@ -0,0 +1,96 @@
+package subformat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var vttTiming = regexp.MustCompile(`^\s*((?:\d+:)?\d{1,2}:\d{1,2}\.\d+)\s*-->\s*((?:\d+:)?\d{1,2}:\d{1,2}\.\d+)`)
+
+// vtt is the WebVTT format
+type vtt struct{}
+
+func (vtt) Name() string {
+	return "vtt"
+}
+
+func (vtt) Ext() string {
+	return ".vtt"
+}
+
+func (vtt) Match(data []byte) bool {
+	return strings.HasPrefix(firstLine(data), "WEBVTT")
+}
+
+func (vtt) Decode(r io.Reader) ([]Cue, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var cues []Cue
+	for _, b := range blocks(lines) {
+		switch strings.SplitN(b[0], " ", 2)[0] {
+		case "WEBVTT", "NOTE", "STYLE", "REGION":
+			continue
+		}
+		for i, l := range b {
+			m := vttTiming.FindStringSubmatch(l)
+			if m == nil {
+				continue
+			}
+			start, err := parseClock(m[1])
+			if err != nil {
+				return nil, err
+			}
+			end, err := parseClock(m[2])
+			if err != nil {
+				return nil, err
+			}
+			cues = append(cues, Cue{start, end, b[i+1:]})
+			break
+		}
+	}
+	return cues, nil
+}
+
+func (vtt) retime(lines []string, t Timing) error {
+	return retimeMatches(lines, vttTiming, t)
+}
+
+func (vtt) spans(lines []string) []span {
+	var blocks [][2]int
+	for _, b := range blockSpans(lines) {
+		switch strings.SplitN(strings.TrimRight(lines[b[0]], "\r"), " ", 2)[0] {
+		case "WEBVTT", "NOTE", "STYLE", "REGION":
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+
+	// Cue identifiers are kept, as they may be referenced by styles
+	spans := timedSpans(lines, blocks, vttTiming.MatchString)
+	for i := range spans {
+		spans[i].number = -1
+	}
+	return spans
+}
+
+func (vtt) replace(lines []string, s span, text []string) []string {
+	return append(append([]string(nil), lines[s.start:s.text]...), text...)
+}
+
+func (vtt) Encode(w io.Writer, cues []Cue) error {
+	buf := bufio.NewWriter(w)
+	fmt.Fprint(buf, "WEBVTT\n\n")
+	for _, c := range cues {
+		fmt.Fprintf(buf, "%s --> %s\n%s\n\n",
+			formatClock(c.Start, 2, ".", 3),
+			formatClock(c.End, 2, ".", 3),
+			strings.Join(c.Lines, "\n"))
+	}
+	return buf.Flush()
+}
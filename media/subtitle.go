@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/tympanix/supper/media/parse"
@@ -103,7 +102,7 @@ func (l *Subtitle) ForMedia() types.Media {
 
 // NewLocalSubtitle returns a new local subtitle
 func NewLocalSubtitle(path string) (types.LocalSubtitle, error) {
-	if filepath.Ext(path) != ".srt" {
+	if !fileIsSubtitle(path) {
 		return nil, errors.New("parsing non subtitle file as subtitle")
 	}
 
@@ -126,6 +125,26 @@ func NewLocalSubtitle(path string) (types.LocalSubtitle, error) {
 	}, nil
 }
 
+// NewLocalSubtitleInfo returns a new local subtitle, using the given file
+// information instead of reading it from disk
+func NewLocalSubtitleInfo(path string, info os.FileInfo) (types.LocalSubtitle, error) {
+	if !fileIsSubtitle(path) {
+		return nil, errors.New("parsing non subtitle file as subtitle")
+	}
+
+	sub, err := NewSubtitle(parse.Filename(path))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalSubtitle{
+		FileInfo: info,
+		Pather:   FilePath(path),
+		Subtitle: sub,
+	}, nil
+}
+
 // LocalSubtitle represents a subtitle stored on disk
 type LocalSubtitle struct {
 	os.FileInfo
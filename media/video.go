@@ -9,8 +9,10 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/tympanix/supper/media/charset"
 	"github.com/tympanix/supper/media/list"
 	"github.com/tympanix/supper/media/parse"
+	"github.com/tympanix/supper/media/subformat"
 	"github.com/tympanix/supper/types"
 	"golang.org/x/text/language"
 )
@@ -54,13 +56,28 @@ func (f *Video) ExistingSubtitles() (types.SubtitleList, error) {
 	return list.Subtitles(subtitles...), nil
 }
 
-// SaveSubtitle saves the subtitle for the given media to disk
+// SaveSubtitle saves the subtitle for the given media to disk. The extension
+// of the subtitle is given by the format of its content (srt by default), in
+// any character encoding
 func (f *Video) SaveSubtitle(r io.Reader, lang language.Tag) (types.LocalSubtitle, error) {
 	if r == nil {
 		return nil, errors.New("invalid subtitle nil")
 	}
 
-	name := fmt.Sprintf("%s.%s.%s", parse.Filename(f.Path()), lang, "srt")
+	data, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ext := subformat.Default.Ext()
+	if text, _, err := charset.ToUTF8(data, lang); err == nil {
+		if format, err := subformat.Detect(text); err == nil {
+			ext = format.Ext()
+		}
+	}
+
+	name := fmt.Sprintf("%s.%s%s", parse.Filename(f.Path()), lang, ext)
 	folder := filepath.Dir(f.Path())
 	srtpath := filepath.Join(folder, name)
 
@@ -71,7 +88,7 @@ func (f *Video) SaveSubtitle(r io.Reader, lang language.Tag) (types.LocalSubtitl
 	}
 
 	defer file.Close()
-	_, err = io.Copy(file, r)
+	_, err = file.Write(data)
 
 	if err != nil {
 		return nil, err
@@ -68,3 +68,22 @@ func TestSaveSubtitle(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, data, sample)
 }
+
+func TestSaveSubtitleFormat(t *testing.T) {
+	f, err := NewLocalFile("test/Inception 2010 720p.mp4")
+	require.NoError(t, err)
+	v, ok := f.(types.Video)
+	require.True(t, ok)
+
+	sample := []byte("WEBVTT\n\n00:01.000 --> 00:02.000\nHello\n")
+
+	s, err := v.SaveSubtitle(bytes.NewBuffer(sample), language.German)
+	require.NoError(t, err)
+	defer os.Remove(s.Path())
+
+	assert.True(t, strings.HasSuffix(s.Path(), ".de.vtt"))
+
+	subs, err := v.ExistingSubtitles()
+	require.NoError(t, err)
+	assert.True(t, subs.LanguageSet().Has(language.German))
+}
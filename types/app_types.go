@@ -1,27 +1,47 @@
 package types
 
 import (
+	"context"
 	"html/template"
 	"net/http"
 	"time"
 
 	"github.com/fatih/set"
 	"github.com/tympanix/supper/app/notify"
+	"github.com/tympanix/supper/app/wanted"
+	"github.com/tympanix/supper/media/cleanup"
+	"github.com/tympanix/supper/media/subformat"
 )
 
 // App is the interface for the top level capabilities of the application.
 // It is an HTTP handler, a provider (for subtitles) and a CLI application.
 // It means App can both be used as a HTTP server and a CLI application.
 type App interface {
-	Provider
 	http.Handler
 	Config() Config
+	Providers() []Provider
 	Scrapers() []Scraper
+	SearchSubtitles(LocalMedia) ([]OnlineSubtitle, error)
+	SearchSubtitlesContext(context.Context, LocalMedia) ([]OnlineSubtitle, error)
+	ResolveSubtitle(Linker) (Downloadable, error)
 	FindMedia(...string) (LocalMediaList, error)
 	DownloadSubtitles(LocalMediaList, set.Interface, chan<- *notify.Entry) ([]LocalSubtitle, error)
+	DownloadSubtitlesContext(context.Context, LocalMediaList, set.Interface, chan<- *notify.Entry) ([]LocalSubtitle, error)
+	RejectSubtitleContext(context.Context, string, chan<- *notify.Entry) ([]LocalSubtitle, error)
+	SyncSubtitle(string, subformat.Timing) error
+	AlignSubtitle(string, string, float64) (subformat.Alignment, error)
+	NormalizeSubtitle(string) (string, bool, error)
+	CleanSubtitle(string) (cleanup.Result, error)
 	RenameMedia(LocalMediaList) error
+	RenameMediaContext(context.Context, LocalMediaList) error
 	FindArchives(...string) ([]MediaArchive, error)
 	ExtractMedia(MediaReadCloser) error
+	WatchContext(context.Context, []string, func(string)) error
+	ProcessMediaContext(context.Context, string, chan<- *notify.Entry) error
+	Wanted() []wanted.Item
+	ScheduleWantedContext(context.Context, func()) error
+	RetryWantedContext(context.Context, chan<- *notify.Entry) error
+	Close() error
 }
 
 // Config is the interface for application configuration
@@ -34,7 +54,12 @@ type Config interface {
 	Modified() time.Duration
 	Dry() bool
 	Score() int
+	Upgrade() int
+	Format() string
+	Encoding() string
+	Validation() string
 	Delay() time.Duration
+	Workers() int
 	Force() bool
 	Config() string
 	Logfile() string
@@ -48,12 +73,59 @@ type Config interface {
 	RenameAction() string
 	Evaluator() Evaluator
 	ProxyPath() string
+	CacheDir() string
+	DataDir() string
+	Index() string
+	Offline() bool
+	Jobs() JobsConfig
+	Watch() WatchConfig
+	Wanted() WantedConfig
+	Cleanup() CleanupConfig
+}
+
+// Cache is an interface for persistent storage of values which expire
+type Cache interface {
+	Get(bucket string, key string, v interface{}) (bool, error)
+	Put(bucket string, key string, v interface{}, ttl time.Duration) error
 }
 
 // APIKeys is the interface for configuration of 3rd party APIs
 type APIKeys interface {
 	TheTVDB() string
 	TheMovieDB() string
+	OpenSubtitles() string
+}
+
+// JobsConfig is the configuration interface for background jobs
+type JobsConfig interface {
+	Concurrency() int
+	Queue() int
+	History() int
+}
+
+// WatchConfig is the configuration interface for watching directories for
+// new media
+type WatchConfig interface {
+	Directories() []string
+	Stable() time.Duration
+	Rename() bool
+	Extract() bool
+	Subtitles() bool
+}
+
+// WantedConfig is the configuration interface for retrying missing subtitles
+type WantedConfig interface {
+	Schedule() []time.Duration
+	MaxAge() time.Duration
+}
+
+// CleanupConfig is the configuration interface for removing ads, credits and
+// hearing impaired annotations from subtitles
+type CleanupConfig interface {
+	Ads() bool
+	HearingImpaired() bool
+	Patterns() []string
+	Phrases() []string
 }
 
 // MediaConfig is the configuration interface for media collections
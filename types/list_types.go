@@ -21,6 +21,7 @@ type SubtitleList interface {
 	LanguageSet() set.Interface
 	FilterLanguage(language.Tag) SubtitleList
 	HearingImpaired(bool) SubtitleList
+	Completed() SubtitleList
 	RateByMedia(Media, Evaluator) RatedSubtitleList
 }
 
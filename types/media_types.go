@@ -1,6 +1,7 @@
 package types
 
 import (
+	"context"
 	"io"
 	"os"
 
@@ -13,20 +14,49 @@ import (
 
 // Provider interfaces with subtitles websites to provide subtitles
 type Provider interface {
+	Name() string
 	SearchSubtitles(LocalMedia) ([]OnlineSubtitle, error)
 	ResolveSubtitle(Linker) (Downloadable, error)
 }
 
+// OfflineProvider is a provider which serves subtitles without network access.
+// Offline providers are searched before going to the internet
+type OfflineProvider interface {
+	Provider
+	Offline() bool
+}
+
+// ContextProvider is a provider which stops searching for subtitles when the
+// context is cancelled
+type ContextProvider interface {
+	Provider
+	SearchSubtitlesContext(context.Context, LocalMedia) ([]OnlineSubtitle, error)
+}
+
 // Scraper interfaces with 3rd party APIs to scrape meta data
 type Scraper interface {
+	Name() string
 	Scrape(Media) (Media, error)
 }
 
+// ContextScraper is a scraper which stops scraping when the context is
+// cancelled
+type ContextScraper interface {
+	Scraper
+	ScrapeContext(context.Context, Media) (Media, error)
+}
+
 // Downloadable is an interface for media that can be downloaded from the internet
 type Downloadable interface {
 	Download() (io.ReadCloser, error)
 }
 
+// HashMatcher is an interface for media which has been matched against local
+// media by file hash, and therefore is guaranteed to be in sync with it
+type HashMatcher interface {
+	HashMatch() bool
+}
+
 // Evaluator determines how well two media types are alike
 type Evaluator interface {
 	Evaluate(Media, Media) float32
@@ -119,6 +149,12 @@ type Subtitle interface {
 	HearingImpaired() bool
 }
 
+// Completable is implemented by subtitles which may not be completely
+// translated yet, such as subtitles which are still being worked on
+type Completable interface {
+	Completed() bool
+}
+
 // LocalSubtitle is an subtitle which is stored on disk
 type LocalSubtitle interface {
 	Local
@@ -145,3 +181,10 @@ type OnlineSubtitle interface {
 	Downloadable
 	Subtitle
 }
+
+// ProvidedSubtitle is an online subtitle which is tagged with the name of the
+// provider it was found by
+type ProvidedSubtitle interface {
+	OnlineSubtitle
+	Provider() string
+}
@@ -50,6 +50,7 @@ class Api {
     let data = Object.assign({}, folder,
       {filepath: media.filepath},
       {link: subtitle.link},
+      {provider: subtitle.provider},
       {language: subtitle.language},
     )
     return axios.post('./api/subtitles', data, config)